@@ -0,0 +1,99 @@
+// Command cga-discover downloads the web UI assets of the device and writes a
+// JSON catalog of the API endpoints referenced in them. It is the same as
+// "cga discover", for use without the rest of the cga command.
+package main
+
+import (
+	"cmp"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/discover"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "cga-discover: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() (err error) {
+	var (
+		baseURL, username, password string
+		knownHosts, output, start   string
+		insecure                    bool
+		maxAssets                   int
+	)
+	flag.StringVar(&baseURL, "url", cmp.Or(os.Getenv("CGA_URL"),
+		client.DefaultBaseURL), "base URL of the device ($CGA_URL)")
+	flag.StringVar(&username, "user", os.Getenv("CGA_USERNAME"),
+		"username ($CGA_USERNAME)")
+	flag.StringVar(&password, "pass", "",
+		"password (prefer setting $CGA_PASSWORD)")
+	flag.StringVar(&knownHosts, "known-hosts", defaultKnownHostsFile(),
+		"file with the pinned device certificates")
+	flag.BoolVar(&insecure, "insecure", false,
+		"do not verify nor pin the device certificate")
+	flag.StringVar(&output, "o", "", "output file (default stdout)")
+	flag.StringVar(&start, "start", "/,/index.html",
+		"comma-separated paths to start crawling from")
+	flag.IntVar(&maxAssets, "max-assets", discover.DefaultMaxAssets,
+		"maximum number of assets to download")
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	p := client.Params{
+		BaseURL:  baseURL,
+		Username: username,
+		Password: cmp.Or(password, os.Getenv("CGA_PASSWORD")),
+	}
+	if !insecure {
+		p.KnownHostsFile = knownHosts
+	}
+	c, err := client.New(p)
+	if err != nil {
+		return err
+	}
+	if err := c.Login(ctx); err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx),
+			10*time.Second)
+		defer cancel()
+		if logoutErr := c.Logout(ctx); logoutErr != nil && err == nil {
+			err = fmt.Errorf("logout: %w", logoutErr)
+		}
+	}()
+
+	res, crawlErr := discover.Crawl(ctx, c.Fetch, strings.Split(start, ","),
+		maxAssets)
+	if crawlErr != nil {
+		fmt.Fprintf(os.Stderr, "cga-discover: %v\n", crawlErr)
+	}
+	fmt.Fprintf(os.Stderr, "fetched %d assets, found %d endpoints, %d errors\n",
+		len(res.Assets), len(res.Endpoints), len(res.Errors))
+
+	if output == "" {
+		return res.WriteJSON(os.Stdout)
+	}
+	return res.WriteFile(output)
+}
+
+func defaultKnownHostsFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "cga", "known_hosts")
+}
@@ -0,0 +1,160 @@
+// Command cga-gateway serves a local JSON REST API for the device, holding a
+// single session to it. See package gateway for the API.
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/gateway"
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/httpdoer"
+)
+
+const shutdownTimeout = 10 * time.Second
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "cga-gateway: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() (err error) {
+	var (
+		baseURL, username, password string
+		knownHosts, tokensFile      string
+		guestDeadline               string
+		listen, newToken            string
+		insecure                    bool
+		timeout                     time.Duration
+	)
+	flag.StringVar(&baseURL, "url", cmp.Or(os.Getenv("CGA_URL"),
+		client.DefaultBaseURL), "base URL of the device ($CGA_URL)")
+	flag.StringVar(&username, "user", os.Getenv("CGA_USERNAME"),
+		"username ($CGA_USERNAME)")
+	flag.StringVar(&password, "pass", "",
+		"password (prefer setting $CGA_PASSWORD)")
+	flag.StringVar(&knownHosts, "known-hosts", configFile("known_hosts"),
+		"file with the pinned device certificates")
+	flag.BoolVar(&insecure, "insecure", false,
+		"do not verify nor pin the device certificate")
+	flag.StringVar(&listen, "listen", "127.0.0.1:8380", "address to listen on")
+	flag.StringVar(&tokensFile, "tokens", configFile("gateway_tokens"),
+		"file with the API tokens, one \"name token\" per line")
+	flag.StringVar(&newToken, "new-token", "",
+		"generate a token with the given name, add it to the tokens file "+
+			"and exit")
+	flag.StringVar(&guestDeadline, "guest-deadline",
+		configFile("gateway_guest_deadline"),
+		"file that stores when to disable the guest Wi-Fi, so that it "+
+			"survives restarts")
+	flag.DurationVar(&timeout, "timeout", gateway.DefaultTimeout,
+		"maximum duration of a request to the device")
+	flag.Parse()
+
+	if newToken != "" {
+		token, err := gateway.AppendToken(tokensFile, newToken)
+		if err != nil {
+			return err
+		}
+		fmt.Println(token)
+		return nil
+	}
+
+	tokens, err := gateway.LoadTokens(tokensFile)
+	if err != nil {
+		return fmt.Errorf("%w; create one with -new-token NAME", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt,
+		syscall.SIGTERM)
+	defer cancel()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	p := client.Params{
+		BaseURL:  baseURL,
+		Username: username,
+		Password: cmp.Or(password, os.Getenv("CGA_PASSWORD")),
+		Breaker: httpdoer.NewBreaker(httpdoer.BreakerParams{
+			OnStateChange: func(host string, from, to httpdoer.BreakerState) {
+				logger.Warn("device circuit breaker", "host", host,
+					"from", from.String(), "to", to.String())
+			},
+		}),
+	}
+	if !insecure {
+		p.KnownHostsFile = knownHosts
+	}
+	c, err := client.New(p)
+	if err != nil {
+		return err
+	}
+	if err := c.Login(ctx); err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx),
+			shutdownTimeout)
+		defer cancel()
+		if logoutErr := c.Logout(ctx); logoutErr != nil && err == nil {
+			err = fmt.Errorf("logout: %w", logoutErr)
+		}
+	}()
+
+	handler, err := gateway.New(gateway.Params{
+		Client:  c,
+		Tokens:  tokens,
+		Timeout: timeout,
+		Logger:  logger,
+
+		GuestDeadlineFile: guestDeadline,
+	})
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Addr:              listen,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go handler.Run(ctx)
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	logger.Info("listening", "addr", listen, "tokens", tokens.Len())
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	ctx, cancelShutdown := context.WithTimeout(context.WithoutCancel(ctx),
+		shutdownTimeout)
+	defer cancelShutdown()
+	if err := srv.Shutdown(ctx); err != nil &&
+		!errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+
+	return nil
+}
+
+func configFile(name string) string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "cga", name)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+)
+
+func runAPI(ctx context.Context, args []string) error {
+	var f clientFlags
+	var raw, dataOnly bool
+	fs := newFlagSet("api", "METHOD PATH [name=value ...]")
+	f.register(fs)
+	fs.BoolVar(&raw, "raw", false, "print the response body as received")
+	fs.BoolVar(&dataOnly, "data", false, "print only the data field")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return fmt.Errorf("missing METHOD or PATH")
+	}
+
+	method, path := fs.Arg(0), fs.Arg(1)
+	form := url.Values{}
+	for _, kv := range fs.Args()[2:] {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return fmt.Errorf("invalid form field %q, expected name=value", kv)
+		}
+		form.Add(k, v)
+	}
+
+	return f.withClient(ctx, func(c client.Client) error {
+		res, err := c.Raw(ctx, method, path, form)
+		if err != nil {
+			return err
+		}
+
+		out := res.Raw
+		if dataOnly {
+			out = res.Data
+		}
+		if !raw {
+			buf := new(bytes.Buffer)
+			if err := json.Indent(buf, out, "", "  "); err == nil {
+				out = buf.Bytes()
+			}
+		}
+		os.Stdout.Write(out)
+		fmt.Println()
+
+		return nil
+	})
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+)
+
+func runBridge(ctx context.Context, args []string) error {
+	var f clientFlags
+	var yes bool
+	var lanURL string
+	fs := newFlagSet("bridge", "status|enable|disable")
+	f.register(fs)
+	fs.BoolVar(&yes, "yes", false, "enable: do not ask for confirmation")
+	fs.StringVar(&lanURL, "lan-url", client.DefaultBaseURL,
+		"disable: base URL of the device in router mode")
+	timeout := fs.Duration("timeout", client.DefaultBridgeModeTimeout,
+		"how long to wait for the device after the switch")
+
+	action := "status"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch action {
+	case "status":
+		return f.withClient(ctx, func(c client.Client) error {
+			enabled, err := c.BridgeMode(ctx)
+			if err != nil {
+				return err
+			}
+			if enabled {
+				fmt.Println("bridge mode")
+			} else {
+				fmt.Println("router mode")
+			}
+			return nil
+		})
+
+	case "enable":
+		fmt.Fprintf(os.Stderr, "In bridge mode, the device will only answer "+
+			"at %s. These stop working:\n", client.BridgeManagementAddress)
+		for _, l := range client.BridgeModeLosses {
+			fmt.Fprintf(os.Stderr, "  - %s\n", l)
+		}
+		if !yes && !confirm(`type "bridge" to continue: `, "bridge") {
+			return client.ErrNotConfirmed
+		}
+		return f.withClient(ctx, func(c client.Client) error {
+			if err := c.EnableBridgeMode(ctx, client.ConfirmBridgeMode(),
+				*timeout); err != nil {
+				return err
+			}
+			fmt.Printf("the device is in bridge mode and answers at %s\n",
+				c.CurrentBaseURL())
+			return nil
+		})
+
+	case "disable":
+		return f.withClient(ctx, func(c client.Client) error {
+			if err := c.DisableBridgeMode(ctx, lanURL, *timeout); err != nil {
+				return err
+			}
+			fmt.Printf("the device is in router mode and answers at %s\n",
+				c.CurrentBaseURL())
+			return nil
+		})
+	}
+
+	fs.Usage()
+	return fmt.Errorf("unknown action %q", action)
+}
+
+// confirm prints the prompt and reports whether the user typed word.
+func confirm(prompt, word string) bool {
+	fmt.Fprint(os.Stderr, prompt)
+	s := bufio.NewScanner(os.Stdin)
+	return s.Scan() && strings.TrimSpace(s.Text()) == word
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+)
+
+func runDHCP(ctx context.Context, args []string) error {
+	var f clientFlags
+	var start, end, dns, name string
+	var lease time.Duration
+	var enable, disable bool
+	fs := newFlagSet("dhcp",
+		"show|set|reservations|reserve|unreserve|import|export [ARG...]")
+	f.register(fs)
+	fs.StringVar(&start, "start", "", "set: first address of the pool")
+	fs.StringVar(&end, "end", "", "set: last address of the pool")
+	fs.DurationVar(&lease, "lease", 0, "set: lease time")
+	fs.StringVar(&dns, "dns", "",
+		"set: comma-separated DNS servers handed out, or \"none\"")
+	fs.BoolVar(&enable, "enable", false, "set: enable the DHCP server")
+	fs.BoolVar(&disable, "disable", false, "set: disable the DHCP server")
+	fs.StringVar(&name, "name", "", "reserve: name of the device")
+
+	var action string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch action {
+	case "show":
+		return f.withClient(ctx, func(c client.Client) error {
+			return printDHCP(ctx, c)
+		})
+
+	case "set":
+		return f.withClient(ctx, func(c client.Client) error {
+			s, err := c.DHCP(ctx)
+			if err != nil {
+				return err
+			}
+			if err := applyDHCPFlags(fs, s, start, end, dns, lease, enable,
+				disable); err != nil {
+				return err
+			}
+			return c.SetDHCP(ctx, *s)
+		})
+
+	case "reservations":
+		return f.withClient(ctx, func(c client.Client) error {
+			return printReservations(ctx, c)
+		})
+
+	case "reserve":
+		if fs.NArg() != 2 {
+			fs.Usage()
+			return fmt.Errorf("expected MAC and IP address")
+		}
+		ip, err := netip.ParseAddr(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		return f.withClient(ctx, func(c client.Client) error {
+			return c.SetReservation(ctx, client.Reservation{
+				MAC:  fs.Arg(0),
+				IP:   ip,
+				Name: name,
+			})
+		})
+
+	case "unreserve":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return fmt.Errorf("expected MAC address")
+		}
+		return f.withClient(ctx, func(c client.Client) error {
+			return c.DeleteReservation(ctx, fs.Arg(0))
+		})
+
+	case "import":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return fmt.Errorf("expected file")
+		}
+		file, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		rs, err := client.ParseReservations(file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", fs.Arg(0), err)
+		}
+		return f.withClient(ctx, func(c client.Client) error {
+			res, err := c.ImportReservations(ctx, rs)
+			if err != nil {
+				return err
+			}
+			for _, r := range res.Added {
+				fmt.Printf("+ %s %s %s\n", r.MAC, r.IP, r.Name)
+			}
+			for _, r := range res.Updated {
+				fmt.Printf("~ %s %s %s\n", r.MAC, r.IP, r.Name)
+			}
+			fmt.Printf("%d unchanged\n", len(res.Unchanged))
+			return nil
+		})
+
+	case "export":
+		return f.withClient(ctx, func(c client.Client) error {
+			rs, err := c.Reservations(ctx)
+			if err != nil {
+				return err
+			}
+			return client.WriteReservations(os.Stdout, rs)
+		})
+	}
+
+	fs.Usage()
+	return fmt.Errorf("unknown action %q", action)
+}
+
+// applyDHCPFlags changes the settings given in the flags of the "set" action.
+func applyDHCPFlags(
+	fs *flag.FlagSet,
+	s *client.DHCPSettings,
+	start, end, dns string,
+	lease time.Duration,
+	enable, disable bool,
+) error {
+	var err error
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	if set["start"] {
+		if s.PoolStart, err = netip.ParseAddr(start); err != nil {
+			return fmt.Errorf("-start: %w", err)
+		}
+	}
+	if set["end"] {
+		if s.PoolEnd, err = netip.ParseAddr(end); err != nil {
+			return fmt.Errorf("-end: %w", err)
+		}
+	}
+	if set["lease"] {
+		s.LeaseTime = lease
+	}
+	if set["dns"] {
+		s.DNSServers = nil
+		if dns != "none" {
+			for _, d := range strings.Split(dns, ",") {
+				ip, err := netip.ParseAddr(strings.TrimSpace(d))
+				if err != nil {
+					return fmt.Errorf("-dns: %w", err)
+				}
+				s.DNSServers = append(s.DNSServers, ip)
+			}
+		}
+	}
+	switch {
+	case enable && disable:
+		return fmt.Errorf("-enable and -disable are mutually exclusive")
+	case enable:
+		s.Enabled = true
+	case disable:
+		s.Enabled = false
+	}
+	return nil
+}
+
+func printDHCP(ctx context.Context, c client.Client) error {
+	s, err := c.DHCP(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Enabled:\t%t\n", s.Enabled)
+	fmt.Fprintf(w, "Router:\t%s\n", s.Router)
+	fmt.Fprintf(w, "Subnet:\t%s\n", s.Subnet)
+	fmt.Fprintf(w, "Pool:\t%s - %s\n", s.PoolStart, s.PoolEnd)
+	fmt.Fprintf(w, "Lease time:\t%s\n", s.LeaseTime)
+	dns := make([]string, len(s.DNSServers))
+	for i, ip := range s.DNSServers {
+		dns[i] = ip.String()
+	}
+	fmt.Fprintf(w, "DNS servers:\t%s\n", strings.Join(dns, ", "))
+	return w.Flush()
+}
+
+func printReservations(ctx context.Context, c client.Client) error {
+	rs, err := c.Reservations(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MAC\tIP\tNAME")
+	for _, r := range rs {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.MAC, r.IP, r.Name)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+)
+
+func runDiag(ctx context.Context, args []string) error {
+	var f clientFlags
+	var count, size, maxHops int
+	var server string
+	fs := newFlagSet("diag", "ping|traceroute|dns HOST")
+	f.register(fs)
+	fs.IntVar(&count, "c", 0, "ping: number of echo requests (default 4)")
+	fs.IntVar(&size, "s", 0, "ping: payload size in bytes (default 56)")
+	fs.IntVar(&maxHops, "m", 0, "traceroute: maximum number of hops (default 30)")
+	fs.StringVar(&server, "server", "", "dns: DNS server to query")
+
+	var action string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	host := fs.Arg(0)
+	if host == "" {
+		fs.Usage()
+		return fmt.Errorf("missing host")
+	}
+
+	switch action {
+	case "ping":
+		return f.withClient(ctx, func(c client.Client) error {
+			var sent, lost int
+			for r, err := range c.Ping(ctx, client.PingParams{
+				Host:  host,
+				Count: count,
+				Size:  size,
+			}) {
+				if err != nil {
+					return err
+				}
+				sent++
+				if r.Lost {
+					lost++
+					fmt.Printf("seq=%d timeout\n", r.Seq)
+					continue
+				}
+				fmt.Printf("reply from %s: seq=%d ttl=%d time=%s\n", r.Addr,
+					r.Seq, r.TTL, r.RTT)
+			}
+			if sent > 0 {
+				fmt.Printf("%d sent, %d lost (%d%%)\n", sent, lost,
+					lost*100/sent)
+			}
+			return nil
+		})
+
+	case "traceroute":
+		return f.withClient(ctx, func(c client.Client) error {
+			for h, err := range c.Traceroute(ctx, client.TracerouteParams{
+				Host:    host,
+				MaxHops: maxHops,
+			}) {
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%2d  %s\n", h.Hop, formatHop(h))
+			}
+			return nil
+		})
+
+	case "dns":
+		return f.withClient(ctx, func(c client.Client) error {
+			for a, err := range c.LookupHost(ctx, client.DNSParams{
+				Host:   host,
+				Server: server,
+			}) {
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s\t%s\t(server %s, %s)\n", a.Name, a.Addr,
+					a.Server, a.RTT)
+			}
+			return nil
+		})
+	}
+
+	fs.Usage()
+	return fmt.Errorf("unknown action %q", action)
+}
+
+// formatHop formats a traceroute hop like the traceroute command.
+func formatHop(h client.TracerouteHop) string {
+	if !h.Addr.IsValid() {
+		return strings.TrimSpace(strings.Repeat("* ", h.Lost))
+	}
+	var b strings.Builder
+	if h.Host != "" && h.Host != h.Addr.String() {
+		fmt.Fprintf(&b, "%s (%s)", h.Host, h.Addr)
+	} else {
+		b.WriteString(h.Addr.String())
+	}
+	for _, rtt := range h.RTTs {
+		fmt.Fprintf(&b, "  %s", rtt.Round(time.Microsecond))
+	}
+	b.WriteString(strings.Repeat("  *", h.Lost))
+	return b.String()
+}
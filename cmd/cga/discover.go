@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/discover"
+)
+
+func runDiscover(ctx context.Context, args []string) error {
+	var f clientFlags
+	var output, start string
+	var maxAssets int
+	fs := newFlagSet("discover", "")
+	f.register(fs)
+	fs.StringVar(&output, "o", "", "output file (default stdout)")
+	fs.StringVar(&start, "start", "/,/index.html",
+		"comma-separated paths to start crawling from")
+	fs.IntVar(&maxAssets, "max-assets", discover.DefaultMaxAssets,
+		"maximum number of assets to download")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var res *discover.CrawlResult
+	err := f.withClient(ctx, func(c client.Client) error {
+		var crawlErr error
+		res, crawlErr = discover.Crawl(ctx, c.Fetch,
+			strings.Split(start, ","), maxAssets)
+		if crawlErr != nil {
+			fmt.Fprintf(os.Stderr, "cga discover: %v\n", crawlErr)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "fetched %d assets, found %d endpoints, %d errors\n",
+		len(res.Assets), len(res.Endpoints), len(res.Errors))
+
+	if output == "" {
+		return res.WriteJSON(os.Stdout)
+	}
+	return res.WriteFile(output)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+)
+
+const logoutTimeout = 10 * time.Second
+
+func newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet("cga "+name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: cga %s [flags] %s\n\nflags:\n", name,
+			args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func defaultKnownHostsFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "cga", "known_hosts")
+}
+
+// deviceFlags are the flags needed to reach the device.
+type deviceFlags struct {
+	baseURL    string
+	knownHosts string
+}
+
+func (f *deviceFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.baseURL, "url", cmp.Or(os.Getenv("CGA_URL"),
+		client.DefaultBaseURL), "base URL of the device ($CGA_URL)")
+	fs.StringVar(&f.knownHosts, "known-hosts", defaultKnownHostsFile(),
+		"file with the pinned device certificates")
+}
+
+// hostPort returns the "host:port" of the base URL.
+func (f *deviceFlags) hostPort() (string, error) {
+	u, err := url.Parse(f.baseURL)
+	if err != nil {
+		return "", fmt.Errorf("parse base URL: %w", err)
+	}
+	if u.Port() != "" {
+		return u.Host, nil
+	}
+	port := "443"
+	if u.Scheme == "http" {
+		port = "80"
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
+
+// clientFlags are the flags needed to create an authenticated client.
+type clientFlags struct {
+	deviceFlags
+	username string
+	password string
+	insecure bool
+}
+
+func (f *clientFlags) register(fs *flag.FlagSet) {
+	f.deviceFlags.register(fs)
+	fs.StringVar(&f.username, "user", os.Getenv("CGA_USERNAME"),
+		"username ($CGA_USERNAME)")
+	fs.StringVar(&f.password, "pass", "",
+		"password (prefer setting $CGA_PASSWORD)")
+	fs.BoolVar(&f.insecure, "insecure", false,
+		"do not verify nor pin the device certificate")
+}
+
+func (f *clientFlags) params() client.Params {
+	p := client.Params{
+		BaseURL:  f.baseURL,
+		Username: f.username,
+		Password: cmp.Or(f.password, os.Getenv("CGA_PASSWORD")),
+	}
+	if !f.insecure {
+		p.KnownHostsFile = f.knownHosts
+	}
+	return p
+}
+
+// withClient creates a logged-in client with the params from the flags, calls
+// fn with it, and logs out.
+func (f *clientFlags) withClient(
+	ctx context.Context,
+	fn func(client.Client) error,
+) error {
+	return withClient(ctx, f.params(), fn)
+}
+
+// withClient creates a logged-in client, calls fn with it, and logs out. The
+// device allows a single admin session, so not logging out would lock out the
+// web UI until the session expires.
+func withClient(
+	ctx context.Context,
+	p client.Params,
+	fn func(client.Client) error,
+) (err error) {
+	c, err := client.New(p)
+	if err != nil {
+		return err
+	}
+	if err := c.Login(ctx); err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx),
+			logoutTimeout)
+		defer cancel()
+		if logoutErr := c.Logout(ctx); logoutErr != nil && err == nil {
+			err = fmt.Errorf("logout: %w", logoutErr)
+		}
+	}()
+
+	return fn(c)
+}
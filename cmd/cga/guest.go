@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/gateway"
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/wifiqr"
+)
+
+const (
+	guestDisableAttempts = 3
+	guestDisableRetry    = 10 * time.Second
+)
+
+func runGuest(ctx context.Context, args []string) error {
+	var f clientFlags
+	var (
+		duration            time.Duration
+		showQR, showPass    bool
+		pngFile, ssid, pass string
+		band, gatewayURL    string
+		isolation           bool
+		pngSize             int
+	)
+	fs := newFlagSet("guest", "status|on|off|set|qr")
+	f.register(fs)
+	fs.DurationVar(&duration, "for", 0,
+		"on: disable the guest network again after this time; without "+
+			"-gateway, the command keeps running until then and disables it "+
+			"on interrupt, but the network stays on if it is killed or the "+
+			"host sleeps")
+	fs.StringVar(&gatewayURL, "gateway", os.Getenv("CGA_GATEWAY_URL"),
+		"on: URL of a cga-gateway that enables the guest network and "+
+			"disables it after -for, authenticated with $CGA_GATEWAY_TOKEN "+
+			"($CGA_GATEWAY_URL)")
+	fs.StringVar(&band, "band", "",
+		"on, qr: band of the guest network of the QR code, \"2.4\" or "+
+			"\"5\"; defaults to the first one")
+	fs.BoolVar(&showQR, "qr", false, "on: also show the QR code")
+	fs.BoolVar(&showPass, "show-pass", false, "status: show the passphrase")
+	fs.StringVar(&pngFile, "png", "",
+		"qr: write the QR code to a PNG file instead of the terminal")
+	fs.IntVar(&pngSize, "size", wifiqr.DefaultPNGSize,
+		"qr: size of the PNG image in pixels")
+	fs.StringVar(&ssid, "ssid", "", "set: SSID")
+	fs.StringVar(&pass, "pass-phrase", "", "set: passphrase")
+	fs.BoolVar(&isolation, "isolation", true,
+		"set: prevent guest clients from reaching each other")
+
+	var action string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch action {
+	case "status":
+		return f.withClient(ctx, func(c client.Client) error {
+			return printGuestWiFi(ctx, c, showPass)
+		})
+
+	case "on":
+		if gatewayURL != "" {
+			networks, err := enableGuestWiFiWithGateway(ctx, gatewayURL,
+				duration)
+			if err != nil || !showQR {
+				return err
+			}
+			// the gateway returns the networks, so that no session is
+			// opened here
+			if len(networks) == 0 {
+				return errors.New("the gateway did not return the guest " +
+					"networks for the QR code")
+			}
+			return writeGuestQR(networks, band, "", 0)
+		}
+		err := f.withClient(ctx, func(c client.Client) error {
+			if err := c.SetGuestWiFi(ctx, true); err != nil {
+				return err
+			}
+			if showQR {
+				networks, err := c.GuestWiFi(ctx)
+				if err != nil {
+					return err
+				}
+				return writeGuestQR(networks, band, "", 0)
+			}
+			return nil
+		})
+		if err != nil || duration <= 0 {
+			return err
+		}
+		// the session is not held while waiting, so that the web UI can be
+		// used in the meantime
+		fmt.Printf("guest Wi-Fi enabled until %s\n",
+			time.Now().Add(duration).Format(time.Kitchen))
+		select {
+		case <-time.After(duration):
+		case <-ctx.Done():
+		}
+		return disableGuestWiFi(context.WithoutCancel(ctx), f.params())
+
+	case "off":
+		return f.withClient(ctx, func(c client.Client) error {
+			return c.SetGuestWiFi(ctx, false)
+		})
+
+	case "set":
+		var s client.GuestWiFiSettings
+		fs.Visit(func(fl *flag.Flag) {
+			switch fl.Name {
+			case "ssid":
+				s.SSID = &ssid
+			case "pass-phrase":
+				s.Passphrase = &pass
+			case "isolation":
+				s.ClientIsolation = &isolation
+			}
+		})
+		if s == (client.GuestWiFiSettings{}) {
+			return errors.New("nothing to set; use -ssid, -pass-phrase or " +
+				"-isolation")
+		}
+		if err := s.Validate(); err != nil {
+			return err
+		}
+		return f.withClient(ctx, func(c client.Client) error {
+			return c.ConfigureGuestWiFi(ctx, s)
+		})
+
+	case "qr":
+		return f.withClient(ctx, func(c client.Client) error {
+			networks, err := c.GuestWiFi(ctx)
+			if err != nil {
+				return err
+			}
+			return writeGuestQR(networks, band, pngFile, pngSize)
+		})
+	}
+
+	fs.Usage()
+	return fmt.Errorf("unknown action %q", action)
+}
+
+func printGuestWiFi(ctx context.Context, c client.Client, showPass bool) error {
+	networks, err := c.GuestWiFi(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BAND\tENABLED\tSSID\tSECURITY\tISOLATION\tPASSPHRASE")
+	for _, n := range networks {
+		p := "********"
+		if showPass {
+			p = n.Passphrase
+		}
+		fmt.Fprintf(w, "%s\t%v\t%s\t%s\t%v\t%s\n", n.Band, n.Enabled, n.SSID,
+			n.Security, n.ClientIsolation, p)
+	}
+	return w.Flush()
+}
+
+// writeGuestQR writes the QR code of the guest network of the band, or of the
+// first one if band is empty, to the terminal, or to a PNG file if pngFile is
+// set.
+func writeGuestQR(
+	networks []client.GuestWiFi,
+	band, pngFile string,
+	size int,
+) error {
+	if len(networks) == 0 {
+		return client.ErrNoGuestWiFi
+	}
+	g := networks[0]
+	if band != "" {
+		var err error
+		if band, err = client.ParseBand(band); err != nil {
+			return err
+		}
+		i := slices.IndexFunc(networks, func(n client.GuestWiFi) bool {
+			return n.Band == band
+		})
+		if i < 0 {
+			return fmt.Errorf("no guest Wi-Fi network in the %s band", band)
+		}
+		g = networks[i]
+	}
+	n := wifiqr.Network{
+		SSID:       g.SSID,
+		Passphrase: g.Passphrase,
+		Auth:       wifiqr.AuthFromSecurity(g.Security),
+	}
+
+	if pngFile != "" {
+		return n.WritePNG(pngFile, size)
+	}
+	fmt.Printf("Wi-Fi: %s\n", g.SSID)
+	return n.WriteTerminal(os.Stdout)
+}
+
+// enableGuestWiFiWithGateway enables the guest network through a cga-gateway,
+// which disables it again after d, if positive, and returns the guest networks.
+func enableGuestWiFiWithGateway(
+	ctx context.Context,
+	gatewayURL string,
+	d time.Duration,
+) ([]client.GuestWiFi, error) {
+	q := url.Values{"enabled": {"true"}}
+	if d > 0 {
+		q.Set("for", d.String())
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		strings.TrimSuffix(gatewayURL, "/")+"/v1/guest-wifi?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+os.Getenv("CGA_GATEWAY_TOKEN"))
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("gateway: %w", err)
+	}
+	defer res.Body.Close()
+
+	var body struct {
+		Data  gateway.GuestWiFi `json:"data"`
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("gateway: HTTP status %d: decode body: %w",
+			res.StatusCode, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gateway: %s: %s", body.Error.Code,
+			body.Error.Message)
+	}
+	if body.Data.Until != "" {
+		fmt.Printf("guest Wi-Fi enabled until %s\n", body.Data.Until)
+	}
+
+	var networks []client.GuestWiFi
+	for _, n := range body.Data.Networks {
+		networks = append(networks, client.GuestWiFi{
+			Band:       n.Band,
+			Enabled:    true,
+			SSID:       n.SSID,
+			Security:   n.Security,
+			Passphrase: n.Passphrase,
+		})
+	}
+	return networks, nil
+}
+
+// disableGuestWiFi disables the guest network, retrying a few times since
+// leaving it enabled is worse than waiting.
+func disableGuestWiFi(ctx context.Context, p client.Params) error {
+	var err error
+	for attempt := range guestDisableAttempts {
+		if attempt > 0 {
+			fmt.Fprintf(os.Stderr, "cga guest: disable: %v; retrying\n", err)
+			time.Sleep(guestDisableRetry)
+		}
+		err = withClient(ctx, p, func(c client.Client) error {
+			return c.SetGuestWiFi(ctx, false)
+		})
+		if err == nil {
+			fmt.Println("guest Wi-Fi disabled")
+			return nil
+		}
+	}
+	return fmt.Errorf("disable guest Wi-Fi: %w", err)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+)
+
+func runInfo(ctx context.Context, args []string) error {
+	var f clientFlags
+	fs := newFlagSet("info", "")
+	f.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	return f.withClient(ctx, func(c client.Client) error {
+		info, err := c.DeviceInfo(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "Model:\t%s\n", info.Model)
+		fmt.Fprintf(w, "Hardware version:\t%s\n", info.HardwareVersion)
+		fmt.Fprintf(w, "Firmware version:\t%s\n", info.FirmwareVersion)
+		fmt.Fprintf(w, "Bootloader version:\t%s\n", info.BootloaderVersion)
+		fmt.Fprintf(w, "Serial number:\t%s\n", info.SerialNumber)
+		fmt.Fprintf(w, "CM MAC address:\t%s\n", info.CMMACAddress)
+		fmt.Fprintf(w, "Uptime:\t%s\n", info.Uptime)
+		return w.Flush()
+	})
+}
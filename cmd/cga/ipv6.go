@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+)
+
+func runIPv6(ctx context.Context, args []string) error {
+	var f clientFlags
+	var requirePrefix, enable, disable bool
+	var mode, ra, dns, ula, proto, desc string
+	fs := newFlagSet("ipv6",
+		"status|set-lan|pinholes|add-pinhole|delete-pinhole [ARG...]")
+	f.register(fs)
+	fs.BoolVar(&requirePrefix, "require-prefix", false,
+		"status: fail if the ISP did not delegate a prefix")
+	fs.BoolVar(&enable, "enable", false, "set-lan: enable IPv6 in the LAN")
+	fs.BoolVar(&disable, "disable", false, "set-lan: disable IPv6 in the LAN")
+	fs.StringVar(&mode, "mode", "",
+		"set-lan: address mode (slaac, stateless or stateful)")
+	fs.StringVar(&ra, "ra", "",
+		"set-lan: send router advertisements (true or false)")
+	fs.StringVar(&dns, "dns", "",
+		"set-lan: comma-separated DNS servers handed out, or \"none\"")
+	fs.StringVar(&ula, "ula", "",
+		"set-lan: unique local prefix to announce, or \"none\"")
+	fs.StringVar(&proto, "proto", string(client.PinholeTCP),
+		"add-pinhole: protocol (tcp, udp or both)")
+	fs.StringVar(&desc, "desc", "", "add-pinhole: description")
+
+	action := "status"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch action {
+	case "status":
+		return f.withClient(ctx, func(c client.Client) error {
+			return printIPv6(ctx, c, requirePrefix)
+		})
+
+	case "set-lan":
+		return f.withClient(ctx, func(c client.Client) error {
+			l, err := c.IPv6LAN(ctx)
+			if err != nil {
+				return err
+			}
+			if err := applyIPv6LANFlags(fs, l, mode, ra, dns, ula, enable,
+				disable); err != nil {
+				return err
+			}
+			return c.SetIPv6LAN(ctx, *l)
+		})
+
+	case "pinholes":
+		return f.withClient(ctx, func(c client.Client) error {
+			return printPinholes(ctx, c)
+		})
+
+	case "add-pinhole":
+		if fs.NArg() != 2 {
+			fs.Usage()
+			return fmt.Errorf("expected address and port or port range")
+		}
+		p := client.Pinhole{
+			Description: desc,
+			Enabled:     true,
+			Protocol:    client.PinholeProtocol(proto),
+		}
+		var err error
+		if p.Dest, err = netip.ParseAddr(fs.Arg(0)); err != nil {
+			return err
+		}
+		if p.PortStart, p.PortEnd, err = parsePortRange(fs.Arg(1)); err != nil {
+			return err
+		}
+		return f.withClient(ctx, func(c client.Client) error {
+			p, err := c.AddPinhole(ctx, p)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("added pinhole %d\n", p.ID)
+			return nil
+		})
+
+	case "delete-pinhole":
+		id, err := strconv.Atoi(fs.Arg(0))
+		if err != nil {
+			fs.Usage()
+			return fmt.Errorf("expected pinhole ID: %w", err)
+		}
+		return f.withClient(ctx, func(c client.Client) error {
+			return c.DeletePinhole(ctx, id)
+		})
+	}
+
+	fs.Usage()
+	return fmt.Errorf("unknown action %q", action)
+}
+
+// parsePortRange parses a port, or a range of ports like "8000-8010".
+func parsePortRange(s string) (start, end uint16, err error) {
+	startStr, endStr, isRange := strings.Cut(s, "-")
+	start64, err := strconv.ParseUint(startStr, 10, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid port %q", startStr)
+	}
+	if !isRange {
+		return uint16(start64), uint16(start64), nil
+	}
+	end64, err := strconv.ParseUint(endStr, 10, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid port %q", endStr)
+	}
+	return uint16(start64), uint16(end64), nil
+}
+
+// applyIPv6LANFlags changes the settings given in the flags of the "set-lan"
+// action.
+func applyIPv6LANFlags(
+	fs *flag.FlagSet,
+	l *client.IPv6LAN,
+	mode, ra, dns, ula string,
+	enable, disable bool,
+) error {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	if set["mode"] {
+		l.Mode = client.IPv6LANMode(mode)
+	}
+	if set["ra"] {
+		v, err := strconv.ParseBool(ra)
+		if err != nil {
+			return fmt.Errorf("-ra: %w", err)
+		}
+		l.RouterAdvertisements = v
+	}
+	if set["dns"] {
+		l.DNSServers = nil
+		if dns != "none" {
+			for _, d := range strings.Split(dns, ",") {
+				ip, err := netip.ParseAddr(strings.TrimSpace(d))
+				if err != nil {
+					return fmt.Errorf("-dns: %w", err)
+				}
+				l.DNSServers = append(l.DNSServers, ip)
+			}
+		}
+	}
+	if set["ula"] {
+		l.ULA = netip.Prefix{}
+		if ula != "none" {
+			p, err := netip.ParsePrefix(ula)
+			if err != nil {
+				return fmt.Errorf("-ula: %w", err)
+			}
+			l.ULA = p
+		}
+	}
+	switch {
+	case enable && disable:
+		return errors.New("-enable and -disable are mutually exclusive")
+	case enable:
+		l.Enabled = true
+	case disable:
+		l.Enabled = false
+	}
+	return nil
+}
+
+func printIPv6(ctx context.Context, c client.Client, requirePrefix bool) error {
+	wan, err := c.IPv6WAN(ctx)
+	if err != nil {
+		return err
+	}
+	lan, err := c.IPv6LAN(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "WAN enabled:\t%t\n", wan.Enabled)
+	fmt.Fprintf(w, "WAN mode:\t%s\n", wan.Mode)
+	fmt.Fprintf(w, "WAN addresses:\t%s\n", joinStrings(wan.Addresses))
+	fmt.Fprintf(w, "Delegated prefix:\t%s\n", optional(wan.DelegatedPrefix))
+	fmt.Fprintf(w, "Gateway:\t%s\n", optional(wan.Gateway))
+	fmt.Fprintf(w, "RA received:\t%t (managed: %t, other config: %t)\n",
+		wan.RAReceived, wan.RAManaged, wan.RAOtherConfig)
+	fmt.Fprintf(w, "WAN DNS servers:\t%s\n", joinStrings(wan.DNSServers))
+	fmt.Fprintf(w, "LAN enabled:\t%t\n", lan.Enabled)
+	fmt.Fprintf(w, "LAN mode:\t%s\n", lan.Mode)
+	fmt.Fprintf(w, "LAN prefix:\t%s\n", optional(lan.Prefix))
+	fmt.Fprintf(w, "LAN link-local:\t%s\n", optional(lan.LinkLocal))
+	fmt.Fprintf(w, "Router advertisements:\t%t\n", lan.RouterAdvertisements)
+	fmt.Fprintf(w, "LAN DNS servers:\t%s\n", joinStrings(lan.DNSServers))
+	fmt.Fprintf(w, "ULA prefix:\t%s\n", optional(lan.ULA))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if requirePrefix && !wan.HasDelegatedPrefix() {
+		return errors.New("no delegated prefix")
+	}
+	return nil
+}
+
+func printPinholes(ctx context.Context, c client.Client) error {
+	pinholes, err := c.Pinholes(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tENABLED\tPROTOCOL\tADDRESS\tPORTS\tDESCRIPTION")
+	for _, p := range pinholes {
+		ports := strconv.Itoa(int(p.PortStart))
+		if p.PortEnd != p.PortStart {
+			ports += "-" + strconv.Itoa(int(p.PortEnd))
+		}
+		fmt.Fprintf(w, "%d\t%t\t%s\t%s\t%s\t%s\n", p.ID, p.Enabled, p.Protocol,
+			p.Dest, ports, p.Description)
+	}
+	return w.Flush()
+}
+
+// optional returns "-" for zero values, like a missing prefix.
+func optional[T interface {
+	IsValid() bool
+	String() string
+}](v T) string {
+	if !v.IsValid() {
+		return "-"
+	}
+	return v.String()
+}
+
+func joinStrings[T fmt.Stringer](vs []T) string {
+	if len(vs) == 0 {
+		return "-"
+	}
+	s := make([]string, len(vs))
+	for i, v := range vs {
+		s[i] = v.String()
+	}
+	return strings.Join(s, ", ")
+}
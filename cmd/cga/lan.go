@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+)
+
+func runLAN(ctx context.Context, args []string) error {
+	var f clientFlags
+	fs := newFlagSet("lan", "show | set ADDRESS/BITS")
+	f.register(fs)
+	timeout := fs.Duration("timeout", client.DefaultLANChangeTimeout,
+		"set: how long to wait for the device at the new address")
+
+	action := "show"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch action {
+	case "show":
+		return f.withClient(ctx, func(c client.Client) error {
+			addr, err := c.LAN(ctx)
+			if err != nil {
+				return err
+			}
+			fmt.Println(addr)
+			return nil
+		})
+
+	case "set":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return fmt.Errorf("expected address and subnet, e.g. 10.0.0.1/24")
+		}
+		addr, err := netip.ParsePrefix(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		if err := client.ValidateLANAddress(addr); err != nil {
+			return err
+		}
+		return f.withClient(ctx, func(c client.Client) error {
+			if err := c.SetLAN(ctx, addr, *timeout); err != nil {
+				return err
+			}
+			fmt.Printf("the device now answers at %s; use it with -url or "+
+				"$CGA_URL from now on\n", c.CurrentBaseURL())
+			return nil
+		})
+	}
+
+	fs.Usage()
+	return fmt.Errorf("unknown action %q", action)
+}
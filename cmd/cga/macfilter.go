@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+)
+
+func runMACFilter(ctx context.Context, args []string) error {
+	var f clientFlags
+	var index int
+	var mode, name string
+	fs := newFlagSet("macfilter", "list|mode|add|delete|sync [ARG]")
+	f.register(fs)
+	fs.IntVar(&index, "net", 0,
+		"index of the Wi-Fi network (see 'cga macfilter list')")
+	fs.StringVar(&mode, "mode", "",
+		"sync: also set the mode (disabled, allow or deny)")
+	fs.StringVar(&name, "name", "", "add: name of the device")
+
+	var action string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	arg := fs.Arg(0)
+	if action != "list" && (index <= 0 || arg == "") {
+		fs.Usage()
+		return fmt.Errorf("missing -net or argument")
+	}
+
+	switch action {
+	case "list":
+		return f.withClient(ctx, func(c client.Client) error {
+			return printMACFilters(ctx, c, index)
+		})
+
+	case "mode":
+		return f.withClient(ctx, func(c client.Client) error {
+			return c.SetMACFilterMode(ctx, index, client.MACFilterMode(arg))
+		})
+
+	case "add":
+		return f.withClient(ctx, func(c client.Client) error {
+			return c.AddMACFilterEntry(ctx, index, client.MACFilterEntry{
+				MAC:  arg,
+				Name: name,
+			})
+		})
+
+	case "delete":
+		return f.withClient(ctx, func(c client.Client) error {
+			return c.DeleteMACFilterEntry(ctx, index, arg)
+		})
+
+	case "sync":
+		file, err := os.Open(arg)
+		if err != nil {
+			return err
+		}
+		entries, err := client.ParseMACFilterList(file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", arg, err)
+		}
+		return f.withClient(ctx, func(c client.Client) error {
+			res, err := c.SyncMACFilter(ctx, index,
+				client.MACFilterMode(mode), entries)
+			if err != nil {
+				return err
+			}
+			for _, e := range res.Added {
+				fmt.Printf("+ %s %s\n", e.MAC, e.Name)
+			}
+			for _, e := range res.Renamed {
+				fmt.Printf("~ %s %s\n", e.MAC, e.Name)
+			}
+			for _, e := range res.Removed {
+				fmt.Printf("- %s %s\n", e.MAC, e.Name)
+			}
+			return nil
+		})
+	}
+
+	fs.Usage()
+	return fmt.Errorf("unknown action %q", action)
+}
+
+// printMACFilters prints the MAC filter of the network with the given index,
+// or of all the networks if it is zero.
+func printMACFilters(ctx context.Context, c client.Client, index int) error {
+	networks, err := c.WiFi(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NET\tSSID\tBAND\tMODE\tMAC\tNAME")
+	for _, n := range networks {
+		if index != 0 && n.Index != index {
+			continue
+		}
+		f, err := c.MACFilter(ctx, n.Index)
+		if err != nil {
+			return fmt.Errorf("network %d: %w", n.Index, err)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t\t\n", n.Index, n.SSID, n.Band, f.Mode)
+		for _, e := range f.Entries {
+			fmt.Fprintf(w, "\t\t\t\t%s\t%s\n", e.MAC, e.Name)
+		}
+	}
+	return w.Flush()
+}
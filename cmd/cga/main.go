@@ -0,0 +1,74 @@
+// Command cga manages a Technicolor CGA4233 cable modem.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{"api", "perform an authenticated request to the device API", runAPI},
+	{"bridge", "switch between router and bridge mode", runBridge},
+	{"dhcp", "manage the DHCP server and its static reservations", runDHCP},
+	{"diag", "run ping, traceroute or DNS lookups from the device", runDiag},
+	{"discover", "catalog the API endpoints used by the web UI", runDiscover},
+	{"guest", "manage the guest Wi-Fi and show its QR code", runGuest},
+	{"info", "show the device model and firmware", runInfo},
+	{"ipv6", "show the IPv6 status and manage the LAN settings and pinholes", runIPv6},
+	{"lan", "show or change the LAN address of the device", runLAN},
+	{"macfilter", "manage the Wi-Fi MAC filter of each network", runMACFilter},
+	{"mqtt", "publish the device state to MQTT for Home Assistant", runMQTT},
+	{"parental", "manage access rules, URL filters and device pauses", runParental},
+	{"pin", "pin (or re-pin) the device certificate", runPin},
+	{"presence", "emit events when hosts join or leave the LAN", runPresence},
+	{"schema", "record or check the shape of API responses", runSchema},
+	{"survey", "scan the neighboring Wi-Fi networks and recommend a channel", runSurvey},
+	{"wan", "show the Internet connection and cable modem provisioning", runWAN},
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: cga <command> [flags] [args]\n\ncommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nrun 'cga <command> -h' for help on a command\n")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	name := os.Args[1]
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		err := c.run(ctx, os.Args[2:])
+		if err == flag.ErrHelp {
+			os.Exit(2)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cga %s: %v\n", name, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "cga: unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
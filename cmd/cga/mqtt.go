@@ -0,0 +1,72 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/mqttbridge"
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/mqttbridge/pahomqtt"
+)
+
+const mqttConnectTimeout = 30 * time.Second
+
+func runMQTT(ctx context.Context, args []string) error {
+	var f clientFlags
+	var p mqttbridge.Params
+	var broker, mqttUser, mqttPass, clientID string
+	fs := newFlagSet("mqtt", "")
+	f.register(fs)
+	fs.StringVar(&broker, "broker", cmp.Or(os.Getenv("CGA_MQTT_BROKER"),
+		"tcp://localhost:1883"), "MQTT broker URL ($CGA_MQTT_BROKER)")
+	fs.StringVar(&mqttUser, "mqtt-user", os.Getenv("CGA_MQTT_USERNAME"),
+		"MQTT username ($CGA_MQTT_USERNAME)")
+	fs.StringVar(&mqttPass, "mqtt-pass", "",
+		"MQTT password (prefer setting $CGA_MQTT_PASSWORD)")
+	fs.StringVar(&clientID, "client-id", "cga-bridge", "MQTT client ID")
+	fs.DurationVar(&p.Interval, "interval", mqttbridge.DefaultInterval,
+		"time between state updates")
+	fs.StringVar(&p.TopicPrefix, "prefix", mqttbridge.DefaultTopicPrefix,
+		"prefix of the state and command topics")
+	fs.StringVar(&p.DiscoveryPrefix, "discovery-prefix",
+		mqttbridge.DefaultDiscoveryPrefix, "Home Assistant discovery prefix")
+	fs.StringVar(&p.NodeID, "node", mqttbridge.DefaultNodeID,
+		"node ID of the device in the topics and in Home Assistant")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(clientID).
+		SetUsername(mqttUser).
+		SetPassword(cmp.Or(mqttPass, os.Getenv("CGA_MQTT_PASSWORD"))).
+		SetWill(p.AvailabilityTopic(), mqttbridge.PayloadOffline,
+			pahomqtt.QoS, true).
+		SetAutoReconnect(true)
+	connectCtx, cancel := context.WithTimeout(ctx, mqttConnectTimeout)
+	defer cancel()
+	mc, err := pahomqtt.Connect(connectCtx, opts)
+	if err != nil {
+		return fmt.Errorf("connect to MQTT broker: %w", err)
+	}
+	defer mc.Disconnect(250)
+
+	return f.withClient(ctx, func(c client.Client) error {
+		p.Client = c
+		p.MQTT = mc
+		p.OnError = func(err error) {
+			fmt.Fprintf(os.Stderr, "cga mqtt: %v\n", err)
+		}
+		b, err := mqttbridge.New(p)
+		if err != nil {
+			return err
+		}
+		return b.Run(ctx)
+	})
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+)
+
+const parentalActions = "rules|add|delete|filters|add-filter|" +
+	"enable-filter|disable-filter|delete-filter|block|unblock|pause|resume|" +
+	"expire"
+
+func runParental(ctx context.Context, args []string) error {
+	var f clientFlags
+	var (
+		mac, desc, days, window, until, filterType string
+		pauseFor                                   time.Duration
+	)
+	fs := newFlagSet("parental", parentalActions+" [ARG]")
+	f.register(fs)
+	fs.StringVar(&mac, "mac", "", "add: MAC address of the device")
+	fs.StringVar(&desc, "desc", "", "add: description of the rule")
+	fs.StringVar(&days, "days", "", "add: comma-separated days, e.g. "+
+		"Mon,Tue; empty blocks always")
+	fs.StringVar(&window, "window", "", "add: time window, e.g. 22:00-07:00")
+	fs.StringVar(&until, "until", "",
+		"pause: end of the pause, as 15:04 (next occurrence) or RFC 3339")
+	fs.DurationVar(&pauseFor, "for", 0, "pause: duration of the pause")
+	fs.StringVar(&filterType, "type", string(client.FilterKeyword),
+		"add-filter: keyword or url")
+
+	var action string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	arg := fs.Arg(0)
+	needArg := func(name string) error {
+		if arg == "" {
+			fs.Usage()
+			return fmt.Errorf("missing %s", name)
+		}
+		return nil
+	}
+
+	switch action {
+	case "rules":
+		return f.withClient(ctx, func(c client.Client) error {
+			return printAccessRules(ctx, c)
+		})
+
+	case "add":
+		rules, err := parseAccessRule(mac, desc, days, window)
+		if err != nil {
+			return err
+		}
+		return f.withClient(ctx, func(c client.Client) error {
+			for _, r := range rules {
+				r, err := c.AddAccessRule(ctx, r)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("added rule %d\n", r.ID)
+			}
+			return nil
+		})
+
+	case "delete", "delete-filter":
+		if err := needArg("ID"); err != nil {
+			return err
+		}
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("invalid ID %q", arg)
+		}
+		return f.withClient(ctx, func(c client.Client) error {
+			if action == "delete" {
+				return c.DeleteAccessRule(ctx, id)
+			}
+			return c.DeleteURLFilter(ctx, id)
+		})
+
+	case "filters":
+		return f.withClient(ctx, func(c client.Client) error {
+			filters, err := c.URLFilters(ctx)
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tTYPE\tENABLED\tVALUE")
+			for _, f := range filters {
+				fmt.Fprintf(w, "%d\t%s\t%v\t%s\n", f.ID, f.Type, f.Enabled,
+					f.Value)
+			}
+			return w.Flush()
+		})
+
+	case "add-filter":
+		if err := needArg("keyword or URL"); err != nil {
+			return err
+		}
+		return f.withClient(ctx, func(c client.Client) error {
+			filter, err := c.AddURLFilter(ctx, client.URLFilter{
+				Type:    client.FilterType(filterType),
+				Value:   arg,
+				Enabled: true,
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Printf("added filter %d\n", filter.ID)
+			return nil
+		})
+
+	case "enable-filter", "disable-filter":
+		if err := needArg("ID"); err != nil {
+			return err
+		}
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("invalid ID %q", arg)
+		}
+		return f.withClient(ctx, func(c client.Client) error {
+			filters, err := c.URLFilters(ctx)
+			if err != nil {
+				return err
+			}
+			i := slices.IndexFunc(filters, func(f client.URLFilter) bool {
+				return f.ID == id
+			})
+			if i < 0 {
+				return fmt.Errorf("no filter with ID %d", id)
+			}
+			filters[i].Enabled = action == "enable-filter"
+			return c.UpdateURLFilter(ctx, filters[i])
+		})
+
+	case "block", "unblock", "resume":
+		if err := needArg("MAC address"); err != nil {
+			return err
+		}
+		return f.withClient(ctx, func(c client.Client) error {
+			if action == "resume" {
+				return c.ResumeDevice(ctx, arg)
+			}
+			return c.BlockDevice(ctx, arg, action == "block")
+		})
+
+	case "pause":
+		if err := needArg("MAC address"); err != nil {
+			return err
+		}
+		end, err := pauseEnd(time.Now(), until, pauseFor)
+		if err != nil {
+			return err
+		}
+		return f.withClient(ctx, func(c client.Client) error {
+			if err := c.PauseDevice(ctx, arg, end); err != nil {
+				return err
+			}
+			if end.IsZero() {
+				fmt.Println("paused until resumed")
+			} else {
+				fmt.Printf("paused until %s; the pause would repeat "+
+					"weekly, so keep cga-gateway or 'cga mqtt' running, or "+
+					"run 'cga parental expire' periodically, to remove it\n",
+					end.Format(time.RFC1123))
+			}
+			return nil
+		})
+
+	case "expire":
+		return f.withClient(ctx, func(c client.Client) error {
+			return c.ExpirePauses(ctx)
+		})
+	}
+
+	fs.Usage()
+	return fmt.Errorf("unknown action %q", action)
+}
+
+func printAccessRules(ctx context.Context, c client.Client) error {
+	rules, err := c.AccessRules(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tMAC\tENABLED\tSCHEDULE\tDESCRIPTION")
+	for _, r := range rules {
+		schedule := "always"
+		if !r.Always {
+			var days []string
+			for _, d := range r.Days {
+				days = append(days, d.String()[:3])
+			}
+			schedule = fmt.Sprintf("%s %s-%s", strings.Join(days, ","),
+				r.Start, r.End)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%v\t%s\t%s\n", r.ID, r.MAC, r.Enabled,
+			schedule, r.Description)
+	}
+	return w.Flush()
+}
+
+// parseAccessRule returns the rules for the flags of the add action. See
+// client.NewScheduleRules for the windows that cross midnight.
+func parseAccessRule(mac, desc, days, window string) ([]client.AccessRule, error) {
+	if days == "" && window == "" {
+		r := client.AccessRule{
+			MAC:         mac,
+			Description: desc,
+			Enabled:     true,
+			Always:      true,
+		}
+		return []client.AccessRule{r}, r.Validate()
+	}
+
+	var weekdays []time.Weekday
+	for _, name := range strings.Split(days, ",") {
+		d, err := parseWeekday(name)
+		if err != nil {
+			return nil, err
+		}
+		weekdays = append(weekdays, d)
+	}
+	startStr, endStr, ok := strings.Cut(window, "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid window %q, expected START-END", window)
+	}
+	start, err := client.ParseTimeOfDay(startStr)
+	if err != nil {
+		return nil, err
+	}
+	end, err := client.ParseTimeOfDay(endStr)
+	if err != nil {
+		return nil, err
+	}
+	return client.NewScheduleRules(mac, desc, weekdays, start, end)
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if len(s) >= 3 && strings.HasPrefix(name, s) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid day %q", s)
+}
+
+// pauseEnd returns the end of a pause from the -until and -for flags. A zero
+// time means indefinitely.
+func pauseEnd(now time.Time, until string, d time.Duration) (time.Time, error) {
+	switch {
+	case until != "" && d != 0:
+		return time.Time{}, errors.New("cannot use both -until and -for")
+	case d < 0:
+		return time.Time{}, fmt.Errorf("invalid -for %s", d)
+	case d > 0:
+		return now.Add(d), nil
+	case until == "":
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, until); err == nil {
+		return t, nil
+	}
+	tod, err := client.ParseTimeOfDay(until)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid -until %q", until)
+	}
+	y, m, day := now.Date()
+	end := time.Date(y, m, day, int(tod)/60, int(tod)%60, 0, 0, now.Location())
+	if !end.After(now) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end, nil
+}
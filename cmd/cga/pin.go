@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/httpdoer"
+)
+
+func runPin(ctx context.Context, args []string) error {
+	var f deviceFlags
+	var yes bool
+	fs := newFlagSet("pin", "")
+	f.register(fs)
+	fs.BoolVar(&yes, "yes", false,
+		"replace the pinned certificate if it changed")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if f.knownHosts == "" {
+		return errors.New("no known hosts file")
+	}
+
+	host, err := f.hostPort()
+	if err != nil {
+		return err
+	}
+	kh := httpdoer.NewKnownHosts(f.knownHosts)
+	pinned, ok, err := kh.Lookup(host)
+	if err != nil {
+		return err
+	}
+
+	got, err := httpdoer.FetchFingerprint(ctx, new(net.Dialer), host)
+	if err != nil {
+		return fmt.Errorf("fetch certificate fingerprint: %w", err)
+	}
+
+	switch {
+	case ok && pinned == got:
+		fmt.Printf("%s is already pinned to %s\n", host, got)
+		return nil
+	case ok && !yes:
+		return fmt.Errorf("certificate of %s changed from %s to %s; make "+
+			"sure the change is intentional and run again with -yes to "+
+			"re-pin it", host, pinned, got)
+	}
+
+	if err := kh.Pin(host, got); err != nil {
+		return err
+	}
+	fmt.Printf("pinned %s to %s in %s\n", host, got, f.knownHosts)
+
+	return nil
+}
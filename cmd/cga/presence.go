@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/presence"
+)
+
+func runPresence(ctx context.Context, args []string) error {
+	var f clientFlags
+	var p presence.Params
+	var macs, execCmd, webhook string
+	fs := newFlagSet("presence", "")
+	f.register(fs)
+	fs.DurationVar(&p.Interval, "interval", presence.DefaultInterval,
+		"time between polls of the hosts table")
+	fs.DurationVar(&p.JoinDebounce, "join-debounce", 0,
+		"time a host must be connected before it joins")
+	fs.DurationVar(&p.LeaveDebounce, "leave-debounce",
+		presence.DefaultLeaveDebounce,
+		"time a host must be disconnected before it leaves")
+	fs.StringVar(&macs, "mac", "",
+		"comma-separated MAC addresses to watch (default all)")
+	fs.StringVar(&execCmd, "exec", "",
+		"shell command to run for each event; see CGA_EVENT, CGA_MAC, "+
+			"CGA_HOSTNAME and CGA_IP")
+	fs.StringVar(&webhook, "webhook", "", "URL to POST each event to as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if macs != "" {
+		p.MACs = strings.Split(macs, ",")
+	}
+	enc := json.NewEncoder(os.Stdout)
+	p.Handlers = append(p.Handlers, presence.HandlerFunc(
+		func(_ context.Context, e presence.Event) error {
+			return enc.Encode(e)
+		}))
+	if execCmd != "" {
+		p.Handlers = append(p.Handlers, presence.Exec("sh", "-c", execCmd))
+	}
+	if webhook != "" {
+		p.Handlers = append(p.Handlers, presence.Webhook(webhook, nil))
+	}
+	p.OnError = func(err error) {
+		fmt.Fprintf(os.Stderr, "cga presence: %v\n", err)
+	}
+
+	return f.withClient(ctx, func(c client.Client) error {
+		p.Client = c
+		w, err := presence.New(p)
+		if err != nil {
+			return err
+		}
+		return w.Run(ctx)
+	})
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/schema"
+)
+
+var errSchemaDrift = errors.New("schema drift detected")
+
+func runSchema(ctx context.Context, args []string) error {
+	var f clientFlags
+	var baselineFile string
+	fs := newFlagSet("schema", "record|check [PATH ...]")
+	f.register(fs)
+	fs.StringVar(&baselineFile, "baseline", "cga-schema.json",
+		"file with the baseline shapes")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return errors.New("missing subcommand")
+	}
+	sub, paths := fs.Arg(0), fs.Args()[1:]
+
+	baseline, err := schema.LoadBaseline(baselineFile)
+	if err != nil {
+		return err
+	}
+
+	switch sub {
+	case "record":
+		if len(paths) == 0 {
+			return errors.New("no paths to record")
+		}
+	case "check":
+		if len(paths) == 0 {
+			paths = baselineGETPaths(baseline)
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("no GET endpoints in baseline %q", baselineFile)
+		}
+	default:
+		return fmt.Errorf("unknown subcommand %q", sub)
+	}
+
+	rec := schema.NewRecorder()
+	p := f.params()
+	p.ResponseObserver = rec
+	err = withClient(ctx, p, func(c client.Client) error {
+		for _, path := range paths {
+			if _, err := c.Raw(ctx, http.MethodGet, path, nil); err != nil {
+				return fmt.Errorf("GET %s: %w", path, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	current := rec.Shapes()
+	// only keep the requested endpoints, the recorder also sees the login
+	for k := range current {
+		method, path, _ := strings.Cut(k, " ")
+		if method != http.MethodGet || !slices.Contains(paths, path) {
+			delete(current, k)
+		}
+	}
+
+	if sub == "record" {
+		maps.Copy(baseline, current)
+		if err := baseline.Save(baselineFile); err != nil {
+			return err
+		}
+		fmt.Printf("recorded %d endpoints in %s\n", len(current), baselineFile)
+		return nil
+	}
+
+	changes := baseline.Compare(current)
+	for _, ec := range changes {
+		fmt.Println(ec.Endpoint)
+		for _, c := range ec.Changes {
+			fmt.Printf("  %v\n", c)
+		}
+	}
+	if len(changes) > 0 {
+		return errSchemaDrift
+	}
+	fmt.Printf("no changes in %d endpoints\n", len(current))
+
+	return nil
+}
+
+func baselineGETPaths(b schema.Baseline) []string {
+	var ret []string
+	for k := range b {
+		if path, ok := strings.CutPrefix(k, http.MethodGet+" "); ok {
+			ret = append(ret, path)
+		}
+	}
+	slices.Sort(ret)
+	return ret
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+)
+
+func runSurvey(ctx context.Context, args []string) error {
+	var f clientFlags
+	var band string
+	var width int
+	var dfs, apply bool
+	fs := newFlagSet("survey", "")
+	f.register(fs)
+	fs.StringVar(&band, "band", client.Band24GHz, "band to scan: 2.4GHz or 5GHz")
+	fs.IntVar(&width, "width", 0,
+		"channel width in MHz (default: the width of the network if the "+
+			"survey reports it, else 20 for 2.4GHz and 80 for 5GHz); -apply "+
+			"doesn't change it")
+	fs.BoolVar(&dfs, "dfs", false, "also recommend 5GHz DFS channels")
+	fs.BoolVar(&apply, "apply", false, "set the recommended channel")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	band, err := client.ParseBand(band)
+	if err != nil {
+		return err
+	}
+
+	return f.withClient(ctx, func(c client.Client) error {
+		networks, err := c.WiFi(ctx)
+		if err != nil {
+			return err
+		}
+		var own []string
+		var primary *client.WiFiNetwork
+		for _, n := range networks {
+			// the neighbors have normalized BSSIDs
+			if mac, err := client.NormalizeMAC(n.BSSID); err == nil {
+				own = append(own, mac)
+			}
+			if n.Band == band && !n.Guest && primary == nil {
+				primary = &n
+			}
+		}
+		if primary == nil {
+			return fmt.Errorf("no %s network", band)
+		}
+
+		neighbors, err := c.SiteSurvey(ctx, band)
+		if err != nil {
+			return err
+		}
+		// the survey also reports the own networks, with their width
+		var ownWidth int
+		if mac, err := client.NormalizeMAC(primary.BSSID); err == nil {
+			i := slices.IndexFunc(neighbors, func(n client.Neighbor) bool {
+				return n.BSSID == mac
+			})
+			if i >= 0 {
+				ownWidth = neighbors[i].Width
+			}
+		}
+		switch {
+		case width == 0:
+			width = ownWidth
+		case !apply || width == ownWidth:
+		case ownWidth == 0:
+			return fmt.Errorf("cannot use -width with -apply, since the "+
+				"width of %s is unknown and -apply doesn't change it",
+				primary.SSID)
+		default:
+			return fmt.Errorf("cannot use -width %d with -apply, since %s "+
+				"uses %d MHz and -apply doesn't change it", width,
+				primary.SSID, ownWidth)
+		}
+		scores, err := client.RecommendChannels(neighbors, client.ChannelParams{
+			Band:    band,
+			Width:   width,
+			DFS:     dfs,
+			Exclude: own,
+		})
+		if err != nil {
+			return err
+		}
+
+		slices.SortFunc(neighbors, func(a, b client.Neighbor) int {
+			return cmp.Compare(b.Signal, a.Signal)
+		})
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SSID\tBSSID\tCHANNEL\tWIDTH\tSIGNAL\tSECURITY")
+		for _, n := range neighbors {
+			if slices.Contains(own, n.BSSID) {
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d MHz\t%d dBm\t%s\n", n.SSID, n.BSSID,
+				n.Channel, n.Width, n.Signal, n.Security)
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "CHANNEL\tNETWORKS\tINTERFERENCE")
+		for _, s := range scores {
+			fmt.Fprintf(w, "%d\t%d\t%.3g mW\n", s.Channel, s.Networks,
+				s.Interference)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if len(scores) == 0 {
+			return fmt.Errorf("no candidate channels for %d MHz", width)
+		}
+
+		best := scores[0].Channel
+		fmt.Printf("\nrecommended channel: %d (current: %d", best, primary.Channel)
+		if primary.AutoChannel {
+			fmt.Print(", automatic")
+		}
+		fmt.Println(")")
+		if !apply {
+			return nil
+		}
+		if best == primary.Channel && !primary.AutoChannel {
+			fmt.Println("already using the recommended channel")
+			return nil
+		}
+		if err := c.SetWiFiChannel(ctx, primary.Index, best); err != nil {
+			return err
+		}
+		fmt.Printf("set channel %d on %s\n", best, primary.SSID)
+		return nil
+	})
+}
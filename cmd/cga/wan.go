@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+)
+
+func runWAN(ctx context.Context, args []string) error {
+	var f clientFlags
+	fs := newFlagSet("wan", "")
+	f.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	return f.withClient(ctx, func(c client.Client) error {
+		st, err := c.WANStatus(ctx)
+		if err != nil {
+			return err
+		}
+		prov, err := c.ProvisioningStatus(ctx)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "Public address:\t%s\n", optional(st.Address))
+		fmt.Fprintf(w, "Gateway:\t%s\n", optional(st.Gateway))
+		fmt.Fprintf(w, "DNS servers:\t%s\n", joinStrings(st.DNSServers))
+		fmt.Fprintf(w, "WAN uptime:\t%s\n", st.Uptime)
+		fmt.Fprintf(w, "CM state:\t%s\n", prov.State)
+		fmt.Fprintf(w, "DOCSIS registration:\t%s\n", prov.Registration)
+		fmt.Fprintf(w, "Ranging:\t%s\n", prov.Ranging)
+		fmt.Fprintf(w, "BPI enabled:\t%t\n", prov.BPIEnabled)
+		fmt.Fprintf(w, "Config file:\t%s\n", prov.ConfigFile)
+		fmt.Fprintf(w, "ToD:\t%s\n", prov.ToD)
+		fmt.Fprintf(w, "CM address:\t%s\n", optional(prov.CMAddress))
+		return w.Flush()
+	})
+}
@@ -0,0 +1,192 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/trace"
+)
+
+// FeatureBridgeMode is switching between router and bridge mode.
+const FeatureBridgeMode Feature = "bridge_mode"
+
+// DefaultBridgeModeTimeout is how long EnableBridgeMode and DisableBridgeMode
+// wait by default for the device to answer after the switch, which reboots it.
+const DefaultBridgeModeTimeout = 5 * time.Minute
+
+// BridgeManagementAddress is the address the device answers at in bridge
+// mode, like most cable modems.
+var BridgeManagementAddress = netip.MustParseAddr("192.168.100.1")
+
+// BridgeModeLosses are the features that stop working in bridge mode.
+var BridgeModeLosses = []string{
+	"routing, NAT and the firewall: the device connected to the LAN port " +
+		"gets a public address and must protect itself",
+	"the Wi-Fi networks, including the guest network",
+	"the DHCP server and its static reservations",
+	"parental control, the MAC filters and the IPv6 pinholes",
+	"usually, all the LAN ports except the first one",
+	"the web UI and this client at the LAN address: the device is only " +
+		"reachable at " + BridgeManagementAddress.String() + ", from a " +
+		"computer with a static address in 192.168.100.0/24",
+}
+
+// ErrNotConfirmed is returned by EnableBridgeMode when it is not confirmed.
+var ErrNotConfirmed = errors.New("bridge mode not confirmed")
+
+// BridgeModeConfirmation is the confirmation required by EnableBridgeMode. Its
+// zero value is not a confirmation; see ConfirmBridgeMode.
+type BridgeModeConfirmation struct {
+	confirmed bool
+}
+
+// ConfirmBridgeMode returns the confirmation for EnableBridgeMode. Use it only
+// after the user accepted the BridgeModeLosses, e.g. after showing them.
+func ConfirmBridgeMode() BridgeModeConfirmation {
+	return BridgeModeConfirmation{confirmed: true}
+}
+
+var (
+	endpointBridgeMode = Endpoint[NoData, bridgeModeData]{
+		Method: http.MethodGet,
+		Path:   "/api/v1/lan/bridge",
+	}
+	endpointSetBridgeMode = Endpoint[bridgeModeData, NoData]{
+		Method:   http.MethodPost,
+		Path:     "/api/v1/lan/bridge",
+		Encoding: EncodingForm,
+	}
+)
+
+type bridgeModeData struct {
+	BridgeMode flexBool `json:"BridgeMode" form:"BridgeMode"`
+}
+
+// BridgeModeError is returned when the client can't log in after switching the
+// bridge mode. Its message explains how to recover access to the device.
+type BridgeModeError struct {
+	// Enabled is the mode that was set.
+	Enabled bool
+	// URL is where the device was expected to answer.
+	URL string
+	// Answered reports whether the device answered at URL, which means that
+	// the switch was applied. Otherwise, it is unknown.
+	Answered bool
+	Err      error
+}
+
+func (e *BridgeModeError) Error() string {
+	mode := "router"
+	if e.Enabled {
+		mode = "bridge"
+	}
+	var b strings.Builder
+	if e.Answered {
+		fmt.Fprintf(&b, "the device switched to %s mode, since it answers at "+
+			"%s, but logging in there failed: %v\n", mode, e.URL, e.Err)
+	} else {
+		fmt.Fprintf(&b, "the switch to %s mode was sent, but the device did "+
+			"not answer at %s, so it is unknown whether it was applied: %v\n",
+			mode, e.URL, e.Err)
+	}
+	if e.Enabled {
+		writeRecovery(&b,
+			"connect this computer to the first LAN port of the device with "+
+				"a cable, since the Wi-Fi is off",
+			"configure the static address 192.168.100.2/24 in this computer, "+
+				"and connect to "+e.URL,
+		)
+	} else {
+		writeRecovery(&b,
+			"renew the DHCP lease of this computer, e.g. by reconnecting it "+
+				"to the network, and connect to "+e.URL,
+			"if the LAN address of the device was changed, connect to that "+
+				"address instead",
+		)
+	}
+	return b.String()
+}
+
+func (e *BridgeModeError) Unwrap() error { return e.Err }
+
+func (c *client) BridgeMode(ctx context.Context) (bool, error) {
+	res, err := callFeature(ctx, c, FeatureBridgeMode, endpointBridgeMode,
+		NoData{})
+	if err != nil {
+		return false, err
+	}
+	return bool(res.Data.BridgeMode), nil
+}
+
+func (c *client) EnableBridgeMode(ctx context.Context, confirmation BridgeModeConfirmation, timeout time.Duration) (err error) {
+	ctx, span := c.startSpan(ctx, "client.EnableBridgeMode")
+	defer func() { trace.End(span, err) }()
+
+	if !confirmation.confirmed {
+		return ErrNotConfirmed
+	}
+	url, err := replaceURLHost(c.CurrentBaseURL(), BridgeManagementAddress)
+	if err != nil {
+		return err
+	}
+	return c.setBridgeMode(ctx, true, url, timeout)
+}
+
+func (c *client) DisableBridgeMode(ctx context.Context, lanURL string, timeout time.Duration) (err error) {
+	ctx, span := c.startSpan(ctx, "client.DisableBridgeMode")
+	defer func() { trace.End(span, err) }()
+
+	if lanURL == "" {
+		lanURL = DefaultBaseURL
+	}
+	return c.setBridgeMode(ctx, false, lanURL, timeout)
+}
+
+// setBridgeMode switches the mode and waits for the device at url. The errors
+// tell whether the switch was applied.
+func (c *client) setBridgeMode(
+	ctx context.Context,
+	enable bool,
+	url string,
+	timeout time.Duration,
+) error {
+	if timeout <= 0 {
+		timeout = DefaultBridgeModeTimeout
+	}
+
+	enabled, err := c.BridgeMode(ctx)
+	if err != nil {
+		return err
+	}
+	if enabled == enable {
+		return nil
+	}
+
+	err = c.applyLosingConnection(ctx, func(ctx context.Context) error {
+		_, err := callFeature(ctx, c, FeatureBridgeMode, endpointSetBridgeMode,
+			bridgeModeData{BridgeMode: flexBool(enable)})
+		return err
+	})
+	switch {
+	case ctx.Err() != nil:
+		return fmt.Errorf("the switch may have been applied: %w", err)
+	case err != nil:
+		return fmt.Errorf("the switch was not applied: %w", err)
+	}
+	answered, err := c.retarget(ctx, url, timeout)
+	if err != nil {
+		return &BridgeModeError{
+			Enabled:  enable,
+			URL:      url,
+			Answered: answered,
+			Err:      err,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBridgeMode(t *testing.T) {
+	t.Parallel()
+
+	lanAddr := netip.MustParseAddr("192.168.0.1")
+	d, c := newMovingDevice(t, lanAddr, Params{})
+	var mu sync.Mutex
+	bridge, lost := false, false
+	d.handle("GET /api/v1/lan/bridge", func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		writeData(w, map[string]any{"BridgeMode": bridge})
+	})
+	d.handle("POST /api/v1/lan/bridge", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		bridge = r.FormValue("BridgeMode") == "true"
+		switch {
+		case lost:
+			d.moveTo(netip.Addr{})
+		case bridge:
+			d.moveTo(BridgeManagementAddress)
+		default:
+			d.moveTo(lanAddr)
+		}
+		writeData(w, nil)
+	})
+	ctx := context.Background()
+
+	if err := c.EnableBridgeMode(ctx, BridgeModeConfirmation{}, time.Second); !errors.Is(err, ErrNotConfirmed) {
+		t.Fatalf("expected ErrNotConfirmed, got %v", err)
+	}
+	if enabled, err := c.BridgeMode(ctx); err != nil || enabled {
+		t.Fatalf("bridge mode enabled without confirmation: %v", err)
+	}
+
+	if err := c.EnableBridgeMode(ctx, ConfirmBridgeMode(), time.Second); err != nil {
+		t.Fatalf("enable bridge mode: %v", err)
+	}
+	if got, want := c.CurrentBaseURL(), d.url(BridgeManagementAddress); got != want {
+		t.Fatalf("unexpected base URL %q, want %q", got, want)
+	}
+	if enabled, err := c.BridgeMode(ctx); err != nil || !enabled {
+		t.Fatalf("bridge mode not enabled: %v", err)
+	}
+
+	if err := c.DisableBridgeMode(ctx, d.url(lanAddr), time.Second); err != nil {
+		t.Fatalf("disable bridge mode: %v", err)
+	}
+	if got := c.CurrentBaseURL(); got != d.url(lanAddr) {
+		t.Fatalf("unexpected base URL %q", got)
+	}
+
+	// the device is lost after the switch
+	mu.Lock()
+	lost = true
+	mu.Unlock()
+	err := c.EnableBridgeMode(ctx, ConfirmBridgeMode(), 100*time.Millisecond)
+	var bridgeErr *BridgeModeError
+	if !errors.As(err, &bridgeErr) || !bridgeErr.Enabled || bridgeErr.Answered ||
+		!strings.Contains(err.Error(), "192.168.100.2/24") ||
+		!strings.Contains(err.Error(), "unknown whether it was applied") {
+		t.Fatalf("expected *BridgeModeError, got %v", err)
+	}
+}
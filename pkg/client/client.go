@@ -1,19 +1,21 @@
 package client
 
 import (
-	"bytes"
 	"cmp"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
+	"iter"
 	"net/http"
 	"net/http/cookiejar"
-	"strings"
+	"net/netip"
+	"net/url"
 	"sync"
+	"time"
 
 	"github.com/diegommm/technicolor-cga4233tch3/pkg/httpdoer"
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/trace"
 )
 
 const (
@@ -31,6 +33,175 @@ type Client interface {
 	Login(context.Context) error
 	Logout(context.Context) error
 	SetAuth(ctx context.Context, user, pass string) error
+
+	// Raw performs an authenticated request to an arbitrary API path, logging
+	// in first if needed. The form is sent in the query string for GET
+	// requests, and in the body otherwise.
+	Raw(ctx context.Context, method, path string, form url.Values) (*Response[json.RawMessage], error)
+
+	// DeviceInfo returns the identification of the device and its firmware.
+	// It is fetched at login, or when first requested. Not all the users can
+	// read it, so an error is also kept until the next login.
+	DeviceInfo(ctx context.Context) (*DeviceInfo, error)
+
+	// Channels returns the DOCSIS downstream and upstream channels.
+	Channels(ctx context.Context) (*Channels, error)
+	// Hosts returns the hosts known to the device, active or not.
+	Hosts(ctx context.Context) ([]Host, error)
+	// WiFi returns the Wi-Fi networks of the device.
+	WiFi(ctx context.Context) ([]WiFiNetwork, error)
+	// GuestWiFi returns the guest Wi-Fi networks, or ErrNoGuestWiFi if there
+	// are none.
+	GuestWiFi(ctx context.Context) ([]GuestWiFi, error)
+	// ConfigureGuestWiFi changes the settings of all the guest Wi-Fi
+	// networks.
+	ConfigureGuestWiFi(ctx context.Context, s GuestWiFiSettings) error
+	// SetGuestWiFi enables or disables all the guest Wi-Fi networks.
+	SetGuestWiFi(ctx context.Context, enabled bool) error
+	// AccessRules returns the access control rules, which block the Internet
+	// access of LAN devices. The rules of the pauses that already ended are
+	// left out, although they apply again the next week until removed with
+	// ExpirePauses.
+	AccessRules(ctx context.Context) ([]AccessRule, error)
+	// AddAccessRule adds a rule and returns it with its ID.
+	AddAccessRule(ctx context.Context, r AccessRule) (AccessRule, error)
+	UpdateAccessRule(ctx context.Context, r AccessRule) error
+	DeleteAccessRule(ctx context.Context, id int) error
+	// URLFilters returns the keyword and URL filters.
+	URLFilters(ctx context.Context) ([]URLFilter, error)
+	// AddURLFilter adds a filter and returns it with its ID.
+	AddURLFilter(ctx context.Context, f URLFilter) (URLFilter, error)
+	// UpdateURLFilter changes the filter with the ID of f, e.g. to enable or
+	// disable it.
+	UpdateURLFilter(ctx context.Context, f URLFilter) error
+	DeleteURLFilter(ctx context.Context, id int) error
+
+	// SiteSurvey scans the neighboring Wi-Fi networks in the band. See
+	// RecommendChannels.
+	SiteSurvey(ctx context.Context, band string) ([]Neighbor, error)
+	// SetWiFiChannel sets the channel of the Wi-Fi network with the given
+	// index, or enables the automatic channel selection if channel is zero.
+	// Only that network is updated; use the primary network of the band,
+	// since the channel belongs to the radio of the band. The channel width
+	// is not changed.
+	SetWiFiChannel(ctx context.Context, index, channel int) error
+
+	// MACFilter returns the MAC filter of the Wi-Fi network with the given
+	// index.
+	MACFilter(ctx context.Context, index int) (*MACFilter, error)
+	SetMACFilterMode(ctx context.Context, index int, mode MACFilterMode) error
+	AddMACFilterEntry(ctx context.Context, index int, e MACFilterEntry) error
+	DeleteMACFilterEntry(ctx context.Context, index int, mac string) error
+	// SyncMACFilter makes the MAC filter list of the network match the given
+	// entries and, if mode is not empty, sets its mode.
+	SyncMACFilter(ctx context.Context, index int, mode MACFilterMode, entries []MACFilterEntry) (*MACFilterSync, error)
+
+	// LAN returns the LAN address and subnet of the device, e.g.
+	// 192.168.0.1/24.
+	LAN(ctx context.Context) (netip.Prefix, error)
+	// SetLAN changes the LAN address and subnet of the device. The client
+	// then targets the new address, copying the pinned certificate of the
+	// old one, and logs in again, waiting up to timeout for the device to
+	// answer. If it doesn't, a *LANChangeError explains how to recover.
+	SetLAN(ctx context.Context, addr netip.Prefix, timeout time.Duration) error
+
+	// WANStatus returns the state of the Internet connection.
+	WANStatus(ctx context.Context) (*WANStatus, error)
+	// ProvisioningStatus returns the state of the cable modem in the DOCSIS
+	// network of the ISP.
+	ProvisioningStatus(ctx context.Context) (*ProvisioningStatus, error)
+
+	// IPv6WAN returns the IPv6 state of the WAN interface.
+	IPv6WAN(ctx context.Context) (*IPv6WAN, error)
+	// IPv6LAN returns the IPv6 settings of the LAN.
+	IPv6LAN(ctx context.Context) (*IPv6LAN, error)
+	// SetIPv6LAN changes the IPv6 settings of the LAN. The read-only fields
+	// are ignored.
+	SetIPv6LAN(ctx context.Context, l IPv6LAN) error
+	// Pinholes returns the IPv6 firewall pinholes.
+	Pinholes(ctx context.Context) ([]Pinhole, error)
+	// AddPinhole adds a pinhole and returns it with its ID.
+	AddPinhole(ctx context.Context, p Pinhole) (Pinhole, error)
+	UpdatePinhole(ctx context.Context, p Pinhole) error
+	DeletePinhole(ctx context.Context, id int) error
+
+	// BridgeMode reports whether the device is in bridge mode.
+	BridgeMode(ctx context.Context) (bool, error)
+	// EnableBridgeMode switches the device to bridge mode, after which it
+	// only answers at BridgeManagementAddress and BridgeModeLosses stop
+	// working. It fails with ErrNotConfirmed unless given ConfirmBridgeMode.
+	// The client then targets the management address, waiting up to timeout
+	// for the device to answer there before logging in again. If that fails,
+	// a *BridgeModeError tells whether the switch was applied and how to
+	// recover.
+	EnableBridgeMode(ctx context.Context, confirmation BridgeModeConfirmation, timeout time.Duration) error
+	// DisableBridgeMode switches the device back to router mode. The client
+	// then targets lanURL, or DefaultBaseURL if empty, like
+	// EnableBridgeMode.
+	DisableBridgeMode(ctx context.Context, lanURL string, timeout time.Duration) error
+
+	// DHCP returns the settings of the LAN DHCP server.
+	DHCP(ctx context.Context) (*DHCPSettings, error)
+	// SetDHCP changes the settings of the LAN DHCP server. The router
+	// address and subnet are ignored. It fails with a
+	// *ReservationConflictError if a reservation would be in the new pool.
+	SetDHCP(ctx context.Context, s DHCPSettings) error
+	// Reservations returns the static DHCP leases.
+	Reservations(ctx context.Context) ([]Reservation, error)
+	// SetReservation adds a static lease, or replaces the one for the same
+	// MAC address. It fails with a *ReservationConflictError if the address
+	// is in the pool or reserved for another MAC address.
+	SetReservation(ctx context.Context, r Reservation) error
+	DeleteReservation(ctx context.Context, mac string) error
+	// ImportReservations sets all the given static leases, after checking
+	// that none of them conflicts. Reservations for other MAC addresses are
+	// kept. The reservations that change IP are deleted and added again, so
+	// that IPs can be swapped.
+	ImportReservations(ctx context.Context, rs []Reservation) (*ReservationImport, error)
+
+	// BlockDevice blocks or unblocks the Internet access of the LAN device
+	// with the given MAC address, with an access rule that always applies.
+	// Unblocking removes all the rules for which AccessRule.Blocks is true,
+	// not only the ones added by BlockDevice.
+	BlockDevice(ctx context.Context, mac string, block bool) error
+	// PauseDevice blocks the Internet access of the LAN device until the
+	// given time, which must be within the next 6 days, or indefinitely if
+	// it is zero. Since access rules repeat weekly, the expired pauses must
+	// be removed with ExpirePauses, which should be called periodically.
+	PauseDevice(ctx context.Context, mac string, until time.Time) error
+	// ResumeDevice removes the pauses and blocks of the LAN device. See
+	// BlockDevice.
+	ResumeDevice(ctx context.Context, mac string) error
+	// ExpirePauses removes the rules of the pauses that already ended.
+	ExpirePauses(ctx context.Context) error
+	// Ping pings a host from the device, yielding each reply as the device
+	// reports it. The iteration ends when the ping completes, with an error
+	// that can be a *DiagnosticError, or when ctx is canceled, which stops
+	// the ping.
+	Ping(ctx context.Context, p PingParams) iter.Seq2[PingReply, error]
+	// Traceroute traces the route to a host from the device, yielding each
+	// hop like Ping.
+	Traceroute(ctx context.Context, p TracerouteParams) iter.Seq2[TracerouteHop, error]
+	// LookupHost looks up a host name from the device, yielding each address
+	// like Ping.
+	LookupHost(ctx context.Context, p DNSParams) iter.Seq2[DNSAnswer, error]
+
+	// Reboot restarts the device. The session is lost.
+	Reboot(ctx context.Context) error
+
+	// CurrentBaseURL returns the base URL the device is reached at, which
+	// starts as Params.BaseURL and changes with SetLAN.
+	CurrentBaseURL() string
+
+	// Fetch performs an authenticated GET of an arbitrary path, like the web
+	// UI assets, and returns the body as is.
+	Fetch(ctx context.Context, path string) ([]byte, error)
+}
+
+// ResponseObserver is called with the raw body of the API responses. It must
+// not retain the body. Implementations must be safe for concurrent use.
+type ResponseObserver interface {
+	ObserveResponse(method, path string, body []byte)
 }
 
 type Params struct {
@@ -42,11 +213,68 @@ type Params struct {
 	TryDefaultAuthFirst              bool
 	SetAuthIfDefault                 bool
 	TLSVerify                        bool
+
+	// Transport configures the transport used when HTTPDoer is nil. Its
+	// InsecureSkipVerify is forced to true if TLSVerify is false.
+	Transport httpdoer.TransportParams
+
+	// KnownHostsFile enables trust-on-first-use pinning of the device
+	// certificate, using the given file to record it. It is ignored if
+	// HTTPDoer or Transport.KnownHosts are set.
+	KnownHostsFile string
+
+	// MaxBodySize is the maximum size of a response body. See
+	// httpdoer.BufferParams.
+	MaxBodySize int64
+
+	// Metrics, if set, receives the metrics of each request to the device.
+	Metrics httpdoer.MetricsRecorder
+
+	// ResponseObserver, if set, receives the body of every API response that
+	// is not an error, even if it could not be decoded. See schema.Recorder.
+	ResponseObserver ResponseObserver
+
+	// Tracer, if set, receives spans for the client operations and for each
+	// hop of the HTTP doer chain.
+	Tracer trace.Tracer
+
+	// Debug, if set, receives a dump of each request and response.
+	Debug io.Writer
+
+	// Limiter controls how many requests are sent concurrently to the device
+	// and how far apart. Share the same Limiter between clients of the same
+	// device. If nil, requests are serialized.
+	Limiter *httpdoer.Limiter
+
+	// Breaker, if set, makes requests fail fast with an
+	// *httpdoer.CircuitOpenError while the device is failing.
+	Breaker *httpdoer.Breaker
+
+	// DiagnosticsPollInterval is the interval between the polls of the
+	// results of Ping, Traceroute and LookupHost. Defaults to
+	// DefaultDiagnosticsPollInterval.
+	DiagnosticsPollInterval time.Duration
+
+	// Location is the time zone of the device, in which it applies the
+	// scheduled access rules. Defaults to time.Local.
+	Location *time.Location
 }
 
 func (p Params) WithDefaults() Params {
-	if p.HTTPDoer == nil {
-		p.HTTPDoer = httpdoer.New(!p.TLSVerify)
+	if !p.TLSVerify {
+		p.Transport.InsecureSkipVerify = true
+	}
+	if p.Limiter == nil {
+		p.Limiter = httpdoer.NewLimiter(httpdoer.LimitParams{})
+	}
+	if p.Tracer == nil {
+		p.Tracer = trace.Nop()
+	}
+	if p.DiagnosticsPollInterval <= 0 {
+		p.DiagnosticsPollInterval = DefaultDiagnosticsPollInterval
+	}
+	if p.Location == nil {
+		p.Location = time.Local
 	}
 	p.BaseURL = cmp.Or(p.BaseURL, DefaultBaseURL)
 	p.UserAgent = cmp.Or(p.UserAgent, DefaultUserAgent)
@@ -67,28 +295,50 @@ func (p Params) WithDefaults() Params {
 func New(p Params) (Client, error) {
 	p = p.WithDefaults()
 
+	if p.HTTPDoer == nil {
+		if p.KnownHostsFile != "" && p.Transport.KnownHosts == nil {
+			p.Transport.KnownHosts = httpdoer.NewKnownHosts(p.KnownHostsFile)
+		}
+		d, err := httpdoer.New(p.Transport)
+		if err != nil {
+			return nil, fmt.Errorf("create HTTP doer: %w", err)
+		}
+		p.HTTPDoer = d
+	}
+	p.HTTPDoer = httpdoer.Trace(p.HTTPDoer, p.Tracer, "httpdoer.transport")
+
+	p.HTTPDoer = httpdoer.BufferBody(p.HTTPDoer, httpdoer.BufferParams{
+		MaxBodySize: p.MaxBodySize,
+	})
+	p.HTTPDoer = httpdoer.Trace(p.HTTPDoer, p.Tracer, "httpdoer.buffer")
+	// outside the buffering, so that it only dumps the bounded bodies, and
+	// inside the headers, so that it dumps them as sent
+	if p.Debug != nil {
+		p.HTTPDoer = httpdoer.Debug(p.HTTPDoer, p.Debug)
+	}
 	p.HTTPDoer = httpdoer.SetHeaders(p.HTTPDoer, httpdoer.KeyValue{
-		httpdoer.HeaderNameUserAgent:   p.UserAgent,
-		httpdoer.HeaderNameContentType: httpdoer.ContentTypeFormURLEncoded,
+		httpdoer.HeaderNameUserAgent: p.UserAgent,
 	}.ToHTTPHeader())
 	p.HTTPDoer = httpdoer.RemoveContentTypeIfNoBody(p.HTTPDoer)
-	p.HTTPDoer = httpdoer.BufferAndCloseBody(p.HTTPDoer)
+	if p.Breaker != nil {
+		p.HTTPDoer = httpdoer.WithBreaker(p.HTTPDoer, p.Breaker)
+	}
+	// outside the breaker, so that the requests it rejects are counted
+	if p.Metrics != nil {
+		p.HTTPDoer = httpdoer.Metrics(p.HTTPDoer, p.Metrics)
+	}
+	p.HTTPDoer = httpdoer.Limit(p.HTTPDoer, p.Limiter)
+	p.HTTPDoer = httpdoer.Trace(p.HTTPDoer, p.Tracer, "httpdoer.limit")
 	cj, err := cookiejar.New(nil)
 	if err != nil {
 		return nil, fmt.Errorf("create cookie jar: %w", err)
 	}
 	p.HTTPDoer = httpdoer.WithCookieJar(p.HTTPDoer, cj)
 
-	//debug
-	origDoer := p.HTTPDoer
-	p.HTTPDoer = httpdoer.HTTPDoerFunc(func(req *http.Request) (*http.Response, error) {
-		fmt.Printf("> %v %v\n", req.Method, req.URL)
-		return origDoer.Do(req)
-	})
-
 	return &client{
-		Params: p,
-		cj:     cj,
+		Params:     p,
+		cj:         cj,
+		currentURL: p.BaseURL,
 	}, nil
 }
 
@@ -98,100 +348,111 @@ type client struct {
 
 	loginResponseMu sync.RWMutex
 	loginResponse   *loginResponse
+
+	// sessionMu serializes the automatic logins of authenticated calls
+	sessionMu sync.Mutex
+
+	deviceInfoMu sync.RWMutex
+	deviceInfo   *DeviceInfo
+	// deviceInfoErr is the error fetching deviceInfo, cached since it is
+	// usually a permanent lack of permission
+	deviceInfoErr error
+
+	// currentURL starts as BaseURL, and changes with the LAN address
+	currentURLMu sync.RWMutex
+	currentURL   string
+}
+
+func (c *client) CurrentBaseURL() string {
+	c.currentURLMu.RLock()
+	defer c.currentURLMu.RUnlock()
+	return c.currentURL
+}
+
+func (c *client) setCurrentBaseURL(u string) {
+	c.currentURLMu.Lock()
+	defer c.currentURLMu.Unlock()
+	c.currentURL = u
+}
+
+// forgetSession drops the session and the cached device information, which
+// don't survive a reboot.
+func (c *client) forgetSession() {
+	c.deviceInfoMu.Lock()
+	c.deviceInfo, c.deviceInfoErr = nil, nil
+	c.deviceInfoMu.Unlock()
+	c.storeLoginResponse(nil)
+}
+
+func (c *client) startSpan(
+	ctx context.Context,
+	name string,
+	attrs ...trace.Attribute,
+) (context.Context, trace.Span) {
+	return trace.StartWithMeta(ctx, c.Tracer, name, attrs...)
 }
 
 func (c *client) storeLoginResponse(res *loginResponse) error {
-	if !c.loginResponseMu.TryLock() {
-		return errors.New("could not acquire lock to store login response")
-	}
+	c.loginResponseMu.Lock()
 	defer c.loginResponseMu.Unlock()
 	c.loginResponse = res
 	return nil
 }
 
 func (c *client) loadLoginResponse() (*loginResponse, error) {
-	if !c.loginResponseMu.TryRLock() {
-		return nil, errors.New("could not acquire lock to load login response")
-	}
+	c.loginResponseMu.RLock()
 	defer c.loginResponseMu.RUnlock()
 	return c.loginResponse, nil
 }
 
-func (c *client) doAndDecode(
-	ctx context.Context,
-	method string,
-	endpoint string,
-	body io.Reader,
-	resPtr any,
-) ([]byte, error) {
-	// build request
-	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+endpoint, body)
-	if err != nil {
-		return nil, fmt.Errorf("build request: %w", err)
+func (c *client) callLogin(ctx context.Context, user, pass string) (*loginResponse, error) {
+	stage := "authenticate"
+	if pass == "seeksalthash" {
+		stage = "seeksalthash"
 	}
+	ctx, span := c.startSpan(ctx, "client.login."+stage)
+	defer span.End()
 
-	// do request
-	res, err := c.HTTPDoer.Do(req)
+	res, err := call(ctx, c, endpointLogin, loginRequest{
+		Username: user,
+		Password: pass,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("perform request: %w", err)
+		return nil, err
 	}
-	resBytes := res.Body.(httpdoer.ReadNopCloser).Reader.(*bytes.Buffer).Bytes()
 
-	if resPtr != nil {
-		// decode response
-		if err := json.Unmarshal(resBytes, &resPtr); err != nil {
-			return nil, fmt.Errorf("decode JSON body: %w; raw response: %s",
-				err, resBytes)
-		}
-
-		// validate response
-		v, _ := resPtr.(interface{ Validate() error })
-		if err := v.Validate(); err != nil {
-			return nil, fmt.Errorf("validate response: %v", err)
-		}
+	var loginRes loginResponse
+	if err := json.Unmarshal(res.Raw, &loginRes); err != nil {
+		return nil, fmt.Errorf("decode login response: %w", err)
 	}
 
-	return resBytes, nil
+	return &loginRes, nil
 }
 
-func (c *client) callLogin(ctx context.Context, user, pass string) (*loginResponse, error) {
-	// build request
-	body := strings.NewReader(httpdoer.KeyValue{
-		"username": user,
-		"password": pass,
-	}.ToURLValues().Encode())
+func (c *client) login(ctx context.Context, user, pass string) (err error) {
+	ctx, span := c.startSpan(ctx, "client.login", trace.String("user", user))
+	defer func() { trace.End(span, err) }()
 
-	// do an decode
-	var res loginResponse
-	_, err := c.doAndDecode(ctx, http.MethodPost,
-		"/api/v1/session/login", body, &res)
-	if err != nil {
-		return nil, err
-	}
-
-	return &res, nil
-}
-
-func (c *client) login(ctx context.Context, user, pass string) error {
 	res, err := c.callLogin(ctx, user, "seeksalthash")
 	if err != nil {
 		return fmt.Errorf("calling login to seek salt hash: %w", err)
 	}
-	pass = DefaultDerivePasswordWebUI([]byte(pass), res.Salt,
-		res.SaltWebUI)
-	res2, err := c.callLogin(ctx, user, pass)
-	if err != nil {
+	_, deriveSpan := c.startSpan(ctx, "client.login.derive")
+	pass = DefaultDerivePasswordWebUI([]byte(pass), []byte(res.Salt),
+		[]byte(res.SaltWebUI))
+	deriveSpan.End()
+	if _, err := c.callLogin(ctx, user, pass); err != nil {
 		return err
 	}
-	res2.Salt = res.Salt
-	res2.SaltWebUI = res.SaltWebUI
 
 	return c.storeLoginResponse(res)
 }
 
-func (c *client) Logout(ctx context.Context) error {
-	_, err := c.doAndDecode(ctx, http.MethodPost, "/api/v1/session/logout", nil,
-		nil)
+func (c *client) Logout(ctx context.Context) (err error) {
+	ctx, span := c.startSpan(ctx, "client.Logout")
+	defer func() { trace.End(span, err) }()
+
+	_, err = call(ctx, c, endpointLogout, NoData{})
 	if err != nil {
 		return err
 	}
@@ -203,19 +464,23 @@ func (c *client) callSetAuth(
 	newUser string,
 	newPass string,
 	oldPass string,
-	loginSalt []byte,
+	loginSalt string,
 ) error {
 	// build request
+	_, bodySpan := c.startSpan(ctx, "client.setauth.body")
 	body, err := defaultNewPasswordChangeRequestBody(newUser, oldPass, newPass,
-		loginSalt)
+		[]byte(loginSalt))
+	bodySpan.End()
 	if err != nil {
 		return fmt.Errorf("generate password change body: %w", err)
 	}
+	form, err := url.ParseQuery(body)
+	if err != nil {
+		return fmt.Errorf("parse password change body: %w", err)
+	}
 
 	// do an decode
-	var res loginResponse
-	_, err = c.doAndDecode(ctx, http.MethodPost, "/api/v1/changepassword",
-		strings.NewReader(body), &res)
+	_, err = call(ctx, c, endpointChangePassword, form)
 	if err != nil {
 		return fmt.Errorf("call change password: %w", err)
 	}
@@ -223,7 +488,10 @@ func (c *client) callSetAuth(
 	return nil
 }
 
-func (c *client) SetAuth(ctx context.Context, user, pass string) error {
+func (c *client) SetAuth(ctx context.Context, user, pass string) (err error) {
+	ctx, span := c.startSpan(ctx, "client.SetAuth", trace.String("user", user))
+	defer func() { trace.End(span, err) }()
+
 	if err := c.login(ctx, c.Username, c.Password); err != nil {
 		return err
 	}
@@ -243,7 +511,10 @@ func (c *client) SetAuth(ctx context.Context, user, pass string) error {
 	return nil
 }
 
-func (c *client) Login(ctx context.Context) error {
+func (c *client) Login(ctx context.Context) (err error) {
+	ctx, span := c.startSpan(ctx, "client.Login")
+	defer func() { trace.End(span, err) }()
+
 	if c.TryDefaultAuthFirst {
 		err := c.login(ctx, c.DefaultUsername, c.DefaultPassword)
 		if err == nil {
@@ -258,5 +529,15 @@ func (c *client) Login(ctx context.Context) error {
 			}
 		}
 	}
-	return c.login(ctx, c.Username, c.Password)
+	if err := c.login(ctx, c.Username, c.Password); err != nil {
+		return err
+	}
+
+	// the device information is refreshed in every login since the firmware
+	// may have been upgraded, but not all users can read it
+	if _, err := c.fetchDeviceInfo(ctx); err != nil {
+		span.RecordError(fmt.Errorf("fetch device information: %w", err))
+	}
+
+	return nil
 }
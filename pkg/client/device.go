@@ -0,0 +1,306 @@
+package client
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	endpointSystemInfo = Endpoint[NoData, systemInfoData]{
+		Method: http.MethodGet,
+		Path: "/api/v1/system/ModelName,HardwareVersion,SoftwareVersion," +
+			"BootloaderVersion,SerialNumber,CMMACAddress,UpTime",
+	}
+	endpointSessionMenu = Endpoint[NoData, []MenuItem]{
+		Method: http.MethodGet,
+		Path:   "/api/v1/session/menu",
+	}
+)
+
+type systemInfoData struct {
+	ModelName         flexString `json:"ModelName"`
+	HardwareVersion   flexString `json:"HardwareVersion"`
+	SoftwareVersion   flexString `json:"SoftwareVersion"`
+	BootloaderVersion flexString `json:"BootloaderVersion"`
+	SerialNumber      flexString `json:"SerialNumber"`
+	CMMACAddress      flexString `json:"CMMACAddress"`
+	UpTime            flexInt    `json:"UpTime"` // seconds
+}
+
+// MenuItem is an entry of the web UI menu available to the session. The menu
+// reflects the permissions of the logged in user.
+type MenuItem struct {
+	ID    string     `json:"id"`
+	Name  string     `json:"name"`
+	URL   string     `json:"url"`
+	Items []MenuItem `json:"items"`
+}
+
+// DeviceInfo identifies the device and its firmware.
+type DeviceInfo struct {
+	Model             string
+	HardwareVersion   string
+	FirmwareVersion   FirmwareVersion
+	BootloaderVersion string
+	SerialNumber      string
+	CMMACAddress      string
+	// Uptime is the uptime at the time the information was fetched.
+	Uptime time.Duration
+	// Menu is the web UI menu available to the session.
+	Menu []MenuItem
+	// FetchedAt is when the information was fetched.
+	FetchedAt time.Time
+}
+
+// CurrentUptime estimates the current uptime from the one fetched.
+func (d *DeviceInfo) CurrentUptime() time.Duration {
+	return d.Uptime + time.Since(d.FetchedAt)
+}
+
+// HasMenuItem reports whether the menu has an item with the given ID, at any
+// depth.
+func (d *DeviceInfo) HasMenuItem(id string) bool {
+	var find func([]MenuItem) bool
+	find = func(items []MenuItem) bool {
+		return slices.ContainsFunc(items, func(i MenuItem) bool {
+			return strings.EqualFold(i.ID, id) || find(i.Items)
+		})
+	}
+	return find(d.Menu)
+}
+
+var reVersion = regexp.MustCompile(`\d+(?:\.\d+)+`)
+
+// FirmwareVersion is a firmware version string, like "CGA4233TCH3-1.0.26-TCH",
+// with its first dotted numeric sequence parsed for comparison.
+type FirmwareVersion struct {
+	Raw   string
+	Parts []int
+}
+
+func ParseFirmwareVersion(s string) FirmwareVersion {
+	ret := FirmwareVersion{Raw: s}
+	if m := reVersion.FindString(s); m != "" {
+		for _, p := range strings.Split(m, ".") {
+			n, _ := strconv.Atoi(p)
+			ret.Parts = append(ret.Parts, n)
+		}
+	}
+	return ret
+}
+
+func (v FirmwareVersion) String() string { return v.Raw }
+
+// Known reports whether a numeric version could be parsed.
+func (v FirmwareVersion) Known() bool { return len(v.Parts) > 0 }
+
+// Compare compares the numeric parts of the versions, with missing parts
+// counting as zero.
+func (v FirmwareVersion) Compare(other FirmwareVersion) int {
+	for i := range max(len(v.Parts), len(other.Parts)) {
+		var a, b int
+		if i < len(v.Parts) {
+			a = v.Parts[i]
+		}
+		if i < len(other.Parts) {
+			b = other.Parts[i]
+		}
+		if c := cmp.Compare(a, b); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// Feature is a functionality that is not available in all the firmware
+// versions or to all the users.
+type Feature string
+
+// featureRequirement describes what a feature needs from the device. Empty
+// fields mean no requirement.
+type featureRequirement struct {
+	// minFirmware is the minimum numeric firmware version, e.g. "1.0.26".
+	minFirmware string
+}
+
+// checkedFirmware is the only firmware version whose API this package was
+// written against. Older versions have not been checked.
+const checkedFirmware = "1.0.26"
+
+// featureRequirements are the requirements of the features, so that they fail
+// before making any request. Only the features that can cut off the access to
+// the device are refused on older firmware than checkedFirmware, as a
+// precaution. The rest are not gated, and an unexpected API is reported by
+// callFeature instead; add their requirements once confirmed on a device.
+var featureRequirements = map[Feature]featureRequirement{
+	FeatureLAN:        {minFirmware: checkedFirmware},
+	FeatureBridgeMode: {minFirmware: checkedFirmware},
+	FeatureIPv6:       {minFirmware: checkedFirmware},
+}
+
+// ErrUnsupported is matched by errors.Is for all *UnsupportedError values.
+var ErrUnsupported = errors.New("unsupported")
+
+// UnsupportedError is returned when a feature is not supported by the firmware
+// of the device or not available to the user.
+type UnsupportedError struct {
+	Feature Feature
+	// Firmware is the firmware version of the device, if known.
+	Firmware string
+	Reason   string
+	// Err is the error returned by the device, if any.
+	Err error
+}
+
+func (e *UnsupportedError) Error() string {
+	firmware := "the device firmware"
+	if e.Firmware != "" {
+		firmware = fmt.Sprintf("firmware %q", e.Firmware)
+	}
+	return fmt.Sprintf("%s is not supported by %s: %s", e.Feature, firmware,
+		e.Reason)
+}
+
+func (e *UnsupportedError) Is(target error) bool { return target == ErrUnsupported }
+
+func (e *UnsupportedError) Unwrap() error { return e.Err }
+
+// Supports returns nil if the device supports the feature, or an
+// *UnsupportedError otherwise. Unknown features are supported.
+func (d *DeviceInfo) Supports(f Feature) error {
+	req, ok := featureRequirements[f]
+	if !ok {
+		return nil
+	}
+
+	if req.minFirmware != "" && d.FirmwareVersion.Known() {
+		min := ParseFirmwareVersion(req.minFirmware)
+		if d.FirmwareVersion.Compare(min) < 0 {
+			return &UnsupportedError{
+				Feature:  f,
+				Firmware: d.FirmwareVersion.Raw,
+				Reason:   "requires firmware " + req.minFirmware + " or newer",
+			}
+		}
+	}
+
+	return nil
+}
+
+// fetchDeviceInfo fetches the device information, and caches it or the error
+// until the session is forgotten.
+func (c *client) fetchDeviceInfo(ctx context.Context) (*DeviceInfo, error) {
+	info, err := c.doFetchDeviceInfo(ctx)
+	c.deviceInfoMu.Lock()
+	defer c.deviceInfoMu.Unlock()
+	c.deviceInfo, c.deviceInfoErr = info, err
+	return info, err
+}
+
+func (c *client) doFetchDeviceInfo(ctx context.Context) (*DeviceInfo, error) {
+	sys, err := call(ctx, c, endpointSystemInfo, NoData{})
+	if err != nil {
+		return nil, fmt.Errorf("get system information: %w", err)
+	}
+	menu, err := call(ctx, c, endpointSessionMenu, NoData{})
+	if err != nil {
+		return nil, fmt.Errorf("get session menu: %w", err)
+	}
+
+	d := sys.Data
+	info := &DeviceInfo{
+		Model:             string(d.ModelName),
+		HardwareVersion:   string(d.HardwareVersion),
+		FirmwareVersion:   ParseFirmwareVersion(string(d.SoftwareVersion)),
+		BootloaderVersion: string(d.BootloaderVersion),
+		SerialNumber:      string(d.SerialNumber),
+		CMMACAddress:      string(d.CMMACAddress),
+		Uptime:            time.Duration(d.UpTime) * time.Second,
+		Menu:              menu.Data,
+		FetchedAt:         time.Now(),
+	}
+
+	return info, nil
+}
+
+func (c *client) DeviceInfo(ctx context.Context) (*DeviceInfo, error) {
+	c.deviceInfoMu.RLock()
+	info, err := c.deviceInfo, c.deviceInfoErr
+	c.deviceInfoMu.RUnlock()
+	if info != nil || err != nil {
+		return info, err
+	}
+
+	if err := c.ensureSession(ctx); err != nil {
+		return nil, err
+	}
+	return c.fetchDeviceInfo(ctx)
+}
+
+// requireFeature returns an *UnsupportedError if the device doesn't support
+// the feature. The device information is only needed for the features with
+// requirements. Not all the users can read it, so if it is not available the
+// feature is not gated, and the request itself reports any error.
+func (c *client) requireFeature(ctx context.Context, f Feature) error {
+	if _, ok := featureRequirements[f]; !ok {
+		return nil
+	}
+	info, err := c.DeviceInfo(ctx)
+	if err != nil {
+		return nil
+	}
+	return info.Supports(f)
+}
+
+// callFeature is like callAuthed, but first checks that the device supports
+// the feature, and reports missing endpoints and responses that don't have the
+// expected format as an *UnsupportedError.
+func callFeature[Req, Res any](
+	ctx context.Context,
+	c *client,
+	f Feature,
+	e Endpoint[Req, Res],
+	req Req,
+) (*Response[Res], error) {
+	if err := c.requireFeature(ctx, f); err != nil {
+		return nil, err
+	}
+
+	res, err := callAuthed(ctx, c, e, req)
+	if err == nil {
+		return res, nil
+	}
+
+	var reason string
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &apiErr) &&
+		(apiErr.StatusCode == http.StatusNotFound ||
+			apiErr.StatusCode == http.StatusNotImplemented):
+		reason = fmt.Sprintf("%s %s is not available", e.Method, e.Path)
+	case errors.Is(err, errUnexpectedResponse):
+		reason = fmt.Sprintf("%s %s returned an unexpected response", e.Method,
+			e.Path)
+	default:
+		return nil, err
+	}
+
+	var firmware string
+	if info, _ := c.DeviceInfo(ctx); info != nil {
+		firmware = info.FirmwareVersion.Raw
+	}
+	return nil, &UnsupportedError{
+		Feature:  f,
+		Firmware: firmware,
+		Reason:   reason,
+		Err:      err,
+	}
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFirmwareVersion(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"CGA4233TCH3-1.0.26-TCH", "1.0.26", 0},
+		{"CGA4233TCH3-1.0.26-TCH", "1.0.27", -1},
+		{"CGA4233TCH3-1.1-TCH", "1.0.27", 1},
+		{"1.2", "1.2.0", 0},
+		{"1.10.0", "1.9.9", 1},
+	}
+
+	for _, tc := range testCases {
+		a, b := ParseFirmwareVersion(tc.a), ParseFirmwareVersion(tc.b)
+		if got := a.Compare(b); got != tc.expected {
+			t.Errorf("compare %q to %q: expected %d, got %d", tc.a, tc.b,
+				tc.expected, got)
+		}
+	}
+
+	if ParseFirmwareVersion("unknown").Known() {
+		t.Errorf("expected unknown version")
+	}
+}
+
+func TestDeviceInfo(t *testing.T) {
+	t.Parallel()
+
+	d, c := newFakeDevice(t)
+	ctx := context.Background()
+
+	if err := c.Login(ctx); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	info, err := c.DeviceInfo(ctx)
+	if err != nil {
+		t.Fatalf("device info: %v", err)
+	}
+	if info.Model != "CGA4233TCH3" || info.Uptime != time.Hour ||
+		!info.HasMenuItem("guest") {
+		t.Fatalf("unexpected device info: %#v", info)
+	}
+
+	for f := range featureRequirements {
+		if err := info.Supports(f); err != nil {
+			t.Errorf("%s: expected supported, got %v", f, err)
+		}
+	}
+	older := *info
+	older.FirmwareVersion = ParseFirmwareVersion("CGA4233TCH3-1.0.20-TCH")
+	if err := older.Supports(FeatureBridgeMode); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected unsupported on older firmware, got %v", err)
+	}
+	if err := older.Supports(FeatureParental); err != nil {
+		t.Errorf("expected ungated features to be supported, got %v", err)
+	}
+
+	// missing endpoints of supported features
+	e := Endpoint[NoData, NoData]{Method: http.MethodGet, Path: "/api/v1/missing"}
+	_, err = callFeature(ctx, c, FeatureLAN, e, NoData{})
+	var unsupportedErr *UnsupportedError
+	if !errors.As(err, &unsupportedErr) {
+		t.Fatalf("expected *UnsupportedError, got %v", err)
+	}
+	if unsupportedErr.Firmware != "CGA4233TCH3-1.0.26-TCH" {
+		t.Fatalf("unexpected error data: %#v", unsupportedErr)
+	}
+
+	// the gated features fail before the request
+	d.setDeviceInfo("CGA4233TCH3-1.0.20-TCH", fakeMenu)
+	c.forgetSession()
+	if _, err := c.LAN(ctx); !errors.As(err, &unsupportedErr) ||
+		!strings.Contains(err.Error(), "requires firmware 1.0.26") {
+		t.Fatalf("expected *UnsupportedError, got %v", err)
+	}
+}
+
+func TestRequireFeatureWithoutDeviceInfo(t *testing.T) {
+	t.Parallel()
+
+	// a device whose user cannot read the system information
+	d := &fakeDevice{mux: http.NewServeMux()}
+	d.mux.HandleFunc("POST /api/v1/session/login", d.login)
+	var lookups atomic.Int32
+	d.handle("GET /api/v1/system/{fields}", func(w http.ResponseWriter, _ *http.Request) {
+		lookups.Add(1)
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]any{"error": "error"})
+	})
+	c := newTestClient(t, d.mux)
+	ctx := context.Background()
+
+	if err := c.Login(ctx); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	for range 2 {
+		if err := c.requireFeature(ctx, FeatureLAN); err != nil {
+			t.Fatalf("expected the feature not to be gated, got %v", err)
+		}
+	}
+	if n := lookups.Load(); n != 1 {
+		t.Fatalf("expected a single lookup, got %d", n)
+	}
+}
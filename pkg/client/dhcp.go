@@ -0,0 +1,521 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FeatureDHCP is the LAN DHCP server configuration.
+const FeatureDHCP Feature = "dhcp"
+
+// maxDNSServers is the maximum number of DNS servers handed out by the DHCP
+// server.
+const maxDNSServers = 3
+
+var (
+	endpointDHCP = Endpoint[NoData, dhcpData]{
+		Method: http.MethodGet,
+		Path:   "/api/v1/dhcp/v4/1",
+	}
+	endpointSetDHCP = Endpoint[dhcpData, NoData]{
+		Method:   http.MethodPost,
+		Path:     "/api/v1/dhcp/v4/1",
+		Encoding: EncodingForm,
+	}
+	endpointReservations = Endpoint[NoData, reservationsData]{
+		Method: http.MethodGet,
+		Path:   "/api/v1/dhcp/v4/1/staticAddressTbl",
+	}
+	endpointAddReservation = Endpoint[reservationData, NoData]{
+		Method:   http.MethodPost,
+		Path:     "/api/v1/dhcp/v4/1/staticAddressTbl",
+		Encoding: EncodingForm,
+	}
+)
+
+func endpointUpdateReservation(id int) Endpoint[reservationData, NoData] {
+	return Endpoint[reservationData, NoData]{
+		Method:   http.MethodPost,
+		Path:     "/api/v1/dhcp/v4/1/staticAddressTbl/" + strconv.Itoa(id),
+		Encoding: EncodingForm,
+	}
+}
+
+func endpointDeleteReservation(id int) Endpoint[NoData, NoData] {
+	return Endpoint[NoData, NoData]{
+		Method: http.MethodDelete,
+		Path:   "/api/v1/dhcp/v4/1/staticAddressTbl/" + strconv.Itoa(id),
+	}
+}
+
+type dhcpData struct {
+	Enable     flexBool   `json:"Enable" form:"Enable"`
+	MinAddress flexString `json:"MinAddress" form:"MinAddress"`
+	MaxAddress flexString `json:"MaxAddress" form:"MaxAddress"`
+	LeaseTime  flexInt    `json:"LeaseTime" form:"LeaseTime"` // seconds
+	DNSServers flexString `json:"DNSServers" form:"DNSServers"`
+	IPRouters  flexString `json:"IPRouters" form:"-"`
+	SubnetMask flexString `json:"SubnetMask" form:"-"`
+}
+
+type reservationsData struct {
+	StaticAddressTbl []reservationData `json:"staticAddressTbl"`
+}
+
+type reservationData struct {
+	ID          flexInt    `json:"__id" form:"-"`
+	Chaddr      flexString `json:"Chaddr" form:"Chaddr"`
+	Yiaddr      flexString `json:"Yiaddr" form:"Yiaddr"`
+	Description flexString `json:"Description" form:"Description"`
+}
+
+// DHCPSettings are the settings of the LAN DHCP server.
+type DHCPSettings struct {
+	Enabled bool
+	// Router is the LAN IP address of the device, and Subnet is the LAN
+	// subnet. They are read-only.
+	Router netip.Addr
+	Subnet netip.Prefix
+
+	PoolStart  netip.Addr
+	PoolEnd    netip.Addr
+	LeaseTime  time.Duration
+	DNSServers []netip.Addr
+}
+
+// InPool reports whether the address is in the DHCP pool.
+func (s *DHCPSettings) InPool(ip netip.Addr) bool {
+	return s.PoolStart.Compare(ip) <= 0 && ip.Compare(s.PoolEnd) <= 0
+}
+
+func (s *DHCPSettings) Validate() error {
+	switch {
+	case !s.PoolStart.Is4() || !s.PoolEnd.Is4():
+		return errors.New("the pool addresses must be IPv4")
+	case s.PoolStart.Compare(s.PoolEnd) > 0:
+		return fmt.Errorf("pool start %s is after pool end %s", s.PoolStart,
+			s.PoolEnd)
+	case s.Subnet.IsValid() &&
+		(!s.Subnet.Contains(s.PoolStart) || !s.Subnet.Contains(s.PoolEnd)):
+		return fmt.Errorf("pool %s-%s is not in the LAN subnet %s",
+			s.PoolStart, s.PoolEnd, s.Subnet)
+	case s.Router.IsValid() && s.InPool(s.Router):
+		return fmt.Errorf("pool %s-%s contains the device address %s",
+			s.PoolStart, s.PoolEnd, s.Router)
+	case s.LeaseTime < time.Minute:
+		return fmt.Errorf("lease time %s is too short", s.LeaseTime)
+	case len(s.DNSServers) > maxDNSServers:
+		return fmt.Errorf("at most %d DNS servers are supported", maxDNSServers)
+	}
+	return nil
+}
+
+func parseAddrList(s string) ([]netip.Addr, error) {
+	var ret []netip.Addr
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' '
+	}) {
+		ip, err := netip.ParseAddr(f)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, ip)
+	}
+	return ret, nil
+}
+
+func (d *dhcpData) settings() (*DHCPSettings, error) {
+	s := &DHCPSettings{
+		Enabled:   bool(d.Enable),
+		LeaseTime: time.Duration(d.LeaseTime) * time.Second,
+	}
+
+	var err error
+	if s.PoolStart, err = netip.ParseAddr(string(d.MinAddress)); err != nil {
+		return nil, fmt.Errorf("pool start: %w", err)
+	}
+	if s.PoolEnd, err = netip.ParseAddr(string(d.MaxAddress)); err != nil {
+		return nil, fmt.Errorf("pool end: %w", err)
+	}
+	if s.DNSServers, err = parseAddrList(string(d.DNSServers)); err != nil {
+		return nil, fmt.Errorf("DNS servers: %w", err)
+	}
+	if routers, err := parseAddrList(string(d.IPRouters)); err == nil &&
+		len(routers) > 0 {
+		s.Router = routers[0]
+		if mask, err := netip.ParseAddr(string(d.SubnetMask)); err == nil {
+			ones := 0
+			for _, b := range mask.AsSlice() {
+				ones += bits.OnesCount8(b)
+			}
+			s.Subnet, _ = s.Router.Prefix(ones)
+		}
+	}
+
+	return s, nil
+}
+
+func (d *dhcpData) Validate() error {
+	_, err := d.settings()
+	return err
+}
+
+func (c *client) DHCP(ctx context.Context) (*DHCPSettings, error) {
+	res, err := callFeature(ctx, c, FeatureDHCP, endpointDHCP, NoData{})
+	if err != nil {
+		return nil, err
+	}
+	return res.Data.settings() // validated
+}
+
+func (c *client) SetDHCP(ctx context.Context, s DHCPSettings) error {
+	current, err := c.DHCP(ctx)
+	if err != nil {
+		return err
+	}
+	s.Router, s.Subnet = current.Router, current.Subnet
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	reservations, err := c.Reservations(ctx)
+	if err != nil {
+		return err
+	}
+	for _, r := range reservations {
+		if s.InPool(r.IP) {
+			return &ReservationConflictError{
+				Reservation: r,
+				Reason: fmt.Sprintf("would be in the new pool %s-%s",
+					s.PoolStart, s.PoolEnd),
+			}
+		}
+	}
+
+	dns := make([]string, len(s.DNSServers))
+	for i, ip := range s.DNSServers {
+		dns[i] = ip.String()
+	}
+	_, err = callFeature(ctx, c, FeatureDHCP, endpointSetDHCP, dhcpData{
+		Enable:     flexBool(s.Enabled),
+		MinAddress: flexString(s.PoolStart.String()),
+		MaxAddress: flexString(s.PoolEnd.String()),
+		LeaseTime:  flexInt(s.LeaseTime / time.Second),
+		DNSServers: flexString(strings.Join(dns, ",")),
+	})
+	return err
+}
+
+// Reservation is a static DHCP lease.
+type Reservation struct {
+	MAC  string
+	IP   netip.Addr
+	Name string
+}
+
+// ErrReservationConflict is matched by errors.Is for all
+// *ReservationConflictError values.
+var ErrReservationConflict = errors.New("reservation conflict")
+
+// ReservationConflictError is returned when a reservation conflicts with the
+// DHCP pool or with another reservation.
+type ReservationConflictError struct {
+	Reservation Reservation
+	Reason      string
+	// Existing is the reservation it conflicts with, if any.
+	Existing *Reservation
+}
+
+func (e *ReservationConflictError) Error() string {
+	return fmt.Sprintf("reservation of %s for %s %s", e.Reservation.IP,
+		e.Reservation.MAC, e.Reason)
+}
+
+func (e *ReservationConflictError) Is(target error) bool {
+	return target == ErrReservationConflict
+}
+
+// checkReservation checks a reservation against the DHCP settings and against
+// the existing reservations, ignoring the one with the same MAC address.
+func checkReservation(r Reservation, s *DHCPSettings, existing []Reservation) error {
+	conflict := func(reason string, other *Reservation) error {
+		return &ReservationConflictError{
+			Reservation: r,
+			Reason:      reason,
+			Existing:    other,
+		}
+	}
+
+	switch {
+	case !r.IP.Is4():
+		return conflict("is not an IPv4 address", nil)
+	case s.Subnet.IsValid() && !s.Subnet.Contains(r.IP):
+		return conflict("is outside the LAN subnet "+s.Subnet.String(), nil)
+	case r.IP == s.Router:
+		return conflict("is the address of the device", nil)
+	case s.InPool(r.IP):
+		return conflict(fmt.Sprintf("is in the DHCP pool %s-%s", s.PoolStart,
+			s.PoolEnd), nil)
+	}
+	for _, e := range existing {
+		if e.MAC != r.MAC && e.IP == r.IP {
+			return conflict("is already reserved for "+e.MAC, &e)
+		}
+	}
+	return nil
+}
+
+func (r Reservation) data() (reservationData, error) {
+	mac, err := NormalizeMAC(r.MAC)
+	if err != nil {
+		return reservationData{}, err
+	}
+	return reservationData{
+		Chaddr:      flexString(mac),
+		Yiaddr:      flexString(r.IP.String()),
+		Description: flexString(r.Name),
+	}, nil
+}
+
+// reservations returns the reservations and their IDs in the device.
+func (c *client) reservations(ctx context.Context) ([]Reservation, []int, error) {
+	res, err := callFeature(ctx, c, FeatureDHCP, endpointReservations,
+		NoData{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var ret []Reservation
+	var ids []int
+	for _, d := range res.Data.StaticAddressTbl {
+		mac, err := NormalizeMAC(string(d.Chaddr))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: reservation %d: %w",
+				errUnexpectedResponse, d.ID, err)
+		}
+		ip, err := netip.ParseAddr(string(d.Yiaddr))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: reservation %d: %w",
+				errUnexpectedResponse, d.ID, err)
+		}
+		ret = append(ret, Reservation{
+			MAC:  mac,
+			IP:   ip,
+			Name: string(d.Description),
+		})
+		ids = append(ids, int(d.ID))
+	}
+
+	return ret, ids, nil
+}
+
+func (c *client) Reservations(ctx context.Context) ([]Reservation, error) {
+	ret, _, err := c.reservations(ctx)
+	return ret, err
+}
+
+func (c *client) SetReservation(ctx context.Context, r Reservation) error {
+	mac, err := NormalizeMAC(r.MAC)
+	if err != nil {
+		return err
+	}
+	r.MAC = mac
+
+	settings, err := c.DHCP(ctx)
+	if err != nil {
+		return err
+	}
+	existing, ids, err := c.reservations(ctx)
+	if err != nil {
+		return err
+	}
+	if err := checkReservation(r, settings, existing); err != nil {
+		return err
+	}
+	return c.setReservation(ctx, r, existing, ids)
+}
+
+func (c *client) setReservation(
+	ctx context.Context,
+	r Reservation,
+	existing []Reservation,
+	ids []int,
+) error {
+	data, err := r.data()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(existing, func(e Reservation) bool {
+		return e.MAC == r.MAC
+	})
+	if i < 0 {
+		_, err = callFeature(ctx, c, FeatureDHCP, endpointAddReservation, data)
+	} else {
+		_, err = callFeature(ctx, c, FeatureDHCP,
+			endpointUpdateReservation(ids[i]), data)
+	}
+	return err
+}
+
+func (c *client) DeleteReservation(ctx context.Context, mac string) error {
+	mac, err := NormalizeMAC(mac)
+	if err != nil {
+		return err
+	}
+	existing, ids, err := c.reservations(ctx)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(existing, func(e Reservation) bool {
+		return e.MAC == mac
+	})
+	if i < 0 {
+		return fmt.Errorf("no reservation for %s", mac)
+	}
+	_, err = callFeature(ctx, c, FeatureDHCP, endpointDeleteReservation(ids[i]),
+		NoData{})
+	return err
+}
+
+// ReservationImport are the changes made by ImportReservations.
+type ReservationImport struct {
+	Added     []Reservation
+	Updated   []Reservation
+	Unchanged []Reservation
+}
+
+func (c *client) ImportReservations(ctx context.Context, rs []Reservation) (*ReservationImport, error) {
+	settings, err := c.DHCP(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existing, ids, err := c.reservations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// check everything before changing anything, including conflicts among
+	// the imported reservations and with the existing ones that will remain
+	rs = slices.Clone(rs)
+	final := slices.Clone(existing)
+	for i := range rs {
+		mac, err := NormalizeMAC(rs[i].MAC)
+		if err != nil {
+			return nil, err
+		}
+		rs[i].MAC = mac
+		if slices.ContainsFunc(rs[:i], func(r Reservation) bool {
+			return r.MAC == mac
+		}) {
+			return nil, fmt.Errorf("%s is imported more than once", mac)
+		}
+		final = slices.DeleteFunc(final, func(r Reservation) bool {
+			return r.MAC == mac
+		})
+		final = append(final, rs[i])
+	}
+	for _, r := range rs {
+		if err := checkReservation(r, settings, final); err != nil {
+			return nil, err
+		}
+	}
+
+	// the reservations that move to another IP are deleted first, since
+	// their IP may be reused by another one, and then added again with the
+	// new ones
+	ret := new(ReservationImport)
+	var moved []Reservation
+	for _, r := range rs {
+		i := slices.IndexFunc(existing, func(e Reservation) bool {
+			return e.MAC == r.MAC
+		})
+		switch {
+		case i < 0:
+			ret.Added = append(ret.Added, r)
+		case existing[i] == r:
+			ret.Unchanged = append(ret.Unchanged, r)
+		case existing[i].IP != r.IP:
+			ret.Updated = append(ret.Updated, r)
+			moved = append(moved, r)
+			_, err := callFeature(ctx, c, FeatureDHCP,
+				endpointDeleteReservation(ids[i]), NoData{})
+			if err != nil {
+				return nil, fmt.Errorf("move %s: %w", r.MAC, err)
+			}
+		default:
+			ret.Updated = append(ret.Updated, r)
+			if err := c.setReservation(ctx, r, existing, ids); err != nil {
+				return nil, fmt.Errorf("rename %s: %w", r.MAC, err)
+			}
+		}
+	}
+	for _, r := range slices.Concat(moved, ret.Added) {
+		if err := c.setReservation(ctx, r, nil, nil); err != nil {
+			return nil, fmt.Errorf("reserve %s for %s: %w", r.IP, r.MAC, err)
+		}
+	}
+
+	return ret, nil
+}
+
+// ParseReservations reads reservations, one per line, with the MAC address,
+// the IP address and an optional name, separated by spaces or tabs. The name
+// is the rest of the line, with its runs of spaces collapsed. Empty lines and
+// lines starting with "#" are ignored. This is the format written by
+// WriteReservations.
+func ParseReservations(r io.Reader) ([]Reservation, error) {
+	var ret []Reservation
+	s := bufio.NewScanner(r)
+	for lineNum := 1; s.Scan(); lineNum++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected MAC IP [NAME]", lineNum)
+		}
+		mac, err := NormalizeMAC(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		ip, err := netip.ParseAddr(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		ret = append(ret, Reservation{
+			MAC:  mac,
+			IP:   ip,
+			Name: strings.Join(fields[2:], " "),
+		})
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("read reservations: %w", err)
+	}
+	return ret, nil
+}
+
+// WriteReservations writes reservations in the format read by
+// ParseReservations.
+func WriteReservations(w io.Writer, rs []Reservation) error {
+	for _, r := range rs {
+		line := r.MAC + " " + r.IP.String()
+		if r.Name != "" {
+			line += " " + r.Name
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
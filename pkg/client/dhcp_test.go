@@ -0,0 +1,220 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseReservations(t *testing.T) {
+	t.Parallel()
+
+	const list = `
+# printers
+00-11-22-33-44-55 192.168.0.10 Printer upstairs
+001122334466 192.168.0.11
+00:11:22:33:44:77	192.168.0.12   TV 	 del  salón
+`
+	rs, err := ParseReservations(strings.NewReader(list))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := []Reservation{
+		{"00:11:22:33:44:55", netip.MustParseAddr("192.168.0.10"), "Printer upstairs"},
+		{"00:11:22:33:44:66", netip.MustParseAddr("192.168.0.11"), ""},
+		{"00:11:22:33:44:77", netip.MustParseAddr("192.168.0.12"), "TV del salón"},
+	}
+	if !slices.Equal(rs, want) {
+		t.Fatalf("unexpected reservations: %#v", rs)
+	}
+
+	var b strings.Builder
+	if err := WriteReservations(&b, rs); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if again, err := ParseReservations(strings.NewReader(b.String())); err != nil ||
+		!slices.Equal(again, want) {
+		t.Fatalf("round trip: %#v, %v", again, err)
+	}
+
+	for _, list := range []string{"00:11:22:33:44:55", "00:11:22:33:44:55 192.168.0"} {
+		if _, err := ParseReservations(strings.NewReader(list)); err == nil {
+			t.Errorf("%q: expected error", list)
+		}
+	}
+}
+
+func TestReservations(t *testing.T) {
+	t.Parallel()
+
+	d, c := newFakeDevice(t)
+	var mu sync.Mutex
+	settings := map[string]any{
+		"Enable":     "true",
+		"MinAddress": "192.168.0.100",
+		"MaxAddress": "192.168.0.200",
+		"LeaseTime":  "86400",
+		"DNSServers": "192.168.0.1",
+		"IPRouters":  "192.168.0.1",
+		"SubnetMask": "255.255.255.0",
+	}
+	tbl := map[int]map[string]string{
+		1: {"Chaddr": "00:11:22:33:44:55", "Yiaddr": "192.168.0.10", "Description": "printer"},
+	}
+	nextID := 2
+	d.handle("GET /api/v1/dhcp/v4/1", func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		writeData(w, settings)
+	})
+	d.handle("POST /api/v1/dhcp/v4/1", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		r.ParseForm()
+		for k := range r.PostForm {
+			settings[k] = r.PostForm.Get(k)
+		}
+		writeData(w, nil)
+	})
+	d.handle("GET /api/v1/dhcp/v4/1/staticAddressTbl", func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		entries := []map[string]any{}
+		for id, e := range tbl {
+			entries = append(entries, map[string]any{"__id": id, "Chaddr": e["Chaddr"],
+				"Yiaddr": e["Yiaddr"], "Description": e["Description"]})
+		}
+		writeData(w, map[string]any{"staticAddressTbl": entries})
+	})
+	// set rejects the IPs reserved for other MAC addresses, like the device
+	set := func(w http.ResponseWriter, id int, r *http.Request) {
+		for otherID, e := range tbl {
+			if otherID != id && e["Yiaddr"] == r.FormValue("Yiaddr") {
+				w.WriteHeader(http.StatusConflict)
+				json.NewEncoder(w).Encode(map[string]any{"error": "error"})
+				return
+			}
+		}
+		tbl[id] = map[string]string{"Chaddr": r.FormValue("Chaddr"),
+			"Yiaddr": r.FormValue("Yiaddr"), "Description": r.FormValue("Description")}
+		writeData(w, nil)
+	}
+	d.handle("POST /api/v1/dhcp/v4/1/staticAddressTbl", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		set(w, nextID, r)
+		nextID++
+	})
+	d.handle("POST /api/v1/dhcp/v4/1/staticAddressTbl/{id}", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		set(w, id, r)
+	})
+	d.handle("DELETE /api/v1/dhcp/v4/1/staticAddressTbl/{id}", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		delete(tbl, id)
+		writeData(w, nil)
+	})
+	ctx := context.Background()
+
+	s, err := c.DHCP(ctx)
+	if err != nil {
+		t.Fatalf("get DHCP settings: %v", err)
+	}
+	if !s.Enabled || s.Router != netip.MustParseAddr("192.168.0.1") ||
+		s.Subnet != netip.MustParsePrefix("192.168.0.0/24") ||
+		s.LeaseTime != 24*time.Hour || len(s.DNSServers) != 1 {
+		t.Fatalf("unexpected DHCP settings: %#v", s)
+	}
+
+	// conflicts
+	for _, ip := range []string{"192.168.0.10", "192.168.0.150", "192.168.1.10", "192.168.0.1"} {
+		err := c.SetReservation(ctx, Reservation{
+			MAC: "00:11:22:33:44:66",
+			IP:  netip.MustParseAddr(ip),
+		})
+		var conflict *ReservationConflictError
+		if !errors.As(err, &conflict) || !errors.Is(err, ErrReservationConflict) {
+			t.Fatalf("%s: expected conflict, got %v", ip, err)
+		}
+	}
+	s.PoolStart = netip.MustParseAddr("192.168.0.2")
+	if err := c.SetDHCP(ctx, *s); !errors.Is(err, ErrReservationConflict) {
+		t.Fatalf("expected conflict with the new pool, got %v", err)
+	}
+
+	// the imported reservations are checked among themselves before changing
+	// anything
+	_, err = c.ImportReservations(ctx, []Reservation{
+		{MAC: "00:11:22:33:44:66", IP: netip.MustParseAddr("192.168.0.20")},
+		{MAC: "00:11:22:33:44:77", IP: netip.MustParseAddr("192.168.0.20")},
+	})
+	if !errors.Is(err, ErrReservationConflict) || len(tbl) != 1 {
+		t.Fatalf("expected conflict and no changes, got %v, %v", err, tbl)
+	}
+
+	// moving a reservation to the address of another one being moved is fine
+	res, err := c.ImportReservations(ctx, []Reservation{
+		{MAC: "00-11-22-33-44-55", IP: netip.MustParseAddr("192.168.0.11"), Name: "printer"},
+		{MAC: "00:11:22:33:44:66", IP: netip.MustParseAddr("192.168.0.10"), Name: "nas"},
+	})
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if len(res.Added) != 1 || len(res.Updated) != 1 || len(res.Unchanged) != 0 {
+		t.Fatalf("unexpected import: %#v", res)
+	}
+
+	// swapping the IPs of two reservations frees them before reusing them
+	res, err = c.ImportReservations(ctx, []Reservation{
+		{MAC: "00:11:22:33:44:55", IP: netip.MustParseAddr("192.168.0.10"), Name: "printer"},
+		{MAC: "00:11:22:33:44:66", IP: netip.MustParseAddr("192.168.0.11"), Name: "nas"},
+	})
+	if err != nil {
+		t.Fatalf("swap: %v", err)
+	}
+	if len(res.Updated) != 2 {
+		t.Fatalf("unexpected import: %#v", res)
+	}
+	rs, err := c.Reservations(ctx)
+	if err != nil {
+		t.Fatalf("get reservations: %v", err)
+	}
+	slices.SortFunc(rs, func(a, b Reservation) int { return a.IP.Compare(b.IP) })
+	if len(rs) != 2 || rs[0].MAC != "00:11:22:33:44:55" ||
+		rs[1].MAC != "00:11:22:33:44:66" {
+		t.Fatalf("unexpected reservations after swap: %#v", rs)
+	}
+
+	if err := c.DeleteReservation(ctx, "001122334455"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	rs, err = c.Reservations(ctx)
+	if err != nil {
+		t.Fatalf("get reservations: %v", err)
+	}
+	if len(rs) != 1 || rs[0].Name != "nas" {
+		t.Fatalf("unexpected reservations: %#v", rs)
+	}
+
+	s.PoolStart = netip.MustParseAddr("192.168.0.50")
+	s.DNSServers = []netip.Addr{netip.MustParseAddr("1.1.1.1"), netip.MustParseAddr("8.8.8.8")}
+	if err := c.SetDHCP(ctx, *s); err != nil {
+		t.Fatalf("set DHCP settings: %v", err)
+	}
+	if settings["MinAddress"] != "192.168.0.50" || settings["DNSServers"] != "1.1.1.1,8.8.8.8" ||
+		settings["LeaseTime"] != "86400" {
+		t.Fatalf("unexpected settings sent: %v", settings)
+	}
+}
@@ -0,0 +1,415 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/trace"
+)
+
+// FeatureDiagnostics is running ping, traceroute and DNS lookups from the
+// device.
+const FeatureDiagnostics Feature = "diagnostics"
+
+// DefaultDiagnosticsPollInterval is the default interval between the polls of
+// the results of a diagnostic.
+const DefaultDiagnosticsPollInterval = time.Second
+
+// stopTimeout bounds the request that stops a diagnostic when its context is
+// canceled.
+const stopTimeout = 5 * time.Second
+
+// Diagnostic states reported by the device.
+const (
+	diagRunning  = "running"
+	diagComplete = "complete"
+	diagError    = "error"
+)
+
+// diagEndpoints are the endpoints of a diagnostic, which is started with a
+// POST, polled with a GET, and stopped with a DELETE of the same path.
+type diagEndpoints[Req, Res any] struct {
+	feature Feature
+	start   Endpoint[Req, NoData]
+	poll    Endpoint[NoData, diagData[Res]]
+	stop    Endpoint[NoData, NoData]
+}
+
+func newDiagEndpoints[Req, Res any](
+	f Feature,
+	path string,
+) diagEndpoints[Req, Res] {
+	return diagEndpoints[Req, Res]{
+		feature: f,
+		start: Endpoint[Req, NoData]{
+			Method:   http.MethodPost,
+			Path:     path,
+			Encoding: EncodingForm,
+		},
+		poll: Endpoint[NoData, diagData[Res]]{
+			Method: http.MethodGet,
+			Path:   path,
+		},
+		stop: Endpoint[NoData, NoData]{
+			Method: http.MethodDelete,
+			Path:   path,
+		},
+	}
+}
+
+var (
+	endpointsPing = newDiagEndpoints[pingRequest, pingData](
+		FeatureDiagnostics, "/api/v1/diagnostics/ping")
+	endpointsTraceroute = newDiagEndpoints[tracerouteRequest, hopData](
+		FeatureDiagnostics, "/api/v1/diagnostics/traceroute")
+	endpointsNSLookup = newDiagEndpoints[nslookupRequest, answerData](
+		FeatureDiagnostics, "/api/v1/diagnostics/nslookup")
+)
+
+// diagData is the state of a diagnostic, with the results so far.
+type diagData[T any] struct {
+	Status  flexString `json:"Status"`
+	Message flexString `json:"Message"`
+	Results []T        `json:"Results"`
+}
+
+func (d *diagData[T]) Validate() error {
+	d.Status = flexString(strings.ToLower(string(d.Status)))
+	switch d.Status {
+	case diagRunning, diagComplete, diagError:
+		return nil
+	}
+	return fmt.Errorf("unknown diagnostic status %q", d.Status)
+}
+
+type pingRequest struct {
+	Host  string `form:"Host"`
+	Count int    `form:"Count"`
+	Size  int    `form:"Size"`
+}
+
+type pingData struct {
+	Seq     flexInt    `json:"Seq"`
+	Address flexString `json:"Address"`
+	Time    flexFloat  `json:"Time"` // milliseconds
+	TTL     flexInt    `json:"TTL"`
+	Status  flexString `json:"Status"`
+}
+
+type tracerouteRequest struct {
+	Host    string `form:"Host"`
+	MaxHops int    `form:"MaxHops"`
+}
+
+type hopData struct {
+	Hop     flexInt    `json:"Hop"`
+	Host    flexString `json:"Host"`
+	Address flexString `json:"Address"`
+	// Times are the round-trip times of the probes in milliseconds, or "*"
+	// for the lost ones, separated by commas.
+	Times flexString `json:"Times"`
+}
+
+type nslookupRequest struct {
+	Host   string `form:"Host"`
+	Server string `form:"Server,omitempty"`
+}
+
+type answerData struct {
+	Name    flexString `json:"Name"`
+	Address flexString `json:"Address"`
+	Server  flexString `json:"Server"`
+	Time    flexFloat  `json:"Time"` // milliseconds
+}
+
+// DiagnosticError is returned when the device reports that a diagnostic
+// failed, e.g. because the host could not be resolved.
+type DiagnosticError struct {
+	Diagnostic string
+	Message    string
+}
+
+func (e *DiagnosticError) Error() string {
+	return fmt.Sprintf("%s failed: %s", e.Diagnostic, e.Message)
+}
+
+// PingParams configures a ping.
+type PingParams struct {
+	// Host is the host name or address to ping.
+	Host string
+	// Count is the number of echo requests. Defaults to 4.
+	Count int
+	// Size is the size of the payload in bytes. Defaults to 56.
+	Size int
+}
+
+func (p PingParams) WithDefaults() PingParams {
+	if p.Count <= 0 {
+		p.Count = 4
+	}
+	if p.Size <= 0 {
+		p.Size = 56
+	}
+	return p
+}
+
+// PingReply is the result of an echo request.
+type PingReply struct {
+	Seq  int
+	Addr netip.Addr
+	// Lost reports whether the request timed out, in which case RTT and TTL
+	// are zero.
+	Lost bool
+	RTT  time.Duration
+	TTL  int
+}
+
+// TracerouteParams configures a traceroute.
+type TracerouteParams struct {
+	// Host is the host name or address to trace the route to.
+	Host string
+	// MaxHops is the maximum number of hops. Defaults to 30.
+	MaxHops int
+}
+
+func (p TracerouteParams) WithDefaults() TracerouteParams {
+	if p.MaxHops <= 0 {
+		p.MaxHops = 30
+	}
+	return p
+}
+
+// TracerouteHop is a hop of a traceroute.
+type TracerouteHop struct {
+	Hop int
+	// Host and Addr are empty if no probe was answered.
+	Host string
+	Addr netip.Addr
+	// RTTs are the round-trip times of the answered probes, and Lost is the
+	// number of probes not answered.
+	RTTs []time.Duration
+	Lost int
+}
+
+// DNSParams configures a DNS lookup.
+type DNSParams struct {
+	// Host is the name to look up.
+	Host string
+	// Server is the DNS server to query. By default, the ones of the device
+	// are used.
+	Server string
+}
+
+// DNSAnswer is an address returned by a DNS lookup.
+type DNSAnswer struct {
+	Name   string
+	Addr   netip.Addr
+	Server string
+	RTT    time.Duration
+}
+
+func validateDiagHost(host string) error {
+	if host == "" || strings.ContainsAny(host, " \t\n;&|$`'\"") {
+		return fmt.Errorf("invalid host %q", host)
+	}
+	return nil
+}
+
+func millis(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+func (c *client) Ping(ctx context.Context, p PingParams) iter.Seq2[PingReply, error] {
+	p = p.WithDefaults()
+	err := validateDiagHost(p.Host)
+	switch {
+	case err != nil:
+	case p.Count > 100:
+		err = fmt.Errorf("too many echo requests: %d", p.Count)
+	case p.Size > 1472:
+		err = fmt.Errorf("payload too large: %d", p.Size)
+	}
+
+	req := pingRequest{Host: p.Host, Count: p.Count, Size: p.Size}
+	return runDiagnostic(ctx, c, "ping", endpointsPing, req, err,
+		func(d pingData) (PingReply, error) {
+			r := PingReply{
+				Seq:  int(d.Seq),
+				Lost: !strings.EqualFold(string(d.Status), "ok"),
+			}
+			if !r.Lost {
+				r.RTT = millis(float64(d.Time))
+				r.TTL = int(d.TTL)
+			}
+			var err error
+			r.Addr, err = parseOptionalAddr(string(d.Address))
+			return r, err
+		})
+}
+
+func (c *client) Traceroute(ctx context.Context, p TracerouteParams) iter.Seq2[TracerouteHop, error] {
+	p = p.WithDefaults()
+	err := validateDiagHost(p.Host)
+	if err == nil && p.MaxHops > 64 {
+		err = fmt.Errorf("too many hops: %d", p.MaxHops)
+	}
+
+	req := tracerouteRequest{Host: p.Host, MaxHops: p.MaxHops}
+	return runDiagnostic(ctx, c, "traceroute", endpointsTraceroute, req, err,
+		func(d hopData) (TracerouteHop, error) {
+			h := TracerouteHop{
+				Hop:  int(d.Hop),
+				Host: string(d.Host),
+			}
+			var err error
+			if h.Addr, err = parseOptionalAddr(string(d.Address)); err != nil {
+				return h, err
+			}
+			for _, t := range strings.Split(string(d.Times), ",") {
+				t = strings.TrimSpace(strings.TrimSuffix(
+					strings.TrimSpace(t), "ms"))
+				if t == "*" || t == "" {
+					h.Lost++
+					continue
+				}
+				ms, err := strconv.ParseFloat(t, 64)
+				if err != nil {
+					return h, fmt.Errorf("hop %d: invalid time %q", h.Hop, t)
+				}
+				h.RTTs = append(h.RTTs, millis(ms))
+			}
+			return h, nil
+		})
+}
+
+func (c *client) LookupHost(ctx context.Context, p DNSParams) iter.Seq2[DNSAnswer, error] {
+	err := validateDiagHost(p.Host)
+	if err == nil && p.Server != "" {
+		err = validateDiagHost(p.Server)
+	}
+
+	req := nslookupRequest{Host: p.Host, Server: p.Server}
+	return runDiagnostic(ctx, c, "DNS lookup", endpointsNSLookup, req, err,
+		func(d answerData) (DNSAnswer, error) {
+			a := DNSAnswer{
+				Name:   string(d.Name),
+				Server: string(d.Server),
+				RTT:    millis(float64(d.Time)),
+			}
+			var err error
+			a.Addr, err = netip.ParseAddr(string(d.Address))
+			return a, err
+		})
+}
+
+// runDiagnostic returns an iterator that starts the diagnostic, and then polls
+// it, yielding the new results converted with convert, until it completes. If
+// validationErr is not nil, it is yielded instead. The diagnostic is stopped
+// if the iteration ends early or ctx is canceled.
+func runDiagnostic[Req, Res, T any](
+	ctx context.Context,
+	c *client,
+	name string,
+	e diagEndpoints[Req, Res],
+	req Req,
+	validationErr error,
+	convert func(Res) (T, error),
+) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		if validationErr != nil {
+			yield(zero, validationErr)
+			return
+		}
+
+		var err error
+		ctx, span := c.startSpan(ctx, "client.diagnostic",
+			trace.String("diagnostic", name))
+		defer func() { trace.End(span, err) }()
+
+		if _, err = callFeature(ctx, c, e.feature, e.start,
+			req); err != nil {
+			yield(zero, fmt.Errorf("start %s: %w", name, err))
+			return
+		}
+		done := false
+		defer func() {
+			if !done {
+				c.stopDiagnostic(ctx, e.feature, e.stop)
+			}
+		}()
+
+		var yielded int
+		ticker := time.NewTicker(c.DiagnosticsPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+				yield(zero, err)
+				return
+			case <-ticker.C:
+			}
+
+			var res *Response[diagData[Res]]
+			res, err = callFeature(ctx, c, e.feature, e.poll, NoData{})
+			if err != nil {
+				yield(zero, fmt.Errorf("poll %s: %w", name, err))
+				return
+			}
+
+			// the results so far are returned every time
+			d := res.Data
+			for _, r := range d.Results[min(yielded, len(d.Results)):] {
+				yielded++
+				v, convErr := convert(r)
+				if convErr != nil {
+					err = fmt.Errorf("%w: %s result: %w",
+						errUnexpectedResponse, name, convErr)
+					yield(zero, err)
+					return
+				}
+				if !yield(v, nil) {
+					return
+				}
+			}
+
+			switch d.Status {
+			case diagComplete:
+				done = true
+				return
+			case diagError:
+				done = true
+				err = &DiagnosticError{
+					Diagnostic: name,
+					Message:    string(d.Message),
+				}
+				yield(zero, err)
+				return
+			}
+		}
+	}
+}
+
+// stopDiagnostic stops a diagnostic. It is best effort, so the errors are
+// only recorded.
+func (c *client) stopDiagnostic(
+	ctx context.Context,
+	f Feature,
+	e Endpoint[NoData, NoData],
+) {
+	ctx, span := c.startSpan(context.WithoutCancel(ctx),
+		"client.diagnostic.stop")
+	defer span.End()
+	ctx, cancel := context.WithTimeout(ctx, stopTimeout)
+	defer cancel()
+	if _, err := callFeature(ctx, c, f, e, NoData{}); err != nil {
+		span.RecordError(err)
+	}
+}
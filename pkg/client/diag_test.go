@@ -0,0 +1,138 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	d, c := newFakeDevice(t)
+	c.DiagnosticsPollInterval = time.Millisecond
+	var mu sync.Mutex
+	var polls, stops int
+	var host string
+	d.handle("POST /api/v1/diagnostics/{name}", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		polls, host = 0, r.FormValue("Host")
+		writeData(w, nil)
+	})
+	d.handle("DELETE /api/v1/diagnostics/{name}", func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		stops++
+		writeData(w, nil)
+	})
+	// each poll returns one more result, up to three
+	d.handle("GET /api/v1/diagnostics/ping", func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		polls++
+		replies := []map[string]any{
+			{"Seq": 1, "Address": "1.1.1.1", "Time": "10.5", "TTL": 57, "Status": "ok"},
+			{"Seq": 2, "Address": "1.1.1.1", "Status": "timeout"},
+			{"Seq": 3, "Address": "1.1.1.1", "Time": "9", "TTL": 57, "Status": "ok"},
+		}
+		status := "running"
+		if polls >= 3 {
+			status = "complete"
+		}
+		if host == "fail.example" {
+			status = "error"
+		}
+		writeData(w, map[string]any{
+			"Status":  status,
+			"Message": "unknown host",
+			"Results": replies[:min(polls, 3)],
+		})
+	})
+	d.handle("GET /api/v1/diagnostics/traceroute", func(w http.ResponseWriter, _ *http.Request) {
+		writeData(w, map[string]any{"Status": "running", "Results": []map[string]any{
+			{"Hop": 1, "Host": "router", "Address": "10.0.0.1", "Times": "1.2 ms, *, 0.9 ms"},
+		}})
+	})
+	d.handle("GET /api/v1/diagnostics/nslookup", func(w http.ResponseWriter, _ *http.Request) {
+		writeData(w, map[string]any{"Status": "complete", "Results": []map[string]any{
+			{"Name": "example.com", "Address": "2606:2800:21f:cb07:6820:80da:af6b:8b2c",
+				"Server": "200.42.4.207", "Time": 12},
+		}})
+	})
+	stopped := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return stops
+	}
+	ctx := context.Background()
+
+	var replies []PingReply
+	for r, err := range c.Ping(ctx, PingParams{Host: "1.1.1.1", Count: 3}) {
+		if err != nil {
+			t.Fatalf("ping: %v", err)
+		}
+		replies = append(replies, r)
+	}
+	if len(replies) != 3 || replies[0].RTT != 10500*time.Microsecond ||
+		!replies[1].Lost || replies[2].TTL != 57 || stopped() != 0 {
+		t.Fatalf("unexpected replies: %#v", replies)
+	}
+
+	// stopping early stops the diagnostic
+	for range c.Ping(ctx, PingParams{Host: "1.1.1.1"}) {
+		break
+	}
+	if stopped() != 1 {
+		t.Fatalf("ping not stopped")
+	}
+
+	var diagErr *DiagnosticError
+	for _, err := range c.Ping(ctx, PingParams{Host: "fail.example"}) {
+		if err != nil && !errors.As(err, &diagErr) {
+			t.Fatalf("expected *DiagnosticError, got %v", err)
+		}
+	}
+	if diagErr == nil || diagErr.Message != "unknown host" {
+		t.Fatalf("expected diagnostic error, got %v", diagErr)
+	}
+
+	for _, err := range c.Ping(ctx, PingParams{Host: "1.1.1.1; reboot"}) {
+		if err == nil {
+			t.Fatalf("expected validation error")
+		}
+	}
+
+	// the traceroute never completes, so it ends with the context
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var hops []TracerouteHop
+	for h, err := range c.Traceroute(ctx, TracerouteParams{Host: "example.com"}) {
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("expected cancellation, got %v", err)
+			}
+			continue
+		}
+		hops = append(hops, h)
+		cancel()
+	}
+	if len(hops) != 1 || len(hops[0].RTTs) != 2 || hops[0].Lost != 1 ||
+		hops[0].Host != "router" || stopped() != 2 {
+		t.Fatalf("unexpected hops: %#v", hops)
+	}
+
+	var answers []DNSAnswer
+	for a, err := range c.LookupHost(context.Background(), DNSParams{Host: "example.com"}) {
+		if err != nil {
+			t.Fatalf("lookup: %v", err)
+		}
+		answers = append(answers, a)
+	}
+	if len(answers) != 1 || !answers[0].Addr.Is6() || answers[0].RTT != 12*time.Millisecond {
+		t.Fatalf("unexpected answers: %#v", answers)
+	}
+}
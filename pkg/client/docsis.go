@@ -0,0 +1,138 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// FeatureDOCSIS is the DOCSIS channel information.
+const FeatureDOCSIS Feature = "docsis"
+
+var endpointChannels = Endpoint[NoData, channelsData]{
+	Method: http.MethodGet,
+	Path:   "/api/v1/modem/exUSTbl,exDSTbl,USTbl,DSTbl,ErrTbl",
+}
+
+type channelsData struct {
+	ExUSTbl []upstreamData   `json:"exUSTbl"`
+	ExDSTbl []downstreamData `json:"exDSTbl"`
+	USTbl   []upstreamData   `json:"USTbl"`
+	DSTbl   []downstreamData `json:"DSTbl"`
+	ErrTbl  []errorsData     `json:"ErrTbl"`
+}
+
+type downstreamData struct {
+	ChannelID   flexInt    `json:"ChannelID"`
+	Frequency   flexInt    `json:"Frequency"`
+	PowerLevel  flexFloat  `json:"PowerLevel"`
+	SNRLevel    flexFloat  `json:"SNRLevel"`
+	Modulation  flexString `json:"Modulation"`
+	ChannelType flexString `json:"ChannelType"`
+	LockStatus  flexString `json:"LockStatus"`
+}
+
+type upstreamData struct {
+	ChannelID   flexInt    `json:"ChannelID"`
+	Frequency   flexInt    `json:"Frequency"`
+	PowerLevel  flexFloat  `json:"PowerLevel"`
+	SymbolRate  flexInt    `json:"SymbolRate"`
+	Modulation  flexString `json:"Modulation"`
+	ChannelType flexString `json:"ChannelType"`
+	LockStatus  flexString `json:"LockStatus"`
+}
+
+type errorsData struct {
+	ChannelID      flexInt `json:"ChannelID"`
+	Unerroreds     flexInt `json:"Unerroreds"`
+	Correcteds     flexInt `json:"Correcteds"`
+	Uncorrectables flexInt `json:"Uncorrectables"`
+}
+
+// Channels are the DOCSIS channels of the cable modem.
+type Channels struct {
+	Downstream []DownstreamChannel `json:"downstream"`
+	Upstream   []UpstreamChannel   `json:"upstream"`
+}
+
+// DownstreamChannel is a DOCSIS downstream channel, either SC-QAM (DOCSIS 3.0)
+// or OFDM (DOCSIS 3.1).
+type DownstreamChannel struct {
+	ID int `json:"id"`
+	// Frequency is in Hz.
+	Frequency int64 `json:"frequency"`
+	// Power is in dBmV.
+	Power float64 `json:"power"`
+	// SNR is in dB.
+	SNR        float64 `json:"snr"`
+	Modulation string  `json:"modulation"`
+	Type       string  `json:"type"`
+	Locked     bool    `json:"locked"`
+
+	// Codeword counters.
+	Unerrored     int64 `json:"unerrored"`
+	Corrected     int64 `json:"corrected"`
+	Uncorrectable int64 `json:"uncorrectable"`
+}
+
+// UpstreamChannel is a DOCSIS upstream channel, either SC-QAM (DOCSIS 3.0) or
+// OFDMA (DOCSIS 3.1).
+type UpstreamChannel struct {
+	ID int `json:"id"`
+	// Frequency is in Hz.
+	Frequency int64 `json:"frequency"`
+	// Power is in dBmV.
+	Power float64 `json:"power"`
+	// SymbolRate is in ksym/s.
+	SymbolRate int64  `json:"symbolRate"`
+	Modulation string `json:"modulation"`
+	Type       string `json:"type"`
+	Locked     bool   `json:"locked"`
+}
+
+func isLocked(status flexString) bool {
+	return strings.EqualFold(strings.TrimSpace(string(status)), "locked")
+}
+
+func (c *client) Channels(ctx context.Context) (*Channels, error) {
+	res, err := callFeature(ctx, c, FeatureDOCSIS, endpointChannels, NoData{})
+	if err != nil {
+		return nil, err
+	}
+	d := res.Data
+
+	errs := make(map[flexInt]errorsData, len(d.ErrTbl))
+	for _, e := range d.ErrTbl {
+		errs[e.ChannelID] = e
+	}
+
+	ret := new(Channels)
+	for _, ds := range append(d.DSTbl, d.ExDSTbl...) {
+		e := errs[ds.ChannelID]
+		ret.Downstream = append(ret.Downstream, DownstreamChannel{
+			ID:            int(ds.ChannelID),
+			Frequency:     int64(ds.Frequency),
+			Power:         float64(ds.PowerLevel),
+			SNR:           float64(ds.SNRLevel),
+			Modulation:    string(ds.Modulation),
+			Type:          string(ds.ChannelType),
+			Locked:        isLocked(ds.LockStatus),
+			Unerrored:     int64(e.Unerroreds),
+			Corrected:     int64(e.Correcteds),
+			Uncorrectable: int64(e.Uncorrectables),
+		})
+	}
+	for _, us := range append(d.USTbl, d.ExUSTbl...) {
+		ret.Upstream = append(ret.Upstream, UpstreamChannel{
+			ID:         int(us.ChannelID),
+			Frequency:  int64(us.Frequency),
+			Power:      float64(us.PowerLevel),
+			SymbolRate: int64(us.SymbolRate),
+			Modulation: string(us.Modulation),
+			Type:       string(us.ChannelType),
+			Locked:     isLocked(us.LockStatus),
+		})
+	}
+
+	return ret, nil
+}
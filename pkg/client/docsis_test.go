@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestChannels(t *testing.T) {
+	t.Parallel()
+
+	d, c := newFakeDevice(t)
+	d.handle("GET /api/v1/modem/{tables}", func(w http.ResponseWriter, _ *http.Request) {
+		writeData(w, map[string]any{
+			"DSTbl": []map[string]any{{
+				"ChannelID":   "3",
+				"Frequency":   "602000000",
+				"PowerLevel":  "4.2",
+				"SNRLevel":    40.1,
+				"Modulation":  "256QAM",
+				"ChannelType": "SC-QAM",
+				"LockStatus":  "Locked",
+			}},
+			"exDSTbl": []map[string]any{{
+				"ChannelID":   33,
+				"ChannelType": "OFDM",
+				"LockStatus":  "Not Locked",
+			}},
+			"USTbl": []map[string]any{{
+				"ChannelID":  1,
+				"PowerLevel": "44.5",
+				"SymbolRate": "5120",
+				"LockStatus": "Locked",
+			}},
+			"ErrTbl": []map[string]any{{
+				"ChannelID":      "3",
+				"Correcteds":     "12",
+				"Uncorrectables": 1,
+			}},
+		})
+	})
+
+	ch, err := c.Channels(context.Background())
+	if err != nil {
+		t.Fatalf("get channels: %v", err)
+	}
+	if len(ch.Downstream) != 2 || len(ch.Upstream) != 1 {
+		t.Fatalf("unexpected channels: %#v", ch)
+	}
+	ds := ch.Downstream[0]
+	if ds.ID != 3 || ds.Frequency != 602000000 || ds.Power != 4.2 ||
+		ds.SNR != 40.1 || !ds.Locked || ds.Corrected != 12 ||
+		ds.Uncorrectable != 1 {
+		t.Fatalf("unexpected downstream channel: %#v", ds)
+	}
+	if ch.Downstream[1].Locked || ch.Downstream[1].Type != "OFDM" {
+		t.Fatalf("unexpected OFDM channel: %#v", ch.Downstream[1])
+	}
+	if us := ch.Upstream[0]; us.Power != 44.5 || us.SymbolRate != 5120 {
+		t.Fatalf("unexpected upstream channel: %#v", us)
+	}
+}
@@ -1,32 +1,37 @@
 package client
 
 import (
-	"encoding/json"
-	"fmt"
-	"strings"
+	"net/http"
+	"net/url"
 )
 
-type response struct {
-	Error   string         `json:"error"`
-	Message string         `json:"message"`
-	Data    map[string]any `json:"data"`
-}
-
-func (r response) Validate() error {
-	if strings.ToLower(r.Error) != "ok" {
-		return fmt.Errorf("unexpected error: %v; message: %v; data: %#v",
-			r.Error, r.Message, r.Data)
+var (
+	endpointLogin = Endpoint[loginRequest, NoData]{
+		Method:   http.MethodPost,
+		Path:     "/api/v1/session/login",
+		Encoding: EncodingForm,
+	}
+	endpointLogout = Endpoint[NoData, NoData]{
+		Method: http.MethodPost,
+		Path:   "/api/v1/session/logout",
+	}
+	endpointChangePassword = Endpoint[url.Values, NoData]{
+		Method:   http.MethodPost,
+		Path:     "/api/v1/changepassword",
+		Encoding: EncodingForm,
 	}
-	return nil
+)
+
+type loginRequest struct {
+	Username string `form:"username"`
+	Password string `form:"password"`
 }
 
+// loginResponse has the top-level fields of the login response that are only
+// set when the password was "seeksalthash".
 type loginResponse struct {
-	response
-
-	// fields only set when password was "seeksalthash"
-
-	Salt      json.RawMessage `json:"salt"`
-	SaltWebUI json.RawMessage `json:"saltwebui"`
+	Salt      string `json:"salt"`
+	SaltWebUI string `json:"saltwebui"`
 }
 
 type sjclData struct {
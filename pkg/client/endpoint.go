@@ -0,0 +1,285 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/httpdoer"
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/trace"
+)
+
+// Encoding is how the request of an Endpoint is sent.
+type Encoding int
+
+const (
+	// EncodingNone sends no body nor query.
+	EncodingNone Encoding = iota
+	// EncodingForm sends the request URL-encoded in the body, or in the query
+	// string for GET requests.
+	EncodingForm
+	// EncodingJSON sends the request JSON-encoded in the body.
+	EncodingJSON
+)
+
+// NoData is used as the request type of endpoints that take no parameters, and
+// as the response type of endpoints whose data is not used.
+type NoData struct{}
+
+// Endpoint describes an API endpoint of the device. Req is the type of the
+// request parameters, and Res is the type of the "data" field of the response.
+//
+// With EncodingForm, Req can be url.Values, httpdoer.KeyValue, or a struct
+// whose fields have a `form:"name"` tag. Fields of type string, bool, integer
+// or fmt.Stringer, and pointers to them, are supported. The ",omitempty" tag
+// option skips zero values, and nil pointers are always skipped. Untagged
+// fields are ignored.
+//
+// If *Res implements `Validate() error`, it is called after decoding.
+type Endpoint[Req, Res any] struct {
+	Method   string
+	Path     string
+	Encoding Encoding
+}
+
+// Response is the envelope of all the API responses.
+type Response[T any] struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+	Data    T      `json:"data"`
+
+	// Raw is the undecoded response body.
+	Raw []byte `json:"-"`
+}
+
+// APIError is returned when the device responds with an error.
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	// Code is the "error" field of the response, e.g. "error".
+	Code    string
+	Message string
+	Raw     []byte
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s %s: HTTP status %d; error: %q; message: %q",
+		e.Method, e.Path, e.StatusCode, e.Code, e.Message)
+}
+
+// Call performs a request to the endpoint using c, which must have been
+// created with New.
+func Call[Req, Res any](
+	ctx context.Context,
+	c Client,
+	e Endpoint[Req, Res],
+	req Req,
+) (*Response[Res], error) {
+	cc, ok := c.(*client)
+	if !ok {
+		return nil, fmt.Errorf("unsupported client type %T", c)
+	}
+	return call(ctx, cc, e, req)
+}
+
+func call[Req, Res any](
+	ctx context.Context,
+	c *client,
+	e Endpoint[Req, Res],
+	req Req,
+) (_ *Response[Res], err error) {
+	ctx, span := c.startSpan(ctx, "client.call",
+		trace.String("method", e.Method),
+		trace.String("endpoint", e.Path),
+	)
+	defer func() { trace.End(span, err) }()
+
+	// build request
+	httpReq, err := e.newRequest(ctx, c.CurrentBaseURL(), req)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+
+	// do request
+	httpRes, err := c.HTTPDoer.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("perform request: %w", err)
+	}
+	resBytes, err := httpdoer.ReadAndCloseBody(httpRes)
+	if err != nil {
+		return nil, err
+	}
+	res, err := decodeResponse[Res](e.Method, e.Path, httpRes.StatusCode,
+		resBytes)
+	// the error envelopes don't have the shape of the endpoint, but the
+	// responses that could not be decoded may be a new one
+	var apiErr *APIError
+	if c.ResponseObserver != nil && !errors.As(err, &apiErr) {
+		c.ResponseObserver.ObserveResponse(e.Method, e.Path, resBytes)
+	}
+
+	return res, err
+}
+
+func (e Endpoint[Req, Res]) newRequest(
+	ctx context.Context,
+	baseURL string,
+	req Req,
+) (*http.Request, error) {
+	u := baseURL + e.Path
+	var body io.Reader
+	var contentType string
+
+	switch e.Encoding {
+	case EncodingNone:
+	case EncodingForm:
+		values, err := encodeForm(req)
+		if err != nil {
+			return nil, fmt.Errorf("encode form: %w", err)
+		}
+		if e.Method == http.MethodGet {
+			if len(values) > 0 {
+				sep := "?"
+				if strings.Contains(u, "?") {
+					sep = "&"
+				}
+				u += sep + values.Encode()
+			}
+		} else {
+			body = strings.NewReader(values.Encode())
+			contentType = httpdoer.ContentTypeFormURLEncoded
+		}
+	case EncodingJSON:
+		b, err := json.Marshal(req)
+		if err != nil {
+			return nil, fmt.Errorf("encode JSON: %w", err)
+		}
+		body = bytes.NewReader(b)
+		contentType = httpdoer.ContentTypeJSON
+	default:
+		return nil, fmt.Errorf("unknown encoding %d", e.Encoding)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, e.Method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		httpReq.Header.Set(httpdoer.HeaderNameContentType, contentType)
+	}
+
+	return httpReq, nil
+}
+
+func decodeResponse[Res any](
+	method string,
+	path string,
+	statusCode int,
+	raw []byte,
+) (*Response[Res], error) {
+	res := &Response[Res]{Raw: raw}
+	decodeErr := json.Unmarshal(raw, res)
+
+	if statusCode < 200 || statusCode > 299 || decodeErr == nil &&
+		strings.ToLower(res.Error) != "ok" {
+		return nil, &APIError{
+			Method:     method,
+			Path:       path,
+			StatusCode: statusCode,
+			Code:       res.Error,
+			Message:    res.Message,
+			Raw:        raw,
+		}
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("%w: decode JSON body: %w; raw response: %s",
+			errUnexpectedResponse, decodeErr, raw)
+	}
+
+	if v, ok := any(&res.Data).(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: validate: %w", errUnexpectedResponse,
+				err)
+		}
+	}
+
+	return res, nil
+}
+
+// errUnexpectedResponse wraps the errors of responses that could not be decoded or
+// validated, which usually means that the firmware has a different API.
+var errUnexpectedResponse = errors.New("unexpected response")
+
+var stringerType = reflect.TypeFor[fmt.Stringer]()
+
+func encodeForm(v any) (url.Values, error) {
+	switch v := v.(type) {
+	case nil, NoData:
+		return url.Values{}, nil
+	case url.Values:
+		return v, nil
+	case httpdoer.KeyValue:
+		return v.ToURLValues(), nil
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return url.Values{}, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("unsupported form type %T", v)
+	}
+
+	ret := url.Values{}
+	rt := rv.Type()
+	for i := range rt.NumField() {
+		f := rt.Field(i)
+		tag, ok := f.Tag.Lookup("form")
+		if !ok || tag == "-" || !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		fv := rv.Field(i)
+		if opts == "omitempty" && fv.IsZero() {
+			continue
+		}
+		if fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+
+		var s string
+		switch {
+		case fv.Type().Implements(stringerType):
+			s = fv.Interface().(fmt.Stringer).String()
+		case fv.Kind() == reflect.String:
+			s = fv.String()
+		case fv.Kind() == reflect.Bool:
+			s = strconv.FormatBool(fv.Bool())
+		case fv.CanInt():
+			s = strconv.FormatInt(fv.Int(), 10)
+		case fv.CanUint():
+			s = strconv.FormatUint(fv.Uint(), 10)
+		default:
+			return nil, fmt.Errorf("unsupported type %v of form field %q",
+				f.Type, name)
+		}
+		ret.Set(name, s)
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,172 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/httpdoer"
+)
+
+type testFormRequest struct {
+	Name    string `form:"name"`
+	Enabled bool   `form:"enabled"`
+	Count   int    `form:"count,omitempty"`
+	Limit   *bool  `form:"limit"`
+	Ignored string
+}
+
+type testData struct {
+	Value string `json:"value"`
+}
+
+func (d *testData) Validate() error {
+	if d.Value == "invalid" {
+		return errors.New("invalid value")
+	}
+	return nil
+}
+
+func TestCall(t *testing.T) {
+	t.Parallel()
+
+	var gotMethod, gotQuery, gotBody, gotContentType string
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotMethod, gotQuery, gotBody = r.Method, r.URL.RawQuery, string(b)
+		gotContentType = r.Header.Get(httpdoer.HeaderNameContentType)
+		switch r.URL.Path {
+		case "/ok":
+			writeData(w, testData{Value: "hello"})
+		case "/invalid":
+			writeData(w, testData{Value: "invalid"})
+		case "/error":
+			json.NewEncoder(w).Encode(map[string]any{
+				"error":   "error",
+				"message": "no permission",
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	var observed []string
+	c.ResponseObserver = observerFunc(func(_, path string, _ []byte) {
+		observed = append(observed, path)
+	})
+	ctx := context.Background()
+	req := testFormRequest{Name: "a b", Enabled: true, Ignored: "x"}
+
+	// GET with form in the query
+	get := Endpoint[testFormRequest, testData]{http.MethodGet, "/ok", EncodingForm}
+	res, err := call(ctx, c, get, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Data.Value != "hello" || len(res.Raw) == 0 {
+		t.Fatalf("unexpected response: %#v", res)
+	}
+	if gotMethod != http.MethodGet || gotBody != "" ||
+		gotQuery != "enabled=true&name=a+b" {
+		t.Fatalf("unexpected request: %v %q %q", gotMethod, gotQuery, gotBody)
+	}
+
+	// POST with form in the body
+	post := Endpoint[testFormRequest, testData]{http.MethodPost, "/ok", EncodingForm}
+	req.Count = 3
+	req.Limit = new(bool)
+	if _, err := call(ctx, c, post, req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotQuery != "" || gotContentType != httpdoer.ContentTypeFormURLEncoded {
+		t.Fatalf("unexpected request: %q %q", gotQuery, gotContentType)
+	}
+	if v, _ := url.ParseQuery(gotBody); v.Get("count") != "3" ||
+		v.Get("name") != "a b" || v.Get("limit") != "false" ||
+		v.Has("Ignored") {
+		t.Fatalf("unexpected body: %q", gotBody)
+	}
+
+	// JSON
+	postJSON := Endpoint[testData, NoData]{http.MethodPost, "/ok", EncodingJSON}
+	if _, err := call(ctx, c, postJSON, testData{Value: "v"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotBody != `{"value":"v"}` || gotContentType != httpdoer.ContentTypeJSON {
+		t.Fatalf("unexpected request: %q %q", gotBody, gotContentType)
+	}
+
+	// validation
+	invalid := Endpoint[NoData, testData]{Method: http.MethodGet, Path: "/invalid"}
+	if _, err := call(ctx, c, invalid, NoData{}); err == nil {
+		t.Fatalf("expected validation error")
+	}
+
+	// API errors
+	for _, path := range []string{"/error", "/notfound"} {
+		e := Endpoint[NoData, NoData]{Method: http.MethodGet, Path: path}
+		_, err := call(ctx, c, e, NoData{})
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("%s: expected *APIError, got %v", path, err)
+		}
+		if apiErr.Path != path {
+			t.Fatalf("%s: unexpected error path %q", path, apiErr.Path)
+		}
+	}
+
+	// the error envelopes are not observed
+	if expected := []string{"/ok", "/ok", "/ok", "/invalid"}; !slices.Equal(observed, expected) {
+		t.Fatalf("expected observed responses %v, got %v", expected, observed)
+	}
+}
+
+func TestMetricsCircuitOpen(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(srv.Close)
+	var classes []string
+	c, err := New(Params{
+		HTTPDoer: srv.Client(),
+		BaseURL:  srv.URL,
+		Metrics: metricsFunc(func(m httpdoer.RequestMetrics) {
+			classes = append(classes, m.ErrorClass)
+		}),
+		Breaker: httpdoer.NewBreaker(httpdoer.BreakerParams{
+			FailureThreshold: 1,
+			Cooldown:         time.Hour,
+		}),
+	})
+	if err != nil {
+		t.Fatalf("create client: %v", err)
+	}
+
+	e := Endpoint[NoData, NoData]{Method: http.MethodGet, Path: "/fail"}
+	for range 2 {
+		if _, err := call(context.Background(), c.(*client), e, NoData{}); err == nil {
+			t.Fatalf("expected error")
+		}
+	}
+	if expected := []string{"", httpdoer.ErrorClassCircuitOpen}; !slices.Equal(classes, expected) {
+		t.Fatalf("expected error classes %q, got %q", expected, classes)
+	}
+}
+
+type metricsFunc func(httpdoer.RequestMetrics)
+
+func (f metricsFunc) RecordRequest(m httpdoer.RequestMetrics) { f(m) }
+
+type observerFunc func(method, path string, body []byte)
+
+func (f observerFunc) ObserveResponse(method, path string, body []byte) {
+	f(method, path, body)
+}
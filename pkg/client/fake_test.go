@@ -0,0 +1,225 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newTestClient returns a client that talks to a test server with the given
+// handler.
+func newTestClient(t *testing.T, h http.Handler) *client {
+	t.Helper()
+	srv := httptest.NewTLSServer(h)
+	t.Cleanup(srv.Close)
+	c, err := New(Params{
+		HTTPDoer: srv.Client(),
+		BaseURL:  srv.URL,
+	})
+	if err != nil {
+		t.Fatalf("create client: %v", err)
+	}
+	return c.(*client)
+}
+
+// writeData writes a successful API response with the given data.
+func writeData(w http.ResponseWriter, data any) {
+	json.NewEncoder(w).Encode(map[string]any{
+		"error":   "ok",
+		"message": "all values retrieved",
+		"data":    data,
+	})
+}
+
+// fakeMenu has the menu items of all the features.
+var fakeMenu = []MenuItem{
+	{ID: "lan"},
+	{ID: "ipv6"},
+	{ID: "parental"},
+	{ID: "diagnostics"},
+	{ID: "wifi", Items: []MenuItem{{ID: "guest"}}},
+}
+
+// fakeDevice emulates the login and session handling, and the device
+// information, of the device. Routes registered with handle require a valid
+// session.
+type fakeDevice struct {
+	mux *http.ServeMux
+
+	mu       sync.Mutex
+	logins   int
+	session  string
+	firmware string
+	menu     []MenuItem
+}
+
+func newFakeDevice(t *testing.T) (*fakeDevice, *client) {
+	t.Helper()
+	d := newFakeDeviceMux()
+	return d, newTestClient(t, d.mux)
+}
+
+// newFakeDeviceMux returns a device with the firmware
+// "CGA4233TCH3-1.0.26-TCH", which supports all the features.
+func newFakeDeviceMux() *fakeDevice {
+	d := &fakeDevice{
+		mux:      http.NewServeMux(),
+		firmware: "CGA4233TCH3-1.0.26-TCH",
+		menu:     fakeMenu,
+	}
+	d.mux.HandleFunc("POST /api/v1/session/login", d.login)
+	d.mux.HandleFunc("POST /api/v1/session/logout", func(w http.ResponseWriter, _ *http.Request) {
+		d.expire()
+		writeData(w, nil)
+	})
+	d.handle("GET /api/v1/system/{fields}", func(w http.ResponseWriter, _ *http.Request) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		writeData(w, map[string]any{
+			"ModelName":       "CGA4233TCH3",
+			"SoftwareVersion": d.firmware,
+			"UpTime":          "3600",
+		})
+	})
+	d.handle("GET /api/v1/session/menu", func(w http.ResponseWriter, _ *http.Request) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		writeData(w, d.menu)
+	})
+	return d
+}
+
+// setDeviceInfo changes the firmware and menu reported by the device.
+func (d *fakeDevice) setDeviceInfo(firmware string, menu []MenuItem) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.firmware, d.menu = firmware, menu
+}
+
+func (d *fakeDevice) login(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("password") == "seeksalthash" {
+		// no salt, so the password is sent as is
+		json.NewEncoder(w).Encode(map[string]any{
+			"error":     "ok",
+			"salt":      "none",
+			"saltwebui": "none",
+		})
+		return
+	}
+	if r.FormValue("username") != defaultUsername ||
+		r.FormValue("password") != defaultPassword {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.logins++
+	d.session = strconv.Itoa(d.logins)
+	http.SetCookie(w, &http.Cookie{Name: "sid", Value: d.session, Path: "/"})
+	writeData(w, nil)
+}
+
+// expire invalidates the current session.
+func (d *fakeDevice) expire() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.session = ""
+}
+
+func (d *fakeDevice) loginCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.logins
+}
+
+// handle registers an authenticated route.
+func (d *fakeDevice) handle(pattern string, h http.HandlerFunc) {
+	d.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		session := d.session
+		d.mu.Unlock()
+		if c, err := r.Cookie("sid"); err != nil || session == "" ||
+			c.Value != session {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		h(w, r)
+	})
+}
+
+// movingDevice is a fakeDevice that only answers at its current address, for
+// a client whose requests to any address are routed to it.
+type movingDevice struct {
+	*fakeDevice
+	srv  *httptest.Server
+	port string
+
+	addrMu sync.Mutex
+	addr   netip.Addr
+}
+
+// newMovingDevice returns a device at addr and a logged in client for it.
+// The BaseURL and HTTPDoer params are set.
+func newMovingDevice(t *testing.T, addr netip.Addr, p Params) (*movingDevice, *client) {
+	t.Helper()
+	d := &movingDevice{
+		fakeDevice: newFakeDeviceMux(),
+		addr:       addr,
+	}
+	d.srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d.addrMu.Lock()
+		ok := d.addr.IsValid() && strings.HasPrefix(r.Host, d.addr.String()+":")
+		d.addrMu.Unlock()
+		if !ok {
+			dropConnection(w)
+			return
+		}
+		d.mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(d.srv.Close)
+	_, d.port, _ = net.SplitHostPort(d.srv.Listener.Addr().String())
+
+	tr := d.srv.Client().Transport.(*http.Transport).Clone()
+	tr.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	tr.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, network, d.srv.Listener.Addr().String())
+	}
+	p.HTTPDoer = &http.Client{Transport: tr}
+	p.BaseURL = d.url(addr)
+	c, err := New(p)
+	if err != nil {
+		t.Fatalf("create client: %v", err)
+	}
+	if err := c.Login(context.Background()); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	return d, c.(*client)
+}
+
+// moveTo makes the device answer at addr, or nowhere if it is the zero value.
+func (d *movingDevice) moveTo(addr netip.Addr) {
+	d.addrMu.Lock()
+	defer d.addrMu.Unlock()
+	d.addr = addr
+}
+
+func (d *movingDevice) url(addr netip.Addr) string {
+	return "https://" + net.JoinHostPort(addr.String(), d.port)
+}
+
+// dropConnection closes the connection without responding.
+func dropConnection(w http.ResponseWriter) {
+	if conn, _, err := http.NewResponseController(w).Hijack(); err == nil {
+		conn.Close()
+	}
+}
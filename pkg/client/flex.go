@@ -0,0 +1,102 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The device API is inconsistent in how it encodes values: the same field can
+// be a JSON string in one firmware and a number or boolean in another, so the
+// DTOs use these types to decode leniently.
+
+// flexString decodes JSON strings, numbers and booleans as a string.
+type flexString string
+
+func (s *flexString) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, []byte("null")) {
+		*s = ""
+		return nil
+	}
+	var v string
+	if err := json.Unmarshal(b, &v); err == nil {
+		*s = flexString(v)
+		return nil
+	}
+	*s = flexString(b)
+	return nil
+}
+
+func (s flexString) String() string { return string(s) }
+
+// flexInt decodes JSON numbers and numeric strings as an int64. Empty strings
+// and null are zero.
+type flexInt int64
+
+func (i *flexInt) UnmarshalJSON(b []byte) error {
+	var s flexString
+	if err := s.UnmarshalJSON(b); err != nil {
+		return err
+	}
+	str := strings.TrimSpace(string(s))
+	if str == "" {
+		*i = 0
+		return nil
+	}
+	v, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		f, ferr := strconv.ParseFloat(str, 64)
+		if ferr != nil {
+			return fmt.Errorf("invalid integer %s", b)
+		}
+		v = int64(f)
+	}
+	*i = flexInt(v)
+	return nil
+}
+
+// flexFloat decodes JSON numbers and numeric strings as a float64. Empty
+// strings and null are zero.
+type flexFloat float64
+
+func (f *flexFloat) UnmarshalJSON(b []byte) error {
+	var s flexString
+	if err := s.UnmarshalJSON(b); err != nil {
+		return err
+	}
+	str := strings.TrimSpace(string(s))
+	if str == "" {
+		*f = 0
+		return nil
+	}
+	v, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %s", b)
+	}
+	*f = flexFloat(v)
+	return nil
+}
+
+// flexBool decodes JSON booleans, numbers and strings like "true", "1",
+// "enabled" or "on" as a bool.
+type flexBool bool
+
+func (fb flexBool) String() string { return strconv.FormatBool(bool(fb)) }
+
+func (fb *flexBool) UnmarshalJSON(b []byte) error {
+	var s flexString
+	if err := s.UnmarshalJSON(b); err != nil {
+		return err
+	}
+	switch strings.ToLower(strings.TrimSpace(string(s))) {
+	case "true", "1", "enable", "enabled", "on", "yes", "up":
+		*fb = true
+	case "false", "0", "disable", "disabled", "off", "no", "down", "":
+		*fb = false
+	default:
+		return fmt.Errorf("invalid boolean %s", b)
+	}
+	return nil
+}
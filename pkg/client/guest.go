@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ErrNoGuestWiFi is returned when the device has no guest Wi-Fi network.
+var ErrNoGuestWiFi = errors.New("no guest Wi-Fi network")
+
+// GuestWiFi is a guest Wi-Fi network. There is usually one for each band.
+type GuestWiFi struct {
+	// Index identifies the network in the device API.
+	Index   int
+	Band    string
+	Enabled bool
+	SSID    string
+	// Security is the security mode, e.g. "WPA2-Personal".
+	Security   string
+	Passphrase string
+	// ClientIsolation prevents the clients of the network from reaching each
+	// other.
+	ClientIsolation bool
+}
+
+// GuestWiFiSettings are changes to the settings of the guest Wi-Fi networks.
+// Nil fields are not changed.
+type GuestWiFiSettings struct {
+	Enabled         *bool
+	SSID            *string
+	Passphrase      *string
+	ClientIsolation *bool
+}
+
+// Validate checks the SSID and passphrase against the limits of IEEE 802.11
+// and WPA. The passphrase can also be a raw PSK of 64 hexadecimal digits.
+func (s GuestWiFiSettings) Validate() error {
+	if s.SSID != nil && (len(*s.SSID) == 0 || len(*s.SSID) > 32) {
+		return fmt.Errorf("SSID must have between 1 and 32 bytes, got %d",
+			len(*s.SSID))
+	}
+	if s.Passphrase != nil {
+		p := *s.Passphrase
+		if _, err := hex.DecodeString(p); err == nil && len(p) == 64 {
+			return nil
+		}
+		if len(p) < 8 || len(p) > 63 {
+			return fmt.Errorf("passphrase must have between 8 and 63 "+
+				"characters, or 64 hexadecimal digits, got %d", len(p))
+		}
+		for _, r := range p {
+			if r < 0x20 || r > 0x7e {
+				return errors.New("passphrase must have only printable ASCII " +
+					"characters")
+			}
+		}
+	}
+	return nil
+}
+
+func (c *client) GuestWiFi(ctx context.Context) ([]GuestWiFi, error) {
+	res, err := callFeature(ctx, c, FeatureWiFi, endpointWiFi, NoData{})
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []GuestWiFi
+	for _, s := range res.Data.SSIDTbl {
+		if !s.Guest {
+			continue
+		}
+		ret = append(ret, GuestWiFi{
+			Index:           int(s.ID),
+			Band:            string(s.Band),
+			Enabled:         bool(s.SSIDEnable),
+			SSID:            string(s.SSID),
+			Security:        string(s.ModeEnabled),
+			Passphrase:      string(s.KeyPassphrase),
+			ClientIsolation: bool(s.IsolationEnable),
+		})
+	}
+	if len(ret) == 0 {
+		return nil, ErrNoGuestWiFi
+	}
+
+	return ret, nil
+}
+
+func (c *client) ConfigureGuestWiFi(ctx context.Context, s GuestWiFiSettings) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	networks, err := c.GuestWiFi(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range networks {
+		_, err := callFeature(ctx, c, FeatureWiFi, endpointSetSSID(n.Index),
+			ssidSettings{
+				SSIDEnable:      s.Enabled,
+				SSID:            s.SSID,
+				KeyPassphrase:   s.Passphrase,
+				IsolationEnable: s.ClientIsolation,
+			})
+		if err != nil {
+			return fmt.Errorf("configure guest network %q (%s): %w", n.SSID,
+				n.Band, err)
+		}
+	}
+
+	return nil
+}
+
+func (c *client) SetGuestWiFi(ctx context.Context, enabled bool) error {
+	return c.ConfigureGuestWiFi(ctx, GuestWiFiSettings{Enabled: &enabled})
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestConfigureGuestWiFi(t *testing.T) {
+	t.Parallel()
+
+	d, c := newFakeDevice(t)
+	d.handle("GET /api/v1/wifi/ssidTbl", func(w http.ResponseWriter, _ *http.Request) {
+		writeData(w, map[string]any{"ssidTbl": []map[string]any{
+			{"__id": 1, "SSID": "home", "SSIDEnable": true},
+			{"__id": 3, "SSID": "guest", "GuestNetwork": "true",
+				"OperatingFrequencyBand": "2.4GHz"},
+			{"__id": 7, "SSID": "guest", "GuestNetwork": 1,
+				"OperatingFrequencyBand": "5GHz"},
+		}})
+	})
+	var mu sync.Mutex
+	forms := map[string]url.Values{}
+	d.handle("POST /api/v1/wifi/{index}", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		forms[r.PathValue("index")] = r.PostForm
+		mu.Unlock()
+		writeData(w, nil)
+	})
+	ctx := context.Background()
+
+	guests, err := c.GuestWiFi(ctx)
+	if err != nil {
+		t.Fatalf("get guest Wi-Fi: %v", err)
+	}
+	if len(guests) != 2 || guests[1].Band != "5GHz" {
+		t.Fatalf("unexpected guest networks: %#v", guests)
+	}
+
+	short := "short"
+	if err := c.ConfigureGuestWiFi(ctx, GuestWiFiSettings{Passphrase: &short}); err == nil {
+		t.Fatalf("expected validation error")
+	}
+	psk := strings.Repeat("0123456789abcdef", 4)
+	if err := (GuestWiFiSettings{Passphrase: &psk}).Validate(); err != nil {
+		t.Fatalf("raw PSK rejected: %v", err)
+	}
+	notPSK := strings.Repeat("x", 64)
+	if err := (GuestWiFiSettings{Passphrase: &notPSK}).Validate(); err == nil {
+		t.Fatalf("expected validation error for 64 characters")
+	}
+
+	enabled, pass := false, "visitors-welcome"
+	err = c.ConfigureGuestWiFi(ctx, GuestWiFiSettings{
+		Enabled:    &enabled,
+		Passphrase: &pass,
+	})
+	if err != nil {
+		t.Fatalf("configure guest Wi-Fi: %v", err)
+	}
+	if len(forms) != 2 {
+		t.Fatalf("expected the 2 guest networks to be configured, got %v", forms)
+	}
+	for index, f := range forms {
+		if f.Get("SSIDEnable") != "false" ||
+			f.Get("KeyPassphrase") != pass || f.Has("SSID") {
+			t.Errorf("network %s: unexpected form %v", index, f)
+		}
+	}
+}
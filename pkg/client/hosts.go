@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// FeatureHosts is the table of hosts known to the device.
+const FeatureHosts Feature = "hosts"
+
+var endpointHosts = Endpoint[NoData, hostsData]{
+	Method: http.MethodGet,
+	Path:   "/api/v1/host/hostTbl",
+}
+
+type hostsData struct {
+	HostTbl []hostData `json:"hostTbl"`
+}
+
+type hostData struct {
+	PhysAddress     flexString `json:"physaddress"`
+	IPAddress       flexString `json:"ipaddress"`
+	IPv6Address     flexString `json:"ipv6address"`
+	HostName        flexString `json:"hostname"`
+	Active          flexBool   `json:"active"`
+	Layer1Interface flexString `json:"layer1interface"`
+	AddressSource   flexString `json:"addresssource"`
+}
+
+// Host is a device connected, or that was connected, to the LAN.
+type Host struct {
+	MAC      string `json:"mac"`
+	IP       string `json:"ip,omitempty"`
+	IPv6     string `json:"ipv6,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+	Active   bool   `json:"active"`
+	// Interface is how the host is connected, e.g. "Ethernet" or a Wi-Fi
+	// band.
+	Interface string `json:"interface,omitempty"`
+	// AddressSource is how the host got its IP address, e.g. "DHCP" or
+	// "Static".
+	AddressSource string `json:"addressSource,omitempty"`
+}
+
+func (c *client) Hosts(ctx context.Context) ([]Host, error) {
+	res, err := callFeature(ctx, c, FeatureHosts, endpointHosts, NoData{})
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]Host, 0, len(res.Data.HostTbl))
+	for _, h := range res.Data.HostTbl {
+		ret = append(ret, Host{
+			MAC:           string(h.PhysAddress),
+			IP:            string(h.IPAddress),
+			IPv6:          string(h.IPv6Address),
+			Hostname:      string(h.HostName),
+			Active:        bool(h.Active),
+			Interface:     string(h.Layer1Interface),
+			AddressSource: string(h.AddressSource),
+		})
+	}
+
+	return ret, nil
+}
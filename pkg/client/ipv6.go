@@ -0,0 +1,449 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// FeatureIPv6 is the IPv6 status, configuration and firewall pinholes.
+const FeatureIPv6 Feature = "ipv6"
+
+var (
+	endpointIPv6WAN = Endpoint[NoData, ipv6WANData]{
+		Method: http.MethodGet,
+		Path:   "/api/v1/ipv6/wan",
+	}
+	endpointIPv6LAN = Endpoint[NoData, ipv6LANData]{
+		Method: http.MethodGet,
+		Path:   "/api/v1/ipv6/lan",
+	}
+	endpointSetIPv6LAN = Endpoint[ipv6LANData, NoData]{
+		Method:   http.MethodPost,
+		Path:     "/api/v1/ipv6/lan",
+		Encoding: EncodingForm,
+	}
+	endpointPinholes = Endpoint[NoData, pinholesData]{
+		Method: http.MethodGet,
+		Path:   "/api/v1/firewall/ipv6/pinholeTbl",
+	}
+	endpointAddPinhole = Endpoint[pinholeData, addedData]{
+		Method:   http.MethodPost,
+		Path:     "/api/v1/firewall/ipv6/pinholeTbl",
+		Encoding: EncodingForm,
+	}
+)
+
+func endpointUpdatePinhole(id int) Endpoint[pinholeData, NoData] {
+	return Endpoint[pinholeData, NoData]{
+		Method:   http.MethodPost,
+		Path:     "/api/v1/firewall/ipv6/pinholeTbl/" + strconv.Itoa(id),
+		Encoding: EncodingForm,
+	}
+}
+
+func endpointDeletePinhole(id int) Endpoint[NoData, NoData] {
+	return Endpoint[NoData, NoData]{
+		Method: http.MethodDelete,
+		Path:   "/api/v1/firewall/ipv6/pinholeTbl/" + strconv.Itoa(id),
+	}
+}
+
+type ipv6WANData struct {
+	Enable          flexBool   `json:"Enable"`
+	AddressMode     flexString `json:"AddressMode"`
+	GlobalAddress   flexString `json:"GlobalAddress"`
+	DelegatedPrefix flexString `json:"DelegatedPrefix"`
+	DefaultGateway  flexString `json:"DefaultGateway"`
+	RAReceived      flexBool   `json:"RAReceived"`
+	ManagedFlag     flexBool   `json:"ManagedFlag"`
+	OtherConfigFlag flexBool   `json:"OtherConfigFlag"`
+	DNSServers      flexString `json:"DNSServers"`
+}
+
+func (d *ipv6WANData) Validate() error {
+	_, err := d.wan()
+	return err
+}
+
+type ipv6LANData struct {
+	Enable      flexBool   `json:"Enable" form:"Enable"`
+	AddressMode flexString `json:"AddressMode" form:"AddressMode"`
+	RAEnable    flexBool   `json:"RAEnable" form:"RAEnable"`
+	DNSServers  flexString `json:"DNSServers" form:"DNSServers"`
+	ULAEnable   flexBool   `json:"ULAEnable" form:"ULAEnable"`
+	ULAPrefix   flexString `json:"ULAPrefix" form:"ULAPrefix"`
+	Prefix      flexString `json:"Prefix" form:"-"`
+	LinkLocal   flexString `json:"LinkLocalAddress" form:"-"`
+}
+
+func (d *ipv6LANData) Validate() error {
+	_, err := d.lan()
+	return err
+}
+
+type pinholesData struct {
+	PinholeTbl []pinholeData `json:"pinholeTbl"`
+}
+
+type pinholeData struct {
+	ID          flexInt    `json:"__id" form:"-"`
+	Description flexString `json:"Description" form:"Description"`
+	Enable      flexBool   `json:"Enable" form:"Enable"`
+	Protocol    flexString `json:"Protocol" form:"Protocol"`
+	DestAddress flexString `json:"DestAddress" form:"DestAddress"`
+	StartPort   flexInt    `json:"StartPort" form:"StartPort"`
+	EndPort     flexInt    `json:"EndPort" form:"EndPort"`
+}
+
+// IPv6WANMode is how the WAN interface gets its IPv6 address.
+type IPv6WANMode string
+
+const (
+	IPv6WANDisabled IPv6WANMode = "disabled"
+	IPv6WANDHCPv6   IPv6WANMode = "dhcpv6"
+	IPv6WANSLAAC    IPv6WANMode = "slaac"
+)
+
+// IPv6WAN is the IPv6 state of the WAN interface.
+type IPv6WAN struct {
+	Enabled bool
+	// Mode is reported as is, lowercased, even if it is not one of the known
+	// modes.
+	Mode IPv6WANMode
+	// Addresses are the global addresses of the interface.
+	Addresses []netip.Prefix
+	// DelegatedPrefix is the prefix delegated by the ISP with DHCPv6-PD, used
+	// for the LAN. It is the zero value if there is none.
+	DelegatedPrefix netip.Prefix
+	Gateway         netip.Addr
+	// RAReceived reports whether a router advertisement was received, and
+	// RAManaged and RAOtherConfig are its M and O flags.
+	RAReceived    bool
+	RAManaged     bool
+	RAOtherConfig bool
+	DNSServers    []netip.Addr
+}
+
+// HasDelegatedPrefix reports whether the ISP delegated a prefix.
+func (w *IPv6WAN) HasDelegatedPrefix() bool {
+	return w.DelegatedPrefix.IsValid()
+}
+
+// IPv6LANMode is how the LAN devices get their IPv6 addresses.
+type IPv6LANMode string
+
+const (
+	// IPv6LANSLAAC only uses router advertisements.
+	IPv6LANSLAAC IPv6LANMode = "slaac"
+	// IPv6LANStateless uses router advertisements for the addresses, and
+	// stateless DHCPv6 for the other settings, like the DNS servers.
+	IPv6LANStateless IPv6LANMode = "stateless"
+	// IPv6LANStateful assigns the addresses with DHCPv6.
+	IPv6LANStateful IPv6LANMode = "stateful"
+)
+
+func (m IPv6LANMode) Validate() error {
+	switch m {
+	case IPv6LANSLAAC, IPv6LANStateless, IPv6LANStateful:
+		return nil
+	}
+	return fmt.Errorf("invalid IPv6 LAN mode %q", string(m))
+}
+
+// IPv6LAN are the IPv6 settings of the LAN.
+type IPv6LAN struct {
+	Enabled bool
+	// Mode is reported as is, lowercased, even if it is not one of the known
+	// modes, but only the known modes can be set.
+	Mode                 IPv6LANMode
+	RouterAdvertisements bool
+	DNSServers           []netip.Addr
+	// ULA is the unique local prefix announced in the LAN, or the zero value
+	// to announce none.
+	ULA netip.Prefix
+
+	// Prefix is the global prefix of the LAN, taken from the delegated
+	// prefix, and LinkLocal is the link-local address of the device. They
+	// are read-only.
+	Prefix    netip.Prefix
+	LinkLocal netip.Addr
+}
+
+var ulaPrefix = netip.MustParsePrefix("fc00::/7")
+
+func (l *IPv6LAN) Validate() error {
+	if err := l.Mode.Validate(); err != nil {
+		return err
+	}
+	if l.Mode != IPv6LANStateful && l.Enabled && !l.RouterAdvertisements {
+		return fmt.Errorf("IPv6 LAN mode %q needs router advertisements",
+			l.Mode)
+	}
+	if len(l.DNSServers) > maxDNSServers {
+		return fmt.Errorf("at most %d DNS servers are supported", maxDNSServers)
+	}
+	for _, ip := range l.DNSServers {
+		if !ip.Is6() || ip.Is4In6() {
+			return fmt.Errorf("DNS server %s is not an IPv6 address", ip)
+		}
+	}
+	if l.ULA.IsValid() && (!ulaPrefix.Contains(l.ULA.Addr()) ||
+		l.ULA.Bits() < 48 || l.ULA.Bits() > 64) {
+		return fmt.Errorf("invalid unique local prefix %s", l.ULA)
+	}
+	return nil
+}
+
+// PinholeProtocol is the protocol of a Pinhole.
+type PinholeProtocol string
+
+const (
+	PinholeTCP  PinholeProtocol = "tcp"
+	PinholeUDP  PinholeProtocol = "udp"
+	PinholeBoth PinholeProtocol = "both"
+)
+
+// Pinhole is an IPv6 firewall rule that allows the incoming connections to a
+// range of ports of a LAN device.
+type Pinhole struct {
+	// ID identifies the pinhole in the device. It is assigned by the device.
+	ID          int
+	Description string
+	Enabled     bool
+	Protocol    PinholeProtocol
+	Dest        netip.Addr
+	// PortStart and PortEnd are the range of ports, both inclusive.
+	PortStart, PortEnd uint16
+}
+
+func (p Pinhole) Validate() error {
+	switch {
+	case p.Protocol != PinholeTCP && p.Protocol != PinholeUDP &&
+		p.Protocol != PinholeBoth:
+		return fmt.Errorf("invalid pinhole protocol %q", p.Protocol)
+	case !p.Dest.Is6() || !p.Dest.IsGlobalUnicast() || p.Dest.IsPrivate():
+		return fmt.Errorf("pinhole destination %s is not a global IPv6 "+
+			"address", p.Dest)
+	case p.PortStart == 0 || p.PortStart > p.PortEnd:
+		return fmt.Errorf("invalid pinhole port range %d-%d", p.PortStart,
+			p.PortEnd)
+	}
+	return nil
+}
+
+func (p Pinhole) data() pinholeData {
+	return pinholeData{
+		Description: flexString(p.Description),
+		Enable:      flexBool(p.Enabled),
+		Protocol:    flexString(strings.ToUpper(string(p.Protocol))),
+		DestAddress: flexString(p.Dest.String()),
+		StartPort:   flexInt(p.PortStart),
+		EndPort:     flexInt(p.PortEnd),
+	}
+}
+
+// parsePrefixList parses a list of prefixes, or of addresses that are taken
+// as single-address prefixes.
+func parsePrefixList(s string) ([]netip.Prefix, error) {
+	var ret []netip.Prefix
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' '
+	}) {
+		p, err := parsePrefixOrAddr(f)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, p)
+	}
+	return ret, nil
+}
+
+func parsePrefixOrAddr(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		return netip.ParsePrefix(s)
+	}
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(ip, ip.BitLen()), nil
+}
+
+// parseOptionalPrefix parses a prefix, returning the zero value if s is
+// empty.
+func parseOptionalPrefix(s string) (netip.Prefix, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return netip.Prefix{}, nil
+	}
+	return netip.ParsePrefix(s)
+}
+
+// parseOptionalAddr parses an address, returning the zero value if s is
+// empty.
+func parseOptionalAddr(s string) (netip.Addr, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return netip.Addr{}, nil
+	}
+	return netip.ParseAddr(s)
+}
+
+func (d *ipv6WANData) wan() (*IPv6WAN, error) {
+	w := &IPv6WAN{
+		Enabled:       bool(d.Enable),
+		Mode:          IPv6WANMode(strings.ToLower(string(d.AddressMode))),
+		RAReceived:    bool(d.RAReceived),
+		RAManaged:     bool(d.ManagedFlag),
+		RAOtherConfig: bool(d.OtherConfigFlag),
+	}
+
+	var err error
+	if w.Addresses, err = parsePrefixList(string(d.GlobalAddress)); err != nil {
+		return nil, fmt.Errorf("global addresses: %w", err)
+	}
+	if w.DelegatedPrefix, err = parseOptionalPrefix(
+		string(d.DelegatedPrefix)); err != nil {
+		return nil, fmt.Errorf("delegated prefix: %w", err)
+	}
+	if w.Gateway, err = parseOptionalAddr(string(d.DefaultGateway)); err != nil {
+		return nil, fmt.Errorf("default gateway: %w", err)
+	}
+	if w.DNSServers, err = parseAddrList(string(d.DNSServers)); err != nil {
+		return nil, fmt.Errorf("DNS servers: %w", err)
+	}
+
+	return w, nil
+}
+
+func (d *ipv6LANData) lan() (*IPv6LAN, error) {
+	l := &IPv6LAN{
+		Enabled:              bool(d.Enable),
+		Mode:                 IPv6LANMode(strings.ToLower(string(d.AddressMode))),
+		RouterAdvertisements: bool(d.RAEnable),
+	}
+
+	var err error
+	if l.DNSServers, err = parseAddrList(string(d.DNSServers)); err != nil {
+		return nil, fmt.Errorf("DNS servers: %w", err)
+	}
+	if d.ULAEnable {
+		if l.ULA, err = parseOptionalPrefix(string(d.ULAPrefix)); err != nil {
+			return nil, fmt.Errorf("ULA prefix: %w", err)
+		}
+	}
+	if l.Prefix, err = parseOptionalPrefix(string(d.Prefix)); err != nil {
+		return nil, fmt.Errorf("prefix: %w", err)
+	}
+	if l.LinkLocal, err = parseOptionalAddr(string(d.LinkLocal)); err != nil {
+		return nil, fmt.Errorf("link-local address: %w", err)
+	}
+
+	return l, nil
+}
+
+func (c *client) IPv6WAN(ctx context.Context) (*IPv6WAN, error) {
+	res, err := callFeature(ctx, c, FeatureIPv6, endpointIPv6WAN, NoData{})
+	if err != nil {
+		return nil, err
+	}
+	return res.Data.wan() // validated
+}
+
+func (c *client) IPv6LAN(ctx context.Context) (*IPv6LAN, error) {
+	res, err := callFeature(ctx, c, FeatureIPv6, endpointIPv6LAN, NoData{})
+	if err != nil {
+		return nil, err
+	}
+	return res.Data.lan() // validated
+}
+
+func (c *client) SetIPv6LAN(ctx context.Context, l IPv6LAN) error {
+	if err := l.Validate(); err != nil {
+		return err
+	}
+
+	dns := make([]string, len(l.DNSServers))
+	for i, ip := range l.DNSServers {
+		dns[i] = ip.String()
+	}
+	d := ipv6LANData{
+		Enable:      flexBool(l.Enabled),
+		AddressMode: flexString(l.Mode),
+		RAEnable:    flexBool(l.RouterAdvertisements),
+		DNSServers:  flexString(strings.Join(dns, ",")),
+		ULAEnable:   flexBool(l.ULA.IsValid()),
+	}
+	if l.ULA.IsValid() {
+		d.ULAPrefix = flexString(l.ULA.String())
+	}
+	_, err := callFeature(ctx, c, FeatureIPv6, endpointSetIPv6LAN, d)
+	return err
+}
+
+func (c *client) Pinholes(ctx context.Context) ([]Pinhole, error) {
+	res, err := callFeature(ctx, c, FeatureIPv6, endpointPinholes, NoData{})
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]Pinhole, 0, len(res.Data.PinholeTbl))
+	for _, d := range res.Data.PinholeTbl {
+		for _, port := range []flexInt{d.StartPort, d.EndPort} {
+			if port < 0 || port > math.MaxUint16 {
+				return nil, fmt.Errorf("%w: pinhole %d: invalid port %d",
+					errUnexpectedResponse, int(d.ID), int(port))
+			}
+		}
+		p := Pinhole{
+			ID:          int(d.ID),
+			Description: string(d.Description),
+			Enabled:     bool(d.Enable),
+			Protocol: PinholeProtocol(strings.ToLower(
+				string(d.Protocol))),
+			PortStart: uint16(d.StartPort),
+			PortEnd:   uint16(d.EndPort),
+		}
+		if p.Dest, err = netip.ParseAddr(string(d.DestAddress)); err != nil {
+			return nil, fmt.Errorf("%w: pinhole %d: %w", errUnexpectedResponse,
+				p.ID, err)
+		}
+		ret = append(ret, p)
+	}
+
+	return ret, nil
+}
+
+func (c *client) AddPinhole(ctx context.Context, p Pinhole) (Pinhole, error) {
+	if err := p.Validate(); err != nil {
+		return Pinhole{}, err
+	}
+	res, err := callFeature(ctx, c, FeatureIPv6, endpointAddPinhole, p.data())
+	if err != nil {
+		return Pinhole{}, err
+	}
+	p.ID = int(res.Data.ID)
+	return p, nil
+}
+
+func (c *client) UpdatePinhole(ctx context.Context, p Pinhole) error {
+	if p.ID == 0 {
+		return errors.New("missing pinhole ID")
+	}
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	_, err := callFeature(ctx, c, FeatureIPv6, endpointUpdatePinhole(p.ID),
+		p.data())
+	return err
+}
+
+func (c *client) DeletePinhole(ctx context.Context, id int) error {
+	_, err := callFeature(ctx, c, FeatureIPv6, endpointDeletePinhole(id),
+		NoData{})
+	return err
+}
@@ -0,0 +1,161 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/netip"
+	"sync"
+	"testing"
+)
+
+func TestIPv6(t *testing.T) {
+	t.Parallel()
+
+	d, c := newFakeDevice(t)
+	var mu sync.Mutex
+	wan := map[string]any{
+		"Enable":          "true",
+		"AddressMode":     "DHCPv6",
+		"GlobalAddress":   "2001:db8::10/128, 2001:db8::11",
+		"DelegatedPrefix": "",
+		"DefaultGateway":  "fe80::1",
+		"RAReceived":      "true",
+		"ManagedFlag":     "true",
+		"OtherConfigFlag": "true",
+		"DNSServers":      "2001:db8::53",
+	}
+	lan := map[string]any{
+		"Enable":           "true",
+		"AddressMode":      "SLAAC",
+		"RAEnable":         "true",
+		"DNSServers":       "",
+		"ULAEnable":        "false",
+		"ULAPrefix":        "",
+		"Prefix":           "2001:db8:1::/64",
+		"LinkLocalAddress": "fe80::2",
+	}
+	d.handle("GET /api/v1/ipv6/wan", func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		writeData(w, wan)
+	})
+	d.handle("GET /api/v1/ipv6/lan", func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		writeData(w, lan)
+	})
+	d.handle("POST /api/v1/ipv6/lan", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		r.ParseForm()
+		for k := range r.PostForm {
+			lan[k] = r.PostForm.Get(k)
+		}
+		writeData(w, nil)
+	})
+	var pinhole map[string]any
+	d.handle("POST /api/v1/firewall/ipv6/pinholeTbl", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		r.ParseForm()
+		pinhole = map[string]any{"__id": 7}
+		for k := range r.PostForm {
+			pinhole[k] = r.PostForm.Get(k)
+		}
+		writeData(w, map[string]any{"__id": 7})
+	})
+	d.handle("GET /api/v1/firewall/ipv6/pinholeTbl", func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		writeData(w, map[string]any{"pinholeTbl": []any{pinhole}})
+	})
+	ctx := context.Background()
+
+	w, err := c.IPv6WAN(ctx)
+	if err != nil {
+		t.Fatalf("get WAN: %v", err)
+	}
+	if w.Mode != IPv6WANDHCPv6 || len(w.Addresses) != 2 ||
+		w.Addresses[1] != netip.MustParsePrefix("2001:db8::11/128") ||
+		w.HasDelegatedPrefix() || !w.RAManaged || len(w.DNSServers) != 1 {
+		t.Fatalf("unexpected WAN state: %#v", w)
+	}
+	mu.Lock()
+	wan["DelegatedPrefix"] = "2001:db8:1::/56"
+	mu.Unlock()
+	if w, err := c.IPv6WAN(ctx); err != nil || !w.HasDelegatedPrefix() {
+		t.Fatalf("expected delegated prefix: %#v, %v", w, err)
+	}
+
+	l, err := c.IPv6LAN(ctx)
+	if err != nil {
+		t.Fatalf("get LAN: %v", err)
+	}
+	if l.Mode != IPv6LANSLAAC || l.Prefix != netip.MustParsePrefix("2001:db8:1::/64") ||
+		l.ULA.IsValid() {
+		t.Fatalf("unexpected LAN settings: %#v", l)
+	}
+
+	// SLAAC without router advertisements would leave the LAN without IPv6
+	l.RouterAdvertisements = false
+	if err := c.SetIPv6LAN(ctx, *l); err == nil {
+		t.Fatalf("expected validation error")
+	}
+	l.RouterAdvertisements = true
+	l.Mode = IPv6LANStateless
+	l.ULA = netip.MustParsePrefix("fd12:3456:789a::/48")
+	l.DNSServers = []netip.Addr{netip.MustParseAddr("2606:4700:4700::1111")}
+	if err := c.SetIPv6LAN(ctx, *l); err != nil {
+		t.Fatalf("set LAN: %v", err)
+	}
+	got, err := c.IPv6LAN(ctx)
+	if err != nil {
+		t.Fatalf("get LAN: %v", err)
+	}
+	if got.Mode != IPv6LANStateless || got.ULA != l.ULA || len(got.DNSServers) != 1 {
+		t.Fatalf("unexpected LAN settings after update: %#v", got)
+	}
+
+	// pinholes
+	p := Pinhole{
+		Enabled:   true,
+		Protocol:  PinholeTCP,
+		Dest:      netip.MustParseAddr("fd00::5"),
+		PortStart: 443,
+		PortEnd:   443,
+	}
+	if _, err := c.AddPinhole(ctx, p); err == nil {
+		t.Fatalf("expected error for a unique local destination")
+	}
+	p.Dest = netip.MustParseAddr("2001:db8:1::5")
+	if p, err = c.AddPinhole(ctx, p); err != nil || p.ID != 7 {
+		t.Fatalf("add pinhole: %#v, %v", p, err)
+	}
+	pinholes, err := c.Pinholes(ctx)
+	if err != nil {
+		t.Fatalf("get pinholes: %v", err)
+	}
+	if len(pinholes) != 1 || pinholes[0] != p {
+		t.Fatalf("unexpected pinholes: %#v", pinholes)
+	}
+
+	// out of range ports are unexpected
+	mu.Lock()
+	pinhole["EndPort"] = "70000"
+	mu.Unlock()
+	if _, err := c.Pinholes(ctx); !errors.Is(err, errUnexpectedResponse) {
+		t.Fatalf("expected unexpected response error, got %v", err)
+	}
+
+	// unknown LAN modes are reported as is, but cannot be set
+	mu.Lock()
+	lan["AddressMode"] = "Magic"
+	mu.Unlock()
+	if l, err = c.IPv6LAN(ctx); err != nil || l.Mode != "magic" {
+		t.Fatalf("unexpected LAN settings: %#v, %v", l, err)
+	}
+	if err := c.SetIPv6LAN(ctx, *l); err == nil {
+		t.Fatalf("expected error setting an unknown mode")
+	}
+}
@@ -0,0 +1,351 @@
+package client
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/httpdoer"
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/trace"
+)
+
+// FeatureLAN is the LAN address configuration.
+const FeatureLAN Feature = "lan"
+
+const (
+	// DefaultLANChangeTimeout is how long SetLAN waits by default for the
+	// device to answer at its new address.
+	DefaultLANChangeTimeout = 2 * time.Minute
+
+	// applyTimeout bounds the requests that change the address of the
+	// device, which may never be answered since the device stops listening
+	// at the old one.
+	applyTimeout = 10 * time.Second
+	// retryDelay is the delay between the attempts to reach the device at
+	// its new address.
+	retryDelay = 3 * time.Second
+)
+
+var (
+	endpointLAN = Endpoint[NoData, lanData]{
+		Method: http.MethodGet,
+		Path:   "/api/v1/lan/ipv4",
+	}
+	endpointSetLAN = Endpoint[lanData, NoData]{
+		Method:   http.MethodPost,
+		Path:     "/api/v1/lan/ipv4",
+		Encoding: EncodingForm,
+	}
+)
+
+type lanData struct {
+	IPAddress  flexString `json:"IPAddress" form:"IPAddress"`
+	SubnetMask flexString `json:"SubnetMask" form:"SubnetMask"`
+}
+
+func (d *lanData) Validate() error {
+	_, err := d.prefix()
+	return err
+}
+
+func (d *lanData) prefix() (netip.Prefix, error) {
+	ip, err := netip.ParseAddr(string(d.IPAddress))
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("IP address: %w", err)
+	}
+	mask := net.IPMask(net.ParseIP(string(d.SubnetMask)).To4())
+	ones, bits := mask.Size()
+	if bits != 32 {
+		return netip.Prefix{}, fmt.Errorf("invalid subnet mask %q",
+			d.SubnetMask)
+	}
+	return netip.PrefixFrom(ip, ones), nil
+}
+
+// newLANData returns the request that sets the address of the device to the
+// address of the prefix, which is not masked.
+func newLANData(addr netip.Prefix) lanData {
+	mask := net.CIDRMask(addr.Bits(), 32)
+	return lanData{
+		IPAddress:  flexString(addr.Addr().String()),
+		SubnetMask: flexString(net.IP(mask).String()),
+	}
+}
+
+// ValidateLANAddress checks that addr, e.g. 192.168.0.1/24, can be used as the
+// LAN address of the device.
+func ValidateLANAddress(addr netip.Prefix) error {
+	ip := addr.Addr()
+	switch {
+	case !addr.IsValid() || !ip.Is4():
+		return fmt.Errorf("invalid IPv4 LAN address %s", addr)
+	case !ip.IsPrivate():
+		return fmt.Errorf("LAN address %s is not private", ip)
+	case addr.Bits() < 8 || addr.Bits() > 30:
+		return fmt.Errorf("LAN subnet /%d must be between /8 and /30",
+			addr.Bits())
+	case ip == addr.Masked().Addr():
+		return fmt.Errorf("%s is the network address of %s", ip, addr.Masked())
+	case ip == lastAddr(addr):
+		return fmt.Errorf("%s is the broadcast address of %s", ip,
+			addr.Masked())
+	}
+	return nil
+}
+
+// lastAddr returns the last address of the prefix.
+func lastAddr(p netip.Prefix) netip.Addr {
+	b := p.Masked().Addr().As4()
+	n := binary.BigEndian.Uint32(b[:]) | (1<<(32-p.Bits()) - 1)
+	binary.BigEndian.PutUint32(b[:], n)
+	return netip.AddrFrom4(b)
+}
+
+func (c *client) LAN(ctx context.Context) (netip.Prefix, error) {
+	res, err := callFeature(ctx, c, FeatureLAN, endpointLAN, NoData{})
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return res.Data.prefix() // validated
+}
+
+// LANChangeError is returned by SetLAN when the device doesn't answer at its
+// new address. Its message explains how to recover access to the device.
+type LANChangeError struct {
+	// OldAddr and NewAddr are the LAN addresses before and after the change.
+	OldAddr, NewAddr netip.Prefix
+	// OldURL and NewURL are the base URLs before and after the change.
+	OldURL, NewURL string
+	Err            error
+}
+
+func (e *LANChangeError) Error() string {
+	subnet := e.NewAddr.Masked()
+	static := e.NewAddr.Addr().Next()
+	if static == lastAddr(e.NewAddr) {
+		static = e.NewAddr.Addr().Prev()
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "the device did not answer at %s after changing its "+
+		"LAN address from %s to %s: %v\n", e.NewURL, e.OldAddr, e.NewAddr,
+		e.Err)
+	writeRecovery(&b,
+		fmt.Sprintf("renew the DHCP lease of this computer, e.g. by "+
+			"reconnecting it to the network, so that it gets an address in "+
+			"%s, and connect to %s", subnet, e.NewURL),
+		fmt.Sprintf("if it doesn't get one, configure the static address "+
+			"%s/%d in this computer and connect to %s", static,
+			e.NewAddr.Bits(), e.NewURL),
+		fmt.Sprintf("if the change was not applied, the device is still at %s",
+			e.OldURL),
+	)
+	return b.String()
+}
+
+// writeRecovery writes the numbered steps to recover access to the device,
+// followed by the factory reset as the last one.
+func writeRecovery(b *strings.Builder, steps ...string) {
+	steps = append(steps, "as a last resort, hold the reset button of the "+
+		"device for 30 seconds to restore the factory settings, which erases "+
+		"all the configuration")
+	b.WriteString("to recover access to the device:")
+	for i, s := range steps {
+		fmt.Fprintf(b, "\n  %d. %s", i+1, s)
+	}
+}
+
+func (e *LANChangeError) Unwrap() error { return e.Err }
+
+func (c *client) SetLAN(ctx context.Context, addr netip.Prefix, timeout time.Duration) (err error) {
+	ctx, span := c.startSpan(ctx, "client.SetLAN",
+		trace.String("addr", addr.String()))
+	defer func() { trace.End(span, err) }()
+
+	if err := ValidateLANAddress(addr); err != nil {
+		return err
+	}
+	if timeout <= 0 {
+		timeout = DefaultLANChangeTimeout
+	}
+
+	oldAddr, err := c.LAN(ctx)
+	if err != nil {
+		return err
+	}
+	if oldAddr == addr {
+		return nil
+	}
+	oldURL := c.CurrentBaseURL()
+	newURL, err := replaceURLHost(oldURL, addr.Addr())
+	if err != nil {
+		return err
+	}
+
+	err = c.applyLosingConnection(ctx, func(ctx context.Context) error {
+		_, err := callFeature(ctx, c, FeatureLAN, endpointSetLAN,
+			newLANData(addr))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := c.retarget(ctx, newURL, timeout); err != nil {
+		return &LANChangeError{
+			OldAddr: oldAddr,
+			NewAddr: addr,
+			OldURL:  oldURL,
+			NewURL:  newURL,
+			Err:     err,
+		}
+	}
+
+	return nil
+}
+
+// applyLosingConnection calls apply to make a change that may drop the
+// connection to the device, like changing its address. Only the API errors,
+// which mean that the change was rejected, and the cancellation of ctx are
+// returned. Callers read the current setting first, which also checks that the
+// device is reachable, so that the errors of the change can be attributed to
+// it.
+func (c *client) applyLosingConnection(
+	ctx context.Context,
+	apply func(context.Context) error,
+) error {
+	ctx, span := c.startSpan(ctx, "client.apply")
+	defer span.End()
+
+	applyCtx, cancel := context.WithTimeout(ctx, applyTimeout)
+	err := apply(applyCtx)
+	cancel()
+	var apiErr *APIError
+	if errors.As(err, &apiErr) || errors.Is(err, ErrUnsupported) {
+		return err
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		// the connection is usually lost while the device applies the change
+		span.RecordError(err)
+	}
+	return nil
+}
+
+// retarget makes the client use newURL, copying the pinned certificate of the
+// current URL, and waits up to timeout for the device to answer there before
+// logging in again. answered reports whether the device answered, even if the
+// login failed.
+func (c *client) retarget(
+	ctx context.Context,
+	newURL string,
+	timeout time.Duration,
+) (answered bool, err error) {
+	if err := c.copyPin(c.CurrentBaseURL(), newURL); err != nil {
+		return false, err
+	}
+	c.setCurrentBaseURL(newURL)
+	c.forgetSession()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := retry(ctx, func() error { return c.probe(ctx, newURL) }); err != nil {
+		return false, err
+	}
+	return true, retry(ctx, func() error { return c.Login(ctx) })
+}
+
+// probe sends a request to url, and succeeds if it gets any response.
+func (c *client) probe(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	res, err := c.HTTPDoer.Do(req)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// retry calls f until it succeeds or ctx is done.
+func retry(ctx context.Context, f func() error) error {
+	for {
+		err := f()
+		if err == nil {
+			return nil
+		}
+		// retrying won't fix a different certificate
+		var pinErr *httpdoer.PinMismatchError
+		if errors.As(err, &pinErr) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w; last error: %w", ctx.Err(), err)
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+// copyPin pins the certificate of the old URL for the new one, if the client
+// pins certificates and the old one is known.
+func (c *client) copyPin(oldURL, newURL string) error {
+	k := c.Transport.KnownHosts
+	if k == nil {
+		return nil
+	}
+	oldHost, err := urlHostPort(oldURL)
+	if err != nil {
+		return err
+	}
+	newHost, err := urlHostPort(newURL)
+	if err != nil {
+		return err
+	}
+	fingerprint, ok, err := k.Lookup(oldHost)
+	if err != nil || !ok {
+		return err
+	}
+	return k.Pin(newHost, fingerprint)
+}
+
+// replaceURLHost replaces the host of the URL with ip, keeping the port.
+func replaceURLHost(rawURL string, ip netip.Addr) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("parse base URL: %w", err)
+	}
+	if port := u.Port(); port != "" {
+		u.Host = net.JoinHostPort(ip.String(), port)
+	} else {
+		u.Host = ip.String()
+	}
+	return u.String(), nil
+}
+
+// urlHostPort returns the "host:port" of the URL, as used in the known hosts
+// file.
+func urlHostPort(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("parse base URL: %w", err)
+	}
+	if u.Port() != "" {
+		return u.Host, nil
+	}
+	port := "443"
+	if u.Scheme == "http" {
+		port = "80"
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/netip"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/httpdoer"
+)
+
+func TestValidateLANAddress(t *testing.T) {
+	t.Parallel()
+
+	for addr, valid := range map[string]bool{
+		"10.0.0.1/24":      true,
+		"192.168.1.254/24": true,
+		"172.16.5.1/16":    true,
+		"10.0.0.0/24":      false,
+		"10.0.0.255/24":    false,
+		"8.8.8.1/24":       false,
+		"10.0.0.1/31":      false,
+		"fd00::1/64":       false,
+	} {
+		err := ValidateLANAddress(netip.MustParsePrefix(addr))
+		if (err == nil) != valid {
+			t.Errorf("%s: unexpected error %v", addr, err)
+		}
+	}
+}
+
+func TestSetLAN(t *testing.T) {
+	t.Parallel()
+
+	oldAddr := netip.MustParseAddr("192.168.0.1")
+	knownHosts := httpdoer.NewKnownHosts(filepath.Join(t.TempDir(), "known_hosts"))
+	d, c := newMovingDevice(t, oldAddr, Params{
+		Transport: httpdoer.TransportParams{KnownHosts: knownHosts},
+	})
+	port := d.port
+	fingerprint := httpdoer.Fingerprint(d.srv.Certificate())
+	if err := knownHosts.Pin(oldAddr.String()+":"+port, fingerprint); err != nil {
+		t.Fatalf("pin: %v", err)
+	}
+
+	var mu sync.Mutex
+	prefix := netip.MustParsePrefix("192.168.0.1/24")
+	lose := false
+	d.handle("GET /api/v1/lan/ipv4", func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		writeData(w, newLANData(prefix))
+	})
+	d.handle("POST /api/v1/lan/ipv4", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		prefix = netip.MustParsePrefix(r.FormValue("IPAddress") + "/24")
+		if lose {
+			d.moveTo(netip.Addr{})
+		} else {
+			d.moveTo(prefix.Addr())
+		}
+		mu.Unlock()
+		// the connection is lost while the change is applied
+		dropConnection(w)
+	})
+	ctx := context.Background()
+
+	if err := c.SetLAN(ctx, netip.MustParsePrefix("10.0.0.1/24"), time.Second); err != nil {
+		t.Fatalf("set LAN: %v", err)
+	}
+	if got := c.CurrentBaseURL(); got != "https://10.0.0.1:"+port {
+		t.Fatalf("unexpected base URL %q", got)
+	}
+	if got, err := c.LAN(ctx); err != nil || got != netip.MustParsePrefix("10.0.0.1/24") {
+		t.Fatalf("unexpected LAN address %v: %v", got, err)
+	}
+	if fp, ok, _ := knownHosts.Lookup("10.0.0.1:" + port); !ok || fp != fingerprint {
+		t.Fatalf("pin not copied: %q", fp)
+	}
+
+	// the device is lost after the change
+	mu.Lock()
+	lose = true
+	mu.Unlock()
+	err := c.SetLAN(ctx, netip.MustParsePrefix("10.1.0.1/24"), 100*time.Millisecond)
+	var lanErr *LANChangeError
+	if !errors.As(err, &lanErr) {
+		t.Fatalf("expected *LANChangeError, got %v", err)
+	}
+	for _, s := range []string{"https://10.1.0.1:" + port, "10.1.0.0/24", "10.1.0.2/24",
+		"https://10.0.0.1:" + port} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("error does not mention %q: %v", s, err)
+		}
+	}
+}
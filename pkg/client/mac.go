@@ -0,0 +1,30 @@
+package client
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// NormalizeMAC parses a 48-bit MAC address in any of the usual formats,
+// including 12 hexadecimal digits without separators, and returns it in the
+// lowercase, colon separated form used by the device.
+func NormalizeMAC(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == 12 && !strings.ContainsAny(s, ":-.") {
+		var b strings.Builder
+		for i := 0; i < len(s); i += 2 {
+			if i > 0 {
+				b.WriteByte(':')
+			}
+			b.WriteString(s[i : i+2])
+		}
+		s = b.String()
+	}
+
+	hw, err := net.ParseMAC(s)
+	if err != nil || len(hw) != 6 {
+		return "", fmt.Errorf("invalid MAC address %q", s)
+	}
+	return hw.String(), nil
+}
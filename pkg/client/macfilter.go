@@ -0,0 +1,300 @@
+package client
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MACFilterMode is the mode of the Wi-Fi MAC filter of a network.
+type MACFilterMode string
+
+const (
+	MACFilterDisabled MACFilterMode = "disabled"
+	// MACFilterAllow only allows the devices in the list to connect.
+	MACFilterAllow MACFilterMode = "allow"
+	// MACFilterDeny allows all devices except the ones in the list.
+	MACFilterDeny MACFilterMode = "deny"
+)
+
+func (m MACFilterMode) Validate() error {
+	switch m {
+	case MACFilterDisabled, MACFilterAllow, MACFilterDeny:
+		return nil
+	}
+	return fmt.Errorf("invalid MAC filter mode %q", string(m))
+}
+
+func endpointMACFilter(index int) Endpoint[NoData, macFilterData] {
+	return Endpoint[NoData, macFilterData]{
+		Method: http.MethodGet,
+		Path:   "/api/v1/wifi/" + strconv.Itoa(index) + "/macfilter",
+	}
+}
+
+func endpointSetMACFilterMode(index int) Endpoint[macFilterModeRequest, NoData] {
+	return Endpoint[macFilterModeRequest, NoData]{
+		Method:   http.MethodPost,
+		Path:     "/api/v1/wifi/" + strconv.Itoa(index) + "/macfilter",
+		Encoding: EncodingForm,
+	}
+}
+
+func endpointAddMACFilterEntry(index int) Endpoint[macFilterEntryData, NoData] {
+	return Endpoint[macFilterEntryData, NoData]{
+		Method:   http.MethodPost,
+		Path:     "/api/v1/wifi/" + strconv.Itoa(index) + "/macfilterTbl",
+		Encoding: EncodingForm,
+	}
+}
+
+func endpointDeleteMACFilterEntry(index, id int) Endpoint[NoData, NoData] {
+	return Endpoint[NoData, NoData]{
+		Method: http.MethodDelete,
+		Path: "/api/v1/wifi/" + strconv.Itoa(index) + "/macfilterTbl/" +
+			strconv.Itoa(id),
+	}
+}
+
+type macFilterModeRequest struct {
+	Mode MACFilterMode `form:"Mode"`
+}
+
+type macFilterData struct {
+	Mode         flexString           `json:"Mode"`
+	MACFilterTbl []macFilterEntryData `json:"macfilterTbl"`
+}
+
+func (d *macFilterData) Validate() error {
+	d.Mode = flexString(strings.ToLower(string(d.Mode)))
+	if err := MACFilterMode(d.Mode).Validate(); err != nil {
+		return err
+	}
+	for _, e := range d.MACFilterTbl {
+		if _, err := NormalizeMAC(string(e.MACAddress)); err != nil {
+			return fmt.Errorf("entry %d: %w", e.ID, err)
+		}
+	}
+	return nil
+}
+
+type macFilterEntryData struct {
+	ID         flexInt    `json:"__id" form:"-"`
+	MACAddress flexString `json:"MACAddress" form:"MACAddress"`
+	Name       flexString `json:"Name" form:"Name"`
+}
+
+// MACFilterEntry is a device in the MAC filter list.
+type MACFilterEntry struct {
+	MAC  string
+	Name string
+}
+
+// MACFilter is the MAC filter of a Wi-Fi network.
+type MACFilter struct {
+	// Index identifies the network in the device API. See WiFiNetwork.
+	Index   int
+	Mode    MACFilterMode
+	Entries []MACFilterEntry
+
+	// ids are the IDs of the entries in the device, by MAC
+	ids map[string]int
+}
+
+// ParseMACFilterList reads a list of MAC filter entries, one per line, with
+// the MAC address followed by an optional name. Empty lines and lines starting
+// with "#" are ignored. The MAC addresses are normalized, and duplicates are an
+// error.
+func ParseMACFilterList(r io.Reader) ([]MACFilterEntry, error) {
+	var ret []MACFilterEntry
+	seen := make(map[string]int)
+	s := bufio.NewScanner(r)
+	for lineNum := 1; s.Scan(); lineNum++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		mac, err := NormalizeMAC(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		if prev, ok := seen[mac]; ok {
+			return nil, fmt.Errorf("line %d: %s already in line %d", lineNum,
+				mac, prev)
+		}
+		seen[mac] = lineNum
+		ret = append(ret, MACFilterEntry{
+			MAC:  mac,
+			Name: strings.Join(fields[1:], " "),
+		})
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("read MAC filter list: %w", err)
+	}
+	return ret, nil
+}
+
+func (c *client) MACFilter(ctx context.Context, index int) (*MACFilter, error) {
+	res, err := callFeature(ctx, c, FeatureWiFi, endpointMACFilter(index),
+		NoData{})
+	if err != nil {
+		return nil, err
+	}
+
+	f := &MACFilter{
+		Index: index,
+		Mode:  MACFilterMode(res.Data.Mode),
+		ids:   make(map[string]int),
+	}
+	for _, e := range res.Data.MACFilterTbl {
+		mac, _ := NormalizeMAC(string(e.MACAddress)) // validated
+		f.Entries = append(f.Entries, MACFilterEntry{
+			MAC:  mac,
+			Name: string(e.Name),
+		})
+		f.ids[mac] = int(e.ID)
+	}
+
+	return f, nil
+}
+
+func (c *client) SetMACFilterMode(ctx context.Context, index int, mode MACFilterMode) error {
+	if err := mode.Validate(); err != nil {
+		return err
+	}
+	_, err := callFeature(ctx, c, FeatureWiFi, endpointSetMACFilterMode(index),
+		macFilterModeRequest{Mode: mode})
+	return err
+}
+
+func (c *client) AddMACFilterEntry(ctx context.Context, index int, e MACFilterEntry) error {
+	mac, err := NormalizeMAC(e.MAC)
+	if err != nil {
+		return err
+	}
+	_, err = callFeature(ctx, c, FeatureWiFi, endpointAddMACFilterEntry(index),
+		macFilterEntryData{
+			MACAddress: flexString(mac),
+			Name:       flexString(e.Name),
+		})
+	return err
+}
+
+func (c *client) DeleteMACFilterEntry(ctx context.Context, index int, mac string) error {
+	mac, err := NormalizeMAC(mac)
+	if err != nil {
+		return err
+	}
+	f, err := c.MACFilter(ctx, index)
+	if err != nil {
+		return err
+	}
+	id, ok := f.ids[mac]
+	if !ok {
+		return fmt.Errorf("%s is not in the MAC filter", mac)
+	}
+	return c.deleteMACFilterEntry(ctx, index, id)
+}
+
+func (c *client) deleteMACFilterEntry(ctx context.Context, index, id int) error {
+	_, err := callFeature(ctx, c, FeatureWiFi,
+		endpointDeleteMACFilterEntry(index, id), NoData{})
+	return err
+}
+
+// MACFilterSync are the changes made by SyncMACFilter.
+type MACFilterSync struct {
+	Added   []MACFilterEntry
+	Removed []MACFilterEntry
+	// Renamed entries are added again with the new name before removing the
+	// old ones, since the device cannot update them.
+	Renamed []MACFilterEntry
+}
+
+func (c *client) SyncMACFilter(
+	ctx context.Context,
+	index int,
+	mode MACFilterMode,
+	entries []MACFilterEntry,
+) (*MACFilterSync, error) {
+	if mode != "" {
+		if err := mode.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	entries = slices.Clone(entries)
+	want := make(map[string]bool, len(entries))
+	for i, e := range entries {
+		mac, err := NormalizeMAC(e.MAC)
+		if err != nil {
+			return nil, err
+		}
+		if want[mac] {
+			return nil, fmt.Errorf("duplicate MAC address %s", mac)
+		}
+		entries[i].MAC = mac
+		want[mac] = true
+	}
+
+	current, err := c.MACFilter(ctx, index)
+	if err != nil {
+		return nil, err
+	}
+	if cmp.Or(mode, current.Mode) == MACFilterAllow && len(entries) == 0 {
+		return nil, errors.New("an empty allow list would block all the devices")
+	}
+
+	ret := new(MACFilterSync)
+	for _, e := range entries {
+		i := slices.IndexFunc(current.Entries, func(ce MACFilterEntry) bool {
+			return ce.MAC == e.MAC
+		})
+		switch {
+		case i < 0:
+			ret.Added = append(ret.Added, e)
+		case current.Entries[i].Name != e.Name:
+			ret.Renamed = append(ret.Renamed, e)
+		}
+	}
+	for _, e := range current.Entries {
+		if !want[e.MAC] {
+			ret.Removed = append(ret.Removed, e)
+		}
+	}
+
+	// add before removing, so that with an allow list the devices in both
+	// lists never lose access
+	for _, e := range ret.Added {
+		if err := c.AddMACFilterEntry(ctx, index, e); err != nil {
+			return nil, fmt.Errorf("add %s: %w", e.MAC, err)
+		}
+	}
+	for _, e := range ret.Renamed {
+		if err := c.AddMACFilterEntry(ctx, index, e); err != nil {
+			return nil, fmt.Errorf("rename %s: %w", e.MAC, err)
+		}
+		if err := c.deleteMACFilterEntry(ctx, index, current.ids[e.MAC]); err != nil {
+			return nil, fmt.Errorf("rename %s: %w", e.MAC, err)
+		}
+	}
+	for _, e := range ret.Removed {
+		if err := c.deleteMACFilterEntry(ctx, index, current.ids[e.MAC]); err != nil {
+			return nil, fmt.Errorf("remove %s: %w", e.MAC, err)
+		}
+	}
+	if mode != "" && mode != current.Mode {
+		if err := c.SetMACFilterMode(ctx, index, mode); err != nil {
+			return nil, err
+		}
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseMACFilterList(t *testing.T) {
+	t.Parallel()
+
+	entries, err := ParseMACFilterList(strings.NewReader(`
+# kids
+00-11-22-33-44-55 Tablet de Ana
+001122334466
+00:11:22:33:44:77	  TV	del  salón
+`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(entries) != 3 || entries[0] != (MACFilterEntry{"00:11:22:33:44:55", "Tablet de Ana"}) ||
+		entries[1] != (MACFilterEntry{MAC: "00:11:22:33:44:66"}) ||
+		entries[2] != (MACFilterEntry{"00:11:22:33:44:77", "TV del salón"}) {
+		t.Fatalf("unexpected entries: %#v", entries)
+	}
+
+	for _, list := range []string{
+		"00:11:22:33:44:5",
+		"00:11:22:33:44:55 a\n00-11-22-33-44-55 b",
+	} {
+		if _, err := ParseMACFilterList(strings.NewReader(list)); err == nil {
+			t.Errorf("%q: expected error", list)
+		}
+	}
+}
+
+func TestSyncMACFilter(t *testing.T) {
+	t.Parallel()
+
+	d, c := newFakeDevice(t)
+	var mu sync.Mutex
+	mode := "Deny"
+	tbl := map[int]map[string]string{
+		1: {"MACAddress": "00:11:22:33:44:55", "Name": "old name"},
+		2: {"MACAddress": "00:11:22:33:44:66", "Name": "gone"},
+	}
+	nextID := 3
+	d.handle("GET /api/v1/wifi/1/macfilter", func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		entries := []map[string]any{}
+		for id, e := range tbl {
+			entries = append(entries, map[string]any{"__id": id,
+				"MACAddress": e["MACAddress"], "Name": e["Name"]})
+		}
+		writeData(w, map[string]any{"Mode": mode, "macfilterTbl": entries})
+	})
+	d.handle("POST /api/v1/wifi/1/macfilter", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		mode = r.FormValue("Mode")
+		writeData(w, nil)
+	})
+	d.handle("POST /api/v1/wifi/1/macfilterTbl", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		tbl[nextID] = map[string]string{"MACAddress": r.FormValue("MACAddress"),
+			"Name": r.FormValue("Name")}
+		nextID++
+		writeData(w, nil)
+	})
+	d.handle("DELETE /api/v1/wifi/1/macfilterTbl/{id}", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		delete(tbl, id)
+		writeData(w, nil)
+	})
+	ctx := context.Background()
+
+	res, err := c.SyncMACFilter(ctx, 1, MACFilterAllow, []MACFilterEntry{
+		{MAC: "00-11-22-33-44-55", Name: "new name"},
+		{MAC: "00:11:22:33:44:77", Name: "added"},
+	})
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if len(res.Added) != 1 || len(res.Removed) != 1 || len(res.Renamed) != 1 {
+		t.Fatalf("unexpected changes: %#v", res)
+	}
+
+	f, err := c.MACFilter(ctx, 1)
+	if err != nil {
+		t.Fatalf("get MAC filter: %v", err)
+	}
+	if f.Mode != MACFilterAllow || len(f.Entries) != 2 || !slices.Contains(
+		f.Entries, MACFilterEntry{"00:11:22:33:44:55", "new name"}) {
+		t.Fatalf("unexpected MAC filter: %#v", f)
+	}
+
+	// the mode is already allow, so the list cannot be emptied
+	if _, err := c.SyncMACFilter(ctx, 1, "", nil); err == nil {
+		t.Fatalf("expected error emptying the allow list")
+	}
+	if _, err := c.SyncMACFilter(ctx, 1, "", []MACFilterEntry{
+		{MAC: "00:11:22:33:44:55", Name: "a"},
+		{MAC: "00-11-22-33-44-55", Name: "b"},
+	}); err == nil {
+		t.Fatalf("expected error with duplicate MAC addresses")
+	}
+
+	// an unknown mode is reported as unsupported, not as a cryptic error
+	mode = "Whitelist"
+	if _, err := c.MACFilter(ctx, 1); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("expected unsupported error, got %v", err)
+	}
+}
@@ -0,0 +1,595 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/trace"
+)
+
+// FeatureParental is the access control and parental control.
+const FeatureParental Feature = "parental"
+
+const (
+	// blockedDescription is the description of the rules created by
+	// BlockDevice.
+	blockedDescription = "blocked"
+	// pauseDescriptionPrefix is the prefix of the description of the rules
+	// created by PauseDevice, followed by the end of the pause in RFC 3339.
+	pauseDescriptionPrefix = "paused until "
+	// maxPause is the maximum duration of a pause, since rules repeat weekly.
+	maxPause = 6 * 24 * time.Hour
+)
+
+var (
+	endpointAccessRules = Endpoint[NoData, accessRulesData]{
+		Method: http.MethodGet,
+		Path:   "/api/v1/parental/accessTbl",
+	}
+	endpointAddAccessRule = Endpoint[accessRuleData, addedData]{
+		Method:   http.MethodPost,
+		Path:     "/api/v1/parental/accessTbl",
+		Encoding: EncodingForm,
+	}
+	endpointURLFilters = Endpoint[NoData, urlFiltersData]{
+		Method: http.MethodGet,
+		Path:   "/api/v1/parental/filterTbl",
+	}
+	endpointAddURLFilter = Endpoint[urlFilterData, addedData]{
+		Method:   http.MethodPost,
+		Path:     "/api/v1/parental/filterTbl",
+		Encoding: EncodingForm,
+	}
+)
+
+func endpointUpdateAccessRule(id int) Endpoint[accessRuleData, NoData] {
+	return Endpoint[accessRuleData, NoData]{
+		Method:   http.MethodPost,
+		Path:     "/api/v1/parental/accessTbl/" + strconv.Itoa(id),
+		Encoding: EncodingForm,
+	}
+}
+
+func endpointDeleteAccessRule(id int) Endpoint[NoData, NoData] {
+	return Endpoint[NoData, NoData]{
+		Method: http.MethodDelete,
+		Path:   "/api/v1/parental/accessTbl/" + strconv.Itoa(id),
+	}
+}
+
+func endpointUpdateURLFilter(id int) Endpoint[urlFilterData, NoData] {
+	return Endpoint[urlFilterData, NoData]{
+		Method:   http.MethodPost,
+		Path:     "/api/v1/parental/filterTbl/" + strconv.Itoa(id),
+		Encoding: EncodingForm,
+	}
+}
+
+func endpointDeleteURLFilter(id int) Endpoint[NoData, NoData] {
+	return Endpoint[NoData, NoData]{
+		Method: http.MethodDelete,
+		Path:   "/api/v1/parental/filterTbl/" + strconv.Itoa(id),
+	}
+}
+
+// addedData is the response of the endpoints that add table entries.
+type addedData struct {
+	ID flexInt `json:"__id"`
+}
+
+type accessRulesData struct {
+	AccessTbl []accessRuleData `json:"accessTbl"`
+}
+
+type accessRuleData struct {
+	ID          flexInt    `json:"__id" form:"-"`
+	MACAddress  flexString `json:"MACAddress" form:"MACAddress"`
+	Description flexString `json:"Description" form:"Description"`
+	Enable      flexBool   `json:"Enable" form:"Enable"`
+	AlwaysBlock flexBool   `json:"AlwaysBlock" form:"AlwaysBlock"`
+	Days        flexString `json:"Days" form:"Days"`
+	StartTime   flexString `json:"StartTime" form:"StartTime"`
+	EndTime     flexString `json:"EndTime" form:"EndTime"`
+}
+
+type urlFiltersData struct {
+	FilterTbl []urlFilterData `json:"filterTbl"`
+}
+
+type urlFilterData struct {
+	ID     flexInt    `json:"__id" form:"-"`
+	Type   flexString `json:"Type" form:"Type"`
+	Value  flexString `json:"Value" form:"Value"`
+	Enable flexBool   `json:"Enable" form:"Enable"`
+}
+
+// TimeOfDay is a time of the day in minutes since midnight.
+type TimeOfDay int
+
+// EndOfDay is the last minute of the day.
+const EndOfDay TimeOfDay = 24*60 - 1
+
+// ParseTimeOfDay parses a time of the day in the "15:04" format.
+func ParseTimeOfDay(s string) (TimeOfDay, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	return TimeOfDay(t.Hour()*60 + t.Minute()), nil
+}
+
+func timeOfDayOf(t time.Time) TimeOfDay {
+	return TimeOfDay(t.Hour()*60 + t.Minute())
+}
+
+func (t TimeOfDay) String() string {
+	return fmt.Sprintf("%02d:%02d", int(t)/60, int(t)%60)
+}
+
+var dayNames = [...]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+
+func formatDays(days []time.Weekday) string {
+	var names []string
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if slices.Contains(days, d) {
+			names = append(names, dayNames[d])
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+func parseDays(s string) ([]time.Weekday, error) {
+	var ret []time.Weekday
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		i := slices.IndexFunc(dayNames[:], func(d string) bool {
+			return strings.EqualFold(d, name[:min(len(name), 3)])
+		})
+		if i < 0 {
+			return nil, fmt.Errorf("invalid day %q", name)
+		}
+		ret = append(ret, time.Weekday(i))
+	}
+	return ret, nil
+}
+
+// AccessRule blocks the Internet access of a LAN device, either always or
+// during a time window on some days of the week.
+type AccessRule struct {
+	// ID identifies the rule in the device. It is assigned by the device.
+	ID          int
+	MAC         string
+	Description string
+	Enabled     bool
+	// Always blocks the device at all times. Otherwise, the device is
+	// blocked on the given days from Start to End, both inclusive.
+	Always bool
+	Days   []time.Weekday
+	Start  TimeOfDay
+	End    TimeOfDay
+}
+
+func (r AccessRule) Validate() error {
+	if _, err := NormalizeMAC(r.MAC); err != nil {
+		return err
+	}
+	if r.Always {
+		return nil
+	}
+	if len(r.Days) == 0 {
+		return errors.New("a scheduled rule needs at least one day")
+	}
+	if r.Start < 0 || r.End > EndOfDay || r.Start > r.End {
+		return fmt.Errorf("invalid time window %s-%s", r.Start, r.End)
+	}
+	return nil
+}
+
+// Blocks reports whether the rule is enabled and blocks the device at all
+// times.
+func (r AccessRule) Blocks() bool {
+	return r.Always && r.Enabled
+}
+
+// NewScheduleRules returns the enabled rules that block the device on the
+// given days from start to end. Since the rules of the device cannot cross
+// midnight, a window like 22:00-07:00 needs two rules: one until midnight on
+// the given days, and one from midnight on the following days.
+func NewScheduleRules(
+	mac, desc string,
+	days []time.Weekday,
+	start, end TimeOfDay,
+) ([]AccessRule, error) {
+	r := AccessRule{
+		MAC:         mac,
+		Description: desc,
+		Enabled:     true,
+		Days:        days,
+		Start:       start,
+		End:         end,
+	}
+	if start <= end {
+		return []AccessRule{r}, r.Validate()
+	}
+
+	night, morning := r, r
+	night.End = EndOfDay
+	morning.Start = 0
+	morning.Days = make([]time.Weekday, len(days))
+	for i, d := range days {
+		morning.Days[i] = (d + 1) % 7
+	}
+	if err := errors.Join(night.Validate(), morning.Validate()); err != nil {
+		return nil, err
+	}
+	return []AccessRule{night, morning}, nil
+}
+
+func (r AccessRule) data() (accessRuleData, error) {
+	if err := r.Validate(); err != nil {
+		return accessRuleData{}, err
+	}
+	mac, _ := NormalizeMAC(r.MAC)
+	return accessRuleData{
+		MACAddress:  flexString(mac),
+		Description: flexString(r.Description),
+		Enable:      flexBool(r.Enabled),
+		AlwaysBlock: flexBool(r.Always),
+		Days:        flexString(formatDays(r.Days)),
+		StartTime:   flexString(r.Start.String()),
+		EndTime:     flexString(r.End.String()),
+	}, nil
+}
+
+// paused returns the end of the pause if the rule was created by
+// PauseDevice.
+func (r AccessRule) paused() (time.Time, bool) {
+	s, ok := strings.CutPrefix(r.Description, pauseDescriptionPrefix)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	return t, err == nil
+}
+
+// pauseEnded reports whether the rule was created by PauseDevice and the
+// pause already ended.
+func (r AccessRule) pauseEnded(now time.Time) bool {
+	until, paused := r.paused()
+	return paused && !until.After(now)
+}
+
+// FilterType is the type of a URLFilter.
+type FilterType string
+
+const (
+	FilterKeyword FilterType = "keyword"
+	FilterURL     FilterType = "url"
+)
+
+// URLFilter blocks the web sites whose URL contains a keyword, or a specific
+// URL, for all the LAN devices.
+type URLFilter struct {
+	// ID identifies the filter in the device. It is assigned by the device.
+	ID      int
+	Type    FilterType
+	Value   string
+	Enabled bool
+}
+
+func (f URLFilter) Validate() error {
+	if f.Type != FilterKeyword && f.Type != FilterURL {
+		return fmt.Errorf("invalid filter type %q", f.Type)
+	}
+	if strings.TrimSpace(f.Value) == "" {
+		return errors.New("empty filter value")
+	}
+	return nil
+}
+
+func (f URLFilter) data() (urlFilterData, error) {
+	if err := f.Validate(); err != nil {
+		return urlFilterData{}, err
+	}
+	return urlFilterData{
+		Type:   flexString(f.Type),
+		Value:  flexString(f.Value),
+		Enable: flexBool(f.Enabled),
+	}, nil
+}
+
+func (c *client) AccessRules(ctx context.Context) ([]AccessRule, error) {
+	rules, err := c.allAccessRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return slices.DeleteFunc(rules, func(r AccessRule) bool {
+		return r.pauseEnded(now)
+	}), nil
+}
+
+// allAccessRules returns the access rules, including the ones of the pauses
+// that ended.
+func (c *client) allAccessRules(ctx context.Context) ([]AccessRule, error) {
+	res, err := callFeature(ctx, c, FeatureParental, endpointAccessRules,
+		NoData{})
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]AccessRule, 0, len(res.Data.AccessTbl))
+	for _, d := range res.Data.AccessTbl {
+		r := AccessRule{
+			ID:          int(d.ID),
+			MAC:         string(d.MACAddress),
+			Description: string(d.Description),
+			Enabled:     bool(d.Enable),
+			Always:      bool(d.AlwaysBlock),
+		}
+		if mac, err := NormalizeMAC(r.MAC); err == nil {
+			r.MAC = mac
+		}
+		if !r.Always {
+			if r.Days, err = parseDays(string(d.Days)); err != nil {
+				return nil, fmt.Errorf("%w: rule %d: %w",
+					errUnexpectedResponse, r.ID, err)
+			}
+			if r.Start, err = ParseTimeOfDay(string(d.StartTime)); err != nil {
+				return nil, fmt.Errorf("%w: rule %d: %w",
+					errUnexpectedResponse, r.ID, err)
+			}
+			if r.End, err = ParseTimeOfDay(string(d.EndTime)); err != nil {
+				return nil, fmt.Errorf("%w: rule %d: %w",
+					errUnexpectedResponse, r.ID, err)
+			}
+		}
+		ret = append(ret, r)
+	}
+
+	return ret, nil
+}
+
+func (c *client) AddAccessRule(ctx context.Context, r AccessRule) (AccessRule, error) {
+	d, err := r.data()
+	if err != nil {
+		return AccessRule{}, err
+	}
+	res, err := callFeature(ctx, c, FeatureParental, endpointAddAccessRule, d)
+	if err != nil {
+		return AccessRule{}, err
+	}
+	r.ID = int(res.Data.ID)
+	r.MAC = string(d.MACAddress)
+	return r, nil
+}
+
+func (c *client) UpdateAccessRule(ctx context.Context, r AccessRule) error {
+	d, err := r.data()
+	if err != nil {
+		return err
+	}
+	_, err = callFeature(ctx, c, FeatureParental, endpointUpdateAccessRule(r.ID),
+		d)
+	return err
+}
+
+func (c *client) DeleteAccessRule(ctx context.Context, id int) error {
+	_, err := callFeature(ctx, c, FeatureParental, endpointDeleteAccessRule(id),
+		NoData{})
+	return err
+}
+
+func (c *client) URLFilters(ctx context.Context) ([]URLFilter, error) {
+	res, err := callFeature(ctx, c, FeatureParental, endpointURLFilters,
+		NoData{})
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]URLFilter, 0, len(res.Data.FilterTbl))
+	for _, d := range res.Data.FilterTbl {
+		ret = append(ret, URLFilter{
+			ID:      int(d.ID),
+			Type:    FilterType(strings.ToLower(string(d.Type))),
+			Value:   string(d.Value),
+			Enabled: bool(d.Enable),
+		})
+	}
+
+	return ret, nil
+}
+
+func (c *client) AddURLFilter(ctx context.Context, f URLFilter) (URLFilter, error) {
+	d, err := f.data()
+	if err != nil {
+		return URLFilter{}, err
+	}
+	res, err := callFeature(ctx, c, FeatureParental, endpointAddURLFilter, d)
+	if err != nil {
+		return URLFilter{}, err
+	}
+	f.ID = int(res.Data.ID)
+	return f, nil
+}
+
+func (c *client) UpdateURLFilter(ctx context.Context, f URLFilter) error {
+	d, err := f.data()
+	if err != nil {
+		return err
+	}
+	_, err = callFeature(ctx, c, FeatureParental, endpointUpdateURLFilter(f.ID),
+		d)
+	return err
+}
+
+func (c *client) DeleteURLFilter(ctx context.Context, id int) error {
+	_, err := callFeature(ctx, c, FeatureParental, endpointDeleteURLFilter(id),
+		NoData{})
+	return err
+}
+
+// deleteAccessRules deletes the rules of the MAC address that match.
+func (c *client) deleteAccessRules(
+	ctx context.Context,
+	mac string,
+	match func(AccessRule) bool,
+) error {
+	rules, err := c.allAccessRules(ctx)
+	if err != nil {
+		return err
+	}
+	for _, r := range rules {
+		if (mac == "" || r.MAC == mac) && match(r) {
+			if err := c.DeleteAccessRule(ctx, r.ID); err != nil {
+				return fmt.Errorf("delete rule %d: %w", r.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *client) BlockDevice(ctx context.Context, mac string, block bool) (err error) {
+	ctx, span := c.startSpan(ctx, "client.BlockDevice",
+		trace.String("mac", mac), trace.Bool("block", block))
+	defer func() { trace.End(span, err) }()
+
+	mac, err = NormalizeMAC(mac)
+	if err != nil {
+		return err
+	}
+
+	// the pauses that ended are removed too, since they would apply again
+	now := time.Now()
+	if !block {
+		return c.deleteAccessRules(ctx, mac, func(r AccessRule) bool {
+			return r.Blocks() || r.pauseEnded(now)
+		})
+	}
+	if err := c.deleteAccessRules(ctx, mac, func(r AccessRule) bool {
+		return r.pauseEnded(now)
+	}); err != nil {
+		return err
+	}
+
+	rules, err := c.AccessRules(ctx)
+	if err != nil {
+		return err
+	}
+	if slices.ContainsFunc(rules, func(r AccessRule) bool {
+		return r.MAC == mac && r.Blocks()
+	}) {
+		return nil
+	}
+	_, err = c.AddAccessRule(ctx, AccessRule{
+		MAC:         mac,
+		Description: blockedDescription,
+		Enabled:     true,
+		Always:      true,
+	})
+	return err
+}
+
+// pauseRules returns the scheduled rules that block a device from now until
+// the given time. Since rules are weekly time windows, a pause that spans
+// several days needs one rule for the first day, one for the days in between,
+// and one for the last day. The rules are in the time zone of now.
+func pauseRules(mac string, now, until time.Time) []AccessRule {
+	until = until.In(now.Location())
+	desc := pauseDescriptionPrefix + until.Format(time.RFC3339)
+	rule := func(start, end TimeOfDay, days ...time.Weekday) AccessRule {
+		return AccessRule{
+			MAC:         mac,
+			Description: desc,
+			Enabled:     true,
+			Days:        days,
+			Start:       start,
+			End:         end,
+		}
+	}
+
+	y, m, d := now.Date()
+	uy, um, ud := until.Date()
+	if y == uy && m == um && d == ud {
+		return []AccessRule{rule(timeOfDayOf(now), timeOfDayOf(until),
+			now.Weekday())}
+	}
+
+	rules := []AccessRule{rule(timeOfDayOf(now), EndOfDay, now.Weekday())}
+	var middle []time.Weekday
+	first := time.Date(y, m, d+1, 0, 0, 0, 0, now.Location())
+	last := time.Date(uy, um, ud, 0, 0, 0, 0, now.Location())
+	for day := first; day.Before(last); day = day.AddDate(0, 0, 1) {
+		middle = append(middle, day.Weekday())
+	}
+	if len(middle) > 0 {
+		rules = append(rules, rule(0, EndOfDay, middle...))
+	}
+	if end := timeOfDayOf(until); end > 0 {
+		rules = append(rules, rule(0, end, until.Weekday()))
+	}
+	return rules
+}
+
+func (c *client) PauseDevice(ctx context.Context, mac string, until time.Time) (err error) {
+	ctx, span := c.startSpan(ctx, "client.PauseDevice",
+		trace.String("mac", mac))
+	defer func() { trace.End(span, err) }()
+
+	mac, err = NormalizeMAC(mac)
+	if err != nil {
+		return err
+	}
+	if until.IsZero() {
+		return c.BlockDevice(ctx, mac, true)
+	}
+
+	// the rules apply in the time zone of the device
+	now := time.Now().In(c.Location)
+	switch {
+	case !until.After(now):
+		return fmt.Errorf("pause end %s is in the past", until.Format(time.RFC3339))
+	case until.Sub(now) > maxPause:
+		return fmt.Errorf("pauses cannot be longer than %s; block the device "+
+			"instead", maxPause)
+	}
+
+	// replace any previous pause
+	if err := c.ResumeDevice(ctx, mac); err != nil {
+		return err
+	}
+	for _, r := range pauseRules(mac, now, until) {
+		if _, err := c.AddAccessRule(ctx, r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *client) ResumeDevice(ctx context.Context, mac string) error {
+	mac, err := NormalizeMAC(mac)
+	if err != nil {
+		return err
+	}
+	return c.deleteAccessRules(ctx, mac, func(r AccessRule) bool {
+		_, paused := r.paused()
+		return paused || r.Blocks()
+	})
+}
+
+func (c *client) ExpirePauses(ctx context.Context) error {
+	now := time.Now()
+	return c.deleteAccessRules(ctx, "", func(r AccessRule) bool {
+		return r.pauseEnded(now)
+	})
+}
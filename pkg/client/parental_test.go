@@ -0,0 +1,242 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPauseRules(t *testing.T) {
+	t.Parallel()
+
+	// 2024-01-05 is a Friday
+	now := time.Date(2024, 1, 5, 21, 30, 0, 0, time.UTC)
+	testCases := []struct {
+		until    time.Time
+		expected []string
+	}{
+		{
+			time.Date(2024, 1, 5, 23, 0, 0, 0, time.UTC),
+			[]string{"[Friday] 21:30-23:00"},
+		},
+		{
+			time.Date(2024, 1, 6, 8, 0, 0, 0, time.UTC),
+			[]string{"[Friday] 21:30-23:59", "[Saturday] 00:00-08:00"},
+		},
+		{
+			time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC),
+			[]string{"[Friday] 21:30-23:59", "[Saturday Sunday] 00:00-23:59"},
+		},
+		{
+			// in the time zone of the device, it ends on Saturday at 01:00
+			time.Date(2024, 1, 5, 20, 0, 0, 0, time.FixedZone("EST", -5*3600)),
+			[]string{"[Friday] 21:30-23:59", "[Saturday] 00:00-01:00"},
+		},
+	}
+
+	for _, tc := range testCases {
+		var got []string
+		for _, r := range pauseRules("00:11:22:33:44:55", now, tc.until) {
+			if err := r.Validate(); err != nil {
+				t.Errorf("until %v: invalid rule: %v", tc.until, err)
+			}
+			if u, ok := r.paused(); !ok || !u.Equal(tc.until) {
+				t.Errorf("until %v: unexpected description %q", tc.until,
+					r.Description)
+			}
+			got = append(got, fmtRule(r))
+		}
+		if !slices.Equal(got, tc.expected) {
+			t.Errorf("until %v: expected %v, got %v", tc.until, tc.expected, got)
+		}
+	}
+}
+
+func fmtRule(r AccessRule) string {
+	days := make([]string, len(r.Days))
+	for i, d := range r.Days {
+		days[i] = d.String()
+	}
+	return "[" + strings.Join(days, " ") + "] " + r.Start.String() + "-" + r.End.String()
+}
+
+func TestBlockAndPauseDevice(t *testing.T) {
+	t.Parallel()
+
+	d, c := newFakeDevice(t)
+	var mu sync.Mutex
+	rules := map[string]map[string]string{}
+	nextID := 1
+	d.handle("GET /api/v1/parental/accessTbl", func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		tbl := []map[string]string{}
+		for _, r := range rules {
+			tbl = append(tbl, r)
+		}
+		writeData(w, map[string]any{"accessTbl": tbl})
+	})
+	d.handle("POST /api/v1/parental/accessTbl", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		defer mu.Unlock()
+		id := strconv.Itoa(nextID)
+		nextID++
+		rule := map[string]string{"__id": id}
+		for k := range r.PostForm {
+			rule[k] = r.PostForm.Get(k)
+		}
+		rules[id] = rule
+		writeData(w, map[string]any{"__id": id})
+	})
+	d.handle("DELETE /api/v1/parental/accessTbl/{id}", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(rules, r.PathValue("id"))
+		writeData(w, nil)
+	})
+	ctx := context.Background()
+	const mac = "00-11-22-33-44-55"
+
+	for range 2 { // blocking twice adds a single rule
+		if err := c.BlockDevice(ctx, mac, true); err != nil {
+			t.Fatalf("block: %v", err)
+		}
+	}
+	got, err := c.AccessRules(ctx)
+	if err != nil {
+		t.Fatalf("get rules: %v", err)
+	}
+	if len(got) != 1 || !got[0].Always || got[0].MAC != "00:11:22:33:44:55" {
+		t.Fatalf("unexpected rules: %#v", got)
+	}
+
+	// pausing replaces the block
+	if err := c.PauseDevice(ctx, mac, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	got, _ = c.AccessRules(ctx)
+	if len(got) == 0 || slices.ContainsFunc(got, func(r AccessRule) bool {
+		return r.Always
+	}) {
+		t.Fatalf("unexpected rules after pause: %#v", got)
+	}
+
+	if err := c.PauseDevice(ctx, mac, time.Now().Add(-time.Hour)); err == nil {
+		t.Fatalf("expected error pausing until the past")
+	}
+
+	if err := c.ResumeDevice(ctx, mac); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if got, _ = c.AccessRules(ctx); len(got) != 0 {
+		t.Fatalf("expected no rules after resume, got %#v", got)
+	}
+
+	// unblocking removes any rule that always blocks the device
+	if _, err := c.AddAccessRule(ctx, AccessRule{MAC: mac, Description: "mine",
+		Enabled: true, Always: true}); err != nil {
+		t.Fatalf("add rule: %v", err)
+	}
+	if err := c.BlockDevice(ctx, mac, false); err != nil {
+		t.Fatalf("unblock: %v", err)
+	}
+	if got, _ = c.AccessRules(ctx); len(got) != 0 {
+		t.Fatalf("expected no rules after unblock, got %#v", got)
+	}
+
+	// the rules of a pause that ended are left out when read, and only
+	// removed by ExpirePauses
+	mu.Lock()
+	rules["99"] = map[string]string{
+		"__id":        "99",
+		"MACAddress":  "00:11:22:33:44:55",
+		"Description": pauseDescriptionPrefix + "2024-01-05T23:00:00Z",
+		"Enable":      "true",
+		"AlwaysBlock": "false",
+		"Days":        "Fri",
+		"StartTime":   "21:30",
+		"EndTime":     "23:00",
+	}
+	mu.Unlock()
+	if got, err = c.AccessRules(ctx); err != nil || len(got) != 0 {
+		t.Fatalf("expected no rules, got %#v, %v", got, err)
+	}
+	mu.Lock()
+	n := len(rules)
+	mu.Unlock()
+	if n != 1 {
+		t.Fatalf("expected the expired pause to be kept by a read")
+	}
+	if err := c.ExpirePauses(ctx); err != nil {
+		t.Fatalf("expire pauses: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(rules) != 0 {
+		t.Fatalf("expired pause not deleted: %v", rules)
+	}
+}
+
+func TestNewScheduleRules(t *testing.T) {
+	t.Parallel()
+
+	const mac = "00:11:22:33:44:55"
+	rules, err := NewScheduleRules(mac, "school", []time.Weekday{time.Monday},
+		8*60, 14*60)
+	if err != nil || len(rules) != 1 || fmtRule(rules[0]) != "[Monday] 08:00-14:00" {
+		t.Fatalf("unexpected rules: %#v, %v", rules, err)
+	}
+
+	rules, err = NewScheduleRules(mac, "night",
+		[]time.Weekday{time.Friday, time.Saturday}, 22*60, 7*60)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, r := range rules {
+		got = append(got, fmtRule(r))
+	}
+	expected := []string{"[Friday Saturday] 22:00-23:59",
+		"[Saturday Sunday] 00:00-07:00"}
+	if !slices.Equal(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+
+	if _, err := NewScheduleRules(mac, "", nil, 22*60, 7*60); err == nil {
+		t.Fatalf("expected error without days")
+	}
+}
+
+func TestUpdateURLFilter(t *testing.T) {
+	t.Parallel()
+
+	d, c := newFakeDevice(t)
+	var form url.Values
+	d.handle("POST /api/v1/parental/filterTbl/{id}", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm
+		form.Set("id", r.PathValue("id"))
+		writeData(w, nil)
+	})
+	ctx := context.Background()
+
+	f := URLFilter{ID: 3, Type: FilterKeyword, Value: "casino"}
+	if err := c.UpdateURLFilter(ctx, f); err != nil {
+		t.Fatalf("update filter: %v", err)
+	}
+	if form.Get("id") != "3" || form.Get("Enable") != "false" ||
+		form.Get("Value") != "casino" {
+		t.Fatalf("unexpected request: %v", form)
+	}
+	f.Type = "regexp"
+	if err := c.UpdateURLFilter(ctx, f); err == nil {
+		t.Fatalf("expected error for an invalid type")
+	}
+}
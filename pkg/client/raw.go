@@ -0,0 +1,66 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/httpdoer"
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/trace"
+)
+
+func (c *client) Raw(
+	ctx context.Context,
+	method string,
+	path string,
+	form url.Values,
+) (*Response[json.RawMessage], error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("path must start with a slash: %q", path)
+	}
+	e := Endpoint[url.Values, json.RawMessage]{
+		Method:   strings.ToUpper(method),
+		Path:     path,
+		Encoding: EncodingForm,
+	}
+	return callAuthed(ctx, c, e, form)
+}
+
+func (c *client) Fetch(ctx context.Context, path string) (_ []byte, err error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("path must start with a slash: %q", path)
+	}
+	if err := c.ensureSession(ctx); err != nil {
+		return nil, err
+	}
+
+	ctx, span := c.startSpan(ctx, "client.Fetch", trace.String("path", path))
+	defer func() { trace.End(span, err) }()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		c.CurrentBaseURL()+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	res, err := c.HTTPDoer.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("perform request: %w", err)
+	}
+	b, err := httpdoer.ReadAndCloseBody(res)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, &APIError{
+			Method:     http.MethodGet,
+			Path:       path,
+			StatusCode: res.StatusCode,
+			Raw:        b,
+		}
+	}
+
+	return b, nil
+}
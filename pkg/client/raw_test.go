@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestRaw(t *testing.T) {
+	t.Parallel()
+
+	d, c := newFakeDevice(t)
+	d.handle("GET /api/v1/thing", func(w http.ResponseWriter, r *http.Request) {
+		writeData(w, map[string]string{"q": r.URL.Query().Get("q")})
+	})
+	ctx := context.Background()
+
+	// logs in automatically
+	res, err := c.Raw(ctx, "get", "/api/v1/thing", url.Values{"q": {"x"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(res.Data) != `{"q":"x"}` {
+		t.Fatalf("unexpected data: %s", res.Data)
+	}
+	if n := d.loginCount(); n != 1 {
+		t.Fatalf("expected 1 login, got %d", n)
+	}
+
+	// reuses the session
+	if _, err := c.Raw(ctx, http.MethodGet, "/api/v1/thing", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := d.loginCount(); n != 1 {
+		t.Fatalf("expected 1 login, got %d", n)
+	}
+
+	// logs in again if the session expired
+	d.expire()
+	if _, err := c.Raw(ctx, http.MethodGet, "/api/v1/thing", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := d.loginCount(); n != 2 {
+		t.Fatalf("expected 2 logins, got %d", n)
+	}
+
+	if _, err := c.Raw(ctx, http.MethodGet, "api/v1/thing", nil); err == nil {
+		t.Fatalf("expected error for relative path")
+	}
+}
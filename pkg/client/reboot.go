@@ -0,0 +1,36 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/trace"
+)
+
+// FeatureReboot is restarting the device.
+const FeatureReboot Feature = "reboot"
+
+var endpointRestart = Endpoint[restartRequest, NoData]{
+	Method:   http.MethodPost,
+	Path:     "/api/v1/sta_restart",
+	Encoding: EncodingForm,
+}
+
+type restartRequest struct {
+	Restart string `form:"restart"`
+}
+
+func (c *client) Reboot(ctx context.Context) (err error) {
+	ctx, span := c.startSpan(ctx, "client.Reboot")
+	defer func() { trace.End(span, err) }()
+
+	_, err = callFeature(ctx, c, FeatureReboot, endpointRestart, restartRequest{
+		Restart: "Router",
+	})
+	if err != nil {
+		return err
+	}
+
+	c.forgetSession()
+	return nil
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// isSessionError reports whether err means the session is not valid anymore.
+func isSessionError(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) &&
+		(apiErr.StatusCode == http.StatusUnauthorized ||
+			apiErr.StatusCode == http.StatusForbidden)
+}
+
+// ensureSession logs in if there is no session.
+func (c *client) ensureSession(ctx context.Context) error {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+
+	res, err := c.loadLoginResponse()
+	if err != nil || res != nil {
+		return err
+	}
+	return c.Login(ctx)
+}
+
+// relogin discards the current session, unless another call already replaced
+// it, and logs in again.
+func (c *client) relogin(ctx context.Context, stale *loginResponse) error {
+	c.sessionMu.Lock()
+	defer c.sessionMu.Unlock()
+
+	res, err := c.loadLoginResponse()
+	if err != nil {
+		return err
+	}
+	if res != nil && res != stale {
+		return nil
+	}
+	return c.Login(ctx)
+}
+
+// callAuthed is like call, but logs in first if there is no session, and logs
+// in again and retries once if the session expired.
+func callAuthed[Req, Res any](
+	ctx context.Context,
+	c *client,
+	e Endpoint[Req, Res],
+	req Req,
+) (*Response[Res], error) {
+	if err := c.ensureSession(ctx); err != nil {
+		return nil, err
+	}
+	session, err := c.loadLoginResponse()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := call(ctx, c, e, req)
+	if !isSessionError(err) {
+		return res, err
+	}
+
+	if err := c.relogin(ctx, session); err != nil {
+		return nil, err
+	}
+	return call(ctx, c, e, req)
+}
@@ -0,0 +1,256 @@
+package client
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// FeatureSiteSurvey is scanning the neighboring Wi-Fi networks.
+const FeatureSiteSurvey Feature = "site_survey"
+
+var endpointsSiteSurvey = newDiagEndpoints[surveyRequest, neighborData](
+	FeatureSiteSurvey, "/api/v1/wifi/survey")
+
+type surveyRequest struct {
+	Band string `form:"Band"`
+}
+
+type neighborData struct {
+	SSID      flexString `json:"SSID"`
+	BSSID     flexString `json:"BSSID"`
+	Channel   flexInt    `json:"Channel"`
+	Bandwidth flexInt    `json:"Bandwidth"` // MHz
+	Signal    flexInt    `json:"SignalStrength"`
+	Security  flexString `json:"SecurityMode"`
+}
+
+// Neighbor is a Wi-Fi network found by a site survey.
+type Neighbor struct {
+	// SSID is empty for hidden networks.
+	SSID  string
+	BSSID string
+	Band  string
+	// Channel is the primary channel, and Width is the channel width in MHz.
+	Channel int
+	Width   int
+	// Signal is the signal strength in dBm.
+	Signal   int
+	Security string
+}
+
+// ParseBand returns Band24GHz or Band5GHz for the usual ways of writing them,
+// like "2.4" or "5ghz".
+func ParseBand(s string) (string, error) {
+	switch strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "ghz") {
+	case "2.4", "2,4", "2":
+		return Band24GHz, nil
+	case "5":
+		return Band5GHz, nil
+	}
+	return "", fmt.Errorf("unknown Wi-Fi band %q", s)
+}
+
+func (c *client) SiteSurvey(ctx context.Context, band string) ([]Neighbor, error) {
+	band, err := ParseBand(band)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []Neighbor
+	for n, err := range runDiagnostic(ctx, c, "site survey",
+		endpointsSiteSurvey, surveyRequest{Band: band}, nil,
+		func(d neighborData) (Neighbor, error) {
+			n := Neighbor{
+				SSID:     string(d.SSID),
+				BSSID:    string(d.BSSID),
+				Band:     band,
+				Channel:  int(d.Channel),
+				Width:    cmp.Or(int(d.Bandwidth), 20),
+				Signal:   int(d.Signal),
+				Security: string(d.Security),
+			}
+			if mac, err := NormalizeMAC(n.BSSID); err == nil {
+				n.BSSID = mac
+			}
+			if n.Channel <= 0 {
+				return n, fmt.Errorf("invalid channel %d of %s", n.Channel,
+					n.BSSID)
+			}
+			return n, nil
+		}) {
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, n)
+	}
+	return ret, nil
+}
+
+// ChannelParams configures RecommendChannels.
+type ChannelParams struct {
+	// Band is Band24GHz or Band5GHz.
+	Band string
+	// Width is the channel width of the device in MHz. Defaults to 20 for
+	// 2.4GHz and 80 for 5GHz.
+	Width int
+	// DFS allows the 5GHz channels that require radar detection, which are
+	// better when free but may force the device to switch channels.
+	DFS bool
+	// Exclude are the BSSIDs to ignore, usually the ones of the device.
+	Exclude []string
+}
+
+func (p ChannelParams) WithDefaults() ChannelParams {
+	if p.Width <= 0 {
+		p.Width = 20
+		if p.Band == Band5GHz {
+			p.Width = 80
+		}
+	}
+	return p
+}
+
+// ChannelScore is the interference estimated for a channel.
+type ChannelScore struct {
+	Channel int
+	// Interference is the sum of the power in mW of the neighboring networks
+	// that overlap the channel, weighted by the fraction of the channel they
+	// overlap. Lower is better.
+	Interference float64
+	// Networks is the number of neighboring networks that overlap the
+	// channel.
+	Networks int
+}
+
+// Candidate channels. In 2.4GHz, only the non-overlapping channels are
+// recommended.
+var (
+	channels24GHz   = []int{1, 6, 11}
+	channels5GHz    = []int{36, 40, 44, 48, 149, 153, 157, 161, 165}
+	channels5GHzDFS = []int{52, 56, 60, 64, 100, 104, 108, 112, 116, 120, 124, 128, 132, 136, 140, 144}
+)
+
+// channelRange is a range of contiguous 5GHz channels, where the bonded
+// channels are aligned to the first one.
+type channelRange struct{ first, last int }
+
+var ranges5GHz = []channelRange{{36, 64}, {100, 144}, {149, 165}}
+
+// RecommendChannels scores the candidate channels of the band by the
+// interference of the neighbors, and returns them from best to worst.
+func RecommendChannels(neighbors []Neighbor, p ChannelParams) ([]ChannelScore, error) {
+	band, err := ParseBand(p.Band)
+	if err != nil {
+		return nil, err
+	}
+	p.Band = band
+	p = p.WithDefaults()
+	if p.Width != 20 && p.Width != 40 && p.Width != 80 && p.Width != 160 {
+		return nil, fmt.Errorf("invalid channel width %d", p.Width)
+	}
+
+	candidates := channels24GHz
+	if band == Band5GHz {
+		candidates = channels5GHz
+		if p.DFS {
+			candidates = slices.Concat(channels5GHz, channels5GHzDFS)
+		}
+	}
+	exclude := make(map[string]bool, len(p.Exclude))
+	for _, b := range p.Exclude {
+		if mac, err := NormalizeMAC(b); err == nil {
+			exclude[mac] = true
+		}
+	}
+
+	var ret []ChannelScore
+	for _, ch := range candidates {
+		lo, hi := channelSpan(band, ch, p.Width)
+		if lo == 0 {
+			continue // the channel doesn't support the width
+		}
+		s := ChannelScore{Channel: ch}
+		for _, n := range neighbors {
+			if n.Band != band || exclude[n.BSSID] {
+				continue
+			}
+			nlo, nhi := channelSpan(band, n.Channel, n.Width)
+			if nlo == 0 {
+				nlo, nhi = channelSpan(band, n.Channel, 20)
+			}
+			overlap := min(hi, nhi) - max(lo, nlo)
+			if overlap <= 0 {
+				continue
+			}
+			s.Networks++
+			s.Interference += float64(overlap) / float64(hi-lo) *
+				dBmToMW(n.Signal)
+		}
+		ret = append(ret, s)
+	}
+
+	slices.SortStableFunc(ret, func(a, b ChannelScore) int {
+		return cmp.Or(
+			cmp.Compare(a.Interference, b.Interference),
+			cmp.Compare(a.Networks, b.Networks),
+		)
+	})
+	return ret, nil
+}
+
+// dBmToMW converts a power in dBm to mW.
+func dBmToMW(dBm int) float64 {
+	return math.Pow(10, float64(dBm)/10)
+}
+
+// channelSpan returns the range of frequencies in MHz used by a network with
+// the given primary channel and width, or zeros if the channel doesn't support
+// the width. For 40MHz in 2.4GHz, where the secondary channel is not known,
+// the usual one is assumed: above the primary for channels up to 7, and below
+// for the rest.
+func channelSpan(band string, channel, width int) (lo, hi int) {
+	var center int
+	switch {
+	case band == Band24GHz && channel >= 1 && channel <= 14:
+		center = 2407 + 5*channel
+		if channel == 14 {
+			center = 2484
+		}
+		switch width {
+		case 20:
+		case 40:
+			if channel <= 7 {
+				center += 10
+			} else {
+				center -= 10
+			}
+		default:
+			return 0, 0
+		}
+
+	case band == Band5GHz:
+		// bonded channels are aligned to blocks of width/20 channels, which
+		// are numbered every 4
+		var r channelRange
+		for _, r = range ranges5GHz {
+			if channel <= r.last {
+				break
+			}
+		}
+		k := width / 20
+		block := r.first + (channel-r.first)/(4*k)*4*k
+		if channel < r.first || block+(k-1)*4 > r.last {
+			return 0, 0
+		}
+		center = 5000 + 5*(block+(k-1)*2)
+
+	default:
+		return 0, 0
+	}
+
+	return center - width/2, center + width/2
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestChannelSpan(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		band           string
+		channel, width int
+		lo, hi         int
+	}{
+		{Band24GHz, 1, 20, 2402, 2422},
+		{Band24GHz, 6, 40, 2427, 2467},
+		{Band24GHz, 11, 40, 2432, 2472},
+		{Band24GHz, 6, 80, 0, 0},
+		{Band5GHz, 36, 20, 5170, 5190},
+		{Band5GHz, 44, 80, 5170, 5250},
+		{Band5GHz, 157, 80, 5735, 5815},
+		{Band5GHz, 64, 160, 5170, 5330},
+		{Band5GHz, 165, 80, 0, 0},
+		{Band5GHz, 149, 160, 0, 0},
+		{Band5GHz, 68, 20, 0, 0},
+	} {
+		lo, hi := channelSpan(tc.band, tc.channel, tc.width)
+		if lo != tc.lo || hi != tc.hi {
+			t.Errorf("%s channel %d width %d: got %d-%d, want %d-%d", tc.band,
+				tc.channel, tc.width, lo, hi, tc.lo, tc.hi)
+		}
+	}
+}
+
+func TestRecommendChannels(t *testing.T) {
+	t.Parallel()
+
+	neighbors := []Neighbor{
+		// a strong network on 1 weighs more than two weak ones on 11
+		{BSSID: "00:00:00:00:00:01", Band: Band24GHz, Channel: 1, Width: 20, Signal: -40},
+		{BSSID: "00:00:00:00:00:02", Band: Band24GHz, Channel: 11, Width: 20, Signal: -85},
+		{BSSID: "00:00:00:00:00:03", Band: Band24GHz, Channel: 10, Width: 20, Signal: -80},
+		// 40MHz on 6 also overlaps 11, a bit
+		{BSSID: "00:00:00:00:00:04", Band: Band24GHz, Channel: 6, Width: 40, Signal: -70},
+		// the device itself
+		{BSSID: "00:00:00:00:00:05", Band: Band24GHz, Channel: 6, Width: 20, Signal: -20},
+		{BSSID: "00:00:00:00:00:06", Band: Band5GHz, Channel: 36, Width: 80, Signal: -60},
+	}
+	scores, err := RecommendChannels(neighbors, ChannelParams{
+		Band:    "2.4",
+		Exclude: []string{"00-00-00-00-00-05"},
+	})
+	if err != nil {
+		t.Fatalf("recommend: %v", err)
+	}
+	if len(scores) != 3 || scores[0].Channel != 11 || scores[0].Networks != 3 ||
+		scores[2].Channel != 1 {
+		t.Fatalf("unexpected scores: %#v", scores)
+	}
+
+	// the 80MHz block 36-48 is taken, and the DFS channels are not allowed
+	scores, err = RecommendChannels(neighbors, ChannelParams{Band: Band5GHz})
+	if err != nil {
+		t.Fatalf("recommend: %v", err)
+	}
+	if len(scores) != 8 || scores[0].Channel != 149 || scores[7].Networks != 1 {
+		t.Fatalf("unexpected scores: %#v", scores)
+	}
+
+	if _, err := RecommendChannels(nil, ChannelParams{Band: "6GHz"}); err == nil {
+		t.Fatalf("expected error for an unknown band")
+	}
+}
+
+func TestSiteSurvey(t *testing.T) {
+	t.Parallel()
+
+	d, c := newFakeDevice(t)
+	c.DiagnosticsPollInterval = time.Millisecond
+	var mu sync.Mutex
+	var band string
+	channel, auto := "6", "false"
+	d.handle("POST /api/v1/wifi/survey", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		band = r.FormValue("Band")
+		writeData(w, nil)
+	})
+	d.handle("GET /api/v1/wifi/survey", func(w http.ResponseWriter, _ *http.Request) {
+		writeData(w, map[string]any{"Status": "Complete", "Results": []map[string]any{
+			{"SSID": "Vecino", "BSSID": "AA-BB-CC-DD-EE-FF", "Channel": "11",
+				"Bandwidth": "", "SignalStrength": "-67", "SecurityMode": "WPA2-Personal"},
+		}})
+	})
+	d.handle("POST /api/v1/wifi/1", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		channel, auto = r.FormValue("Channel"), r.FormValue("AutoChannelEnable")
+		writeData(w, nil)
+	})
+	ctx := context.Background()
+
+	neighbors, err := c.SiteSurvey(ctx, "2.4ghz")
+	if err != nil {
+		t.Fatalf("site survey: %v", err)
+	}
+	want := Neighbor{
+		SSID:     "Vecino",
+		BSSID:    "aa:bb:cc:dd:ee:ff",
+		Band:     Band24GHz,
+		Channel:  11,
+		Width:    20,
+		Signal:   -67,
+		Security: "WPA2-Personal",
+	}
+	if band != Band24GHz || len(neighbors) != 1 || neighbors[0] != want {
+		t.Fatalf("unexpected neighbors for band %q: %#v", band, neighbors)
+	}
+
+	if err := c.SetWiFiChannel(ctx, 1, 1); err != nil {
+		t.Fatalf("set channel: %v", err)
+	}
+	if channel != "1" || auto != "false" {
+		t.Fatalf("unexpected settings: channel %q, auto %q", channel, auto)
+	}
+	if err := c.SetWiFiChannel(ctx, 1, 0); err != nil {
+		t.Fatalf("set automatic channel: %v", err)
+	}
+	if channel != "" || auto != "true" {
+		t.Fatalf("unexpected settings: channel %q, auto %q", channel, auto)
+	}
+}
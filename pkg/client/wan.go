@@ -0,0 +1,165 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// FeatureWAN is the WAN and cable modem provisioning status.
+const FeatureWAN Feature = "wan"
+
+var (
+	endpointWANStatus = Endpoint[NoData, wanData]{
+		Method: http.MethodGet,
+		Path: "/api/v1/wan/IPAddress,SubnetMask,Gateway,DNSServers," +
+			"UpTime",
+	}
+	endpointProvisioningStatus = Endpoint[NoData, provisioningData]{
+		Method: http.MethodGet,
+		Path: "/api/v1/modem/CMStatus,RegistrationStatus,RangingStatus," +
+			"BPIEnable,ConfigFile,ToDStatus,CMIPAddress",
+	}
+)
+
+type wanData struct {
+	IPAddress  flexString `json:"IPAddress"`
+	SubnetMask flexString `json:"SubnetMask"`
+	Gateway    flexString `json:"Gateway"`
+	DNSServers flexString `json:"DNSServers"`
+	UpTime     flexInt    `json:"UpTime"` // seconds
+}
+
+func (d *wanData) Validate() error {
+	_, err := d.status()
+	return err
+}
+
+type provisioningData struct {
+	CMStatus           flexString `json:"CMStatus"`
+	RegistrationStatus flexString `json:"RegistrationStatus"`
+	RangingStatus      flexString `json:"RangingStatus"`
+	BPIEnable          flexBool   `json:"BPIEnable"`
+	ConfigFile         flexString `json:"ConfigFile"`
+	ToDStatus          flexString `json:"ToDStatus"`
+	CMIPAddress        flexString `json:"CMIPAddress"`
+}
+
+func (d *provisioningData) Validate() error {
+	_, err := parseOptionalAddr(string(d.CMIPAddress))
+	return err
+}
+
+// WANStatus is the state of the Internet connection of the router.
+type WANStatus struct {
+	// Address is the public IPv4 address and its subnet. It is the zero
+	// value while disconnected.
+	Address    netip.Prefix
+	Gateway    netip.Addr
+	DNSServers []netip.Addr
+	// Uptime is the uptime of the connection at the time the status was
+	// fetched.
+	Uptime    time.Duration
+	FetchedAt time.Time
+}
+
+// Connected reports whether the router has a public address.
+func (s *WANStatus) Connected() bool {
+	return s.Address.IsValid()
+}
+
+// CurrentUptime estimates the current uptime of the connection from the one
+// fetched.
+func (s *WANStatus) CurrentUptime() time.Duration {
+	if !s.Connected() {
+		return 0
+	}
+	return s.Uptime + time.Since(s.FetchedAt)
+}
+
+// ProvisioningStatus is the state of the cable modem in the DOCSIS network of
+// the ISP. The states are reported as shown in the web UI.
+type ProvisioningStatus struct {
+	// State is the operational state of the cable modem, e.g. "OPERATIONAL".
+	State string
+	// Registration and Ranging are the DOCSIS registration and ranging
+	// states, e.g. "Registration Complete" and "Complete".
+	Registration string
+	Ranging      string
+	// BPIEnabled reports whether Baseline Privacy encryption is enabled.
+	BPIEnabled bool
+	// ConfigFile is the name of the configuration file provisioned by the
+	// ISP.
+	ConfigFile string
+	// ToD is the state of the Time of Day retrieval, e.g. "Retrieved".
+	ToD string
+	// CMAddress is the address of the cable modem in the ISP network, if
+	// any.
+	CMAddress netip.Addr
+}
+
+// Operational reports whether the cable modem is operational.
+func (s *ProvisioningStatus) Operational() bool {
+	return strings.EqualFold(s.State, "operational")
+}
+
+func (d *wanData) status() (*WANStatus, error) {
+	s := &WANStatus{
+		Uptime:    time.Duration(d.UpTime) * time.Second,
+		FetchedAt: time.Now(),
+	}
+
+	ip, err := parseOptionalAddr(string(d.IPAddress))
+	if err != nil {
+		return nil, fmt.Errorf("IP address: %w", err)
+	}
+	if ip.IsValid() && !ip.IsUnspecified() {
+		bits := 32
+		if m := net.ParseIP(string(d.SubnetMask)).To4(); m != nil {
+			if ones, size := net.IPMask(m).Size(); size == 32 {
+				bits = ones
+			}
+		}
+		s.Address = netip.PrefixFrom(ip, bits)
+	}
+	if s.Gateway, err = parseOptionalAddr(string(d.Gateway)); err != nil {
+		return nil, fmt.Errorf("gateway: %w", err)
+	}
+	if s.DNSServers, err = parseAddrList(string(d.DNSServers)); err != nil {
+		return nil, fmt.Errorf("DNS servers: %w", err)
+	}
+
+	return s, nil
+}
+
+func (c *client) WANStatus(ctx context.Context) (*WANStatus, error) {
+	res, err := callFeature(ctx, c, FeatureWAN, endpointWANStatus, NoData{})
+	if err != nil {
+		return nil, err
+	}
+	return res.Data.status() // validated
+}
+
+func (c *client) ProvisioningStatus(ctx context.Context) (*ProvisioningStatus, error) {
+	res, err := callFeature(ctx, c, FeatureWAN, endpointProvisioningStatus,
+		NoData{})
+	if err != nil {
+		return nil, err
+	}
+
+	d := res.Data
+	cmAddr, _ := parseOptionalAddr(string(d.CMIPAddress)) // validated
+	return &ProvisioningStatus{
+		State:        string(d.CMStatus),
+		Registration: string(d.RegistrationStatus),
+		Ranging:      string(d.RangingStatus),
+		BPIEnabled:   bool(d.BPIEnable),
+		ConfigFile:   string(d.ConfigFile),
+		ToD:          string(d.ToDStatus),
+		CMAddress:    cmAddr,
+	}, nil
+}
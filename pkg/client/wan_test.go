@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWANStatus(t *testing.T) {
+	t.Parallel()
+
+	d, c := newFakeDevice(t)
+	var mu sync.Mutex
+	wan := map[string]any{
+		"IPAddress":  "203.0.113.7",
+		"SubnetMask": "255.255.252.0",
+		"Gateway":    "203.0.112.1",
+		"DNSServers": "200.42.4.207,200.42.0.108",
+		"UpTime":     "3600",
+	}
+	d.handle("GET /api/v1/wan/{fields}", func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		writeData(w, wan)
+	})
+	d.handle("GET /api/v1/modem/{fields}", func(w http.ResponseWriter, _ *http.Request) {
+		writeData(w, map[string]any{
+			"CMStatus":           "OPERATIONAL",
+			"RegistrationStatus": "Registration Complete",
+			"RangingStatus":      "Complete",
+			"BPIEnable":          "1",
+			"ConfigFile":         "gold.cfg",
+			"ToDStatus":          "Retrieved",
+			"CMIPAddress":        "10.20.30.40",
+		})
+	})
+	ctx := context.Background()
+
+	st, err := c.WANStatus(ctx)
+	if err != nil {
+		t.Fatalf("get WAN status: %v", err)
+	}
+	if !st.Connected() || st.Address != netip.MustParsePrefix("203.0.113.7/22") ||
+		st.Gateway != netip.MustParseAddr("203.0.112.1") || len(st.DNSServers) != 2 ||
+		st.CurrentUptime() < time.Hour {
+		t.Fatalf("unexpected WAN status: %#v", st)
+	}
+
+	mu.Lock()
+	wan["IPAddress"], wan["Gateway"], wan["DNSServers"] = "0.0.0.0", "", ""
+	mu.Unlock()
+	if st, err := c.WANStatus(ctx); err != nil || st.Connected() ||
+		st.CurrentUptime() != 0 {
+		t.Fatalf("expected disconnected status: %#v, %v", st, err)
+	}
+
+	prov, err := c.ProvisioningStatus(ctx)
+	if err != nil {
+		t.Fatalf("get provisioning status: %v", err)
+	}
+	if !prov.Operational() || !prov.BPIEnabled || prov.ConfigFile != "gold.cfg" ||
+		prov.CMAddress != netip.MustParseAddr("10.20.30.40") {
+		t.Fatalf("unexpected provisioning status: %#v", prov)
+	}
+}
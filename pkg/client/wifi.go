@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// FeatureWiFi is the Wi-Fi status and configuration.
+const FeatureWiFi Feature = "wifi"
+
+// Wi-Fi bands, as reported in WiFiNetwork.Band.
+const (
+	Band24GHz = "2.4GHz"
+	Band5GHz  = "5GHz"
+)
+
+var endpointWiFi = Endpoint[NoData, wifiData]{
+	Method: http.MethodGet,
+	Path:   "/api/v1/wifi/ssidTbl",
+}
+
+// endpointSetSSID returns the endpoint to change the settings of the Wi-Fi
+// network with the given index.
+func endpointSetSSID(index int) Endpoint[ssidSettings, NoData] {
+	return Endpoint[ssidSettings, NoData]{
+		Method:   http.MethodPost,
+		Path:     "/api/v1/wifi/" + strconv.Itoa(index),
+		Encoding: EncodingForm,
+	}
+}
+
+// ssidSettings are the settings of a Wi-Fi network. Nil fields are not
+// changed.
+type ssidSettings struct {
+	SSIDEnable      *bool   `form:"SSIDEnable"`
+	SSID            *string `form:"SSID"`
+	KeyPassphrase   *string `form:"KeyPassphrase"`
+	IsolationEnable *bool   `form:"IsolationEnable"`
+
+	// The channel is shared by all the networks of the same band.
+	Channel           *int  `form:"Channel"`
+	AutoChannelEnable *bool `form:"AutoChannelEnable"`
+}
+
+type wifiData struct {
+	SSIDTbl []ssidData `json:"ssidTbl"`
+}
+
+type ssidData struct {
+	ID                flexInt    `json:"__id"`
+	SSIDEnable        flexBool   `json:"SSIDEnable"`
+	SSID              flexString `json:"SSID"`
+	BSSID             flexString `json:"BSSID"`
+	Band              flexString `json:"OperatingFrequencyBand"`
+	Channel           flexInt    `json:"Channel"`
+	AutoChannelEnable flexBool   `json:"AutoChannelEnable"`
+	ModeEnabled       flexString `json:"ModeEnabled"`
+	Guest             flexBool   `json:"GuestNetwork"`
+	KeyPassphrase     flexString `json:"KeyPassphrase"`
+	IsolationEnable   flexBool   `json:"IsolationEnable"`
+}
+
+// WiFiNetwork is a Wi-Fi network (SSID) of the device.
+type WiFiNetwork struct {
+	// Index identifies the network in the device API.
+	Index   int    `json:"index"`
+	Enabled bool   `json:"enabled"`
+	SSID    string `json:"ssid"`
+	BSSID   string `json:"bssid,omitempty"`
+	// Band is Band24GHz or Band5GHz.
+	Band        string `json:"band"`
+	Channel     int    `json:"channel"`
+	AutoChannel bool   `json:"autoChannel"`
+	// Security is the security mode, e.g. "WPA2-Personal".
+	Security string `json:"security"`
+	Guest    bool   `json:"guest"`
+}
+
+func (c *client) WiFi(ctx context.Context) ([]WiFiNetwork, error) {
+	res, err := callFeature(ctx, c, FeatureWiFi, endpointWiFi, NoData{})
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]WiFiNetwork, 0, len(res.Data.SSIDTbl))
+	for _, s := range res.Data.SSIDTbl {
+		ret = append(ret, WiFiNetwork{
+			Index:       int(s.ID),
+			Enabled:     bool(s.SSIDEnable),
+			SSID:        string(s.SSID),
+			BSSID:       string(s.BSSID),
+			Band:        string(s.Band),
+			Channel:     int(s.Channel),
+			AutoChannel: bool(s.AutoChannelEnable),
+			Security:    string(s.ModeEnabled),
+			Guest:       bool(s.Guest),
+		})
+	}
+
+	return ret, nil
+}
+
+func (c *client) SetWiFiChannel(ctx context.Context, index, channel int) error {
+	auto := channel == 0
+	settings := ssidSettings{AutoChannelEnable: &auto}
+	if !auto {
+		settings.Channel = &channel
+	}
+	_, err := callFeature(ctx, c, FeatureWiFi, endpointSetSSID(index),
+		settings)
+	return err
+}
@@ -0,0 +1,107 @@
+package discover
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// DefaultMaxAssets is the default maximum number of assets fetched by Crawl.
+const DefaultMaxAssets = 500
+
+// FetchFunc returns the contents of the asset at the given path.
+type FetchFunc func(ctx context.Context, path string) ([]byte, error)
+
+// CrawlResult is the result of Crawl.
+type CrawlResult struct {
+	Catalog
+	// Assets are the paths of the assets that were fetched.
+	Assets []string `json:"assets"`
+	// Errors are the assets that could not be fetched, with the error.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// WriteJSON writes the result as indented JSON.
+func (r *CrawlResult) WriteJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// WriteFile writes the result as indented JSON to the file. The file is
+// readable by everyone, since the catalog only has the public paths and field
+// names of the web UI.
+func (r *CrawlResult) WriteFile(path string) error {
+	var buf bytes.Buffer
+	if err := r.WriteJSON(&buf); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// Crawl fetches the start assets and, recursively, the HTML and JavaScript
+// assets referenced from them, extracting the API endpoints from each. Failing
+// to fetch an asset is not fatal, and it is recorded in the result instead.
+func Crawl(ctx context.Context, fetch FetchFunc, start []string, maxAssets int) (*CrawlResult, error) {
+	if maxAssets <= 0 {
+		maxAssets = DefaultMaxAssets
+	}
+
+	res := &CrawlResult{Errors: map[string]string{}}
+	seen := map[string]bool{}
+	queue := append([]string(nil), start...)
+
+	for len(queue) > 0 && len(res.Assets) < maxAssets {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+
+		p := queue[0]
+		queue = queue[1:]
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+
+		b, err := fetch(ctx, p)
+		if err != nil {
+			res.Errors[p] = err.Error()
+			continue
+		}
+		res.Assets = append(res.Assets, p)
+
+		for _, e := range ExtractEndpoints(b, p) {
+			res.Add(e)
+		}
+		for _, ref := range ExtractAssets(b, assetBase(p)) {
+			if !seen[ref] {
+				queue = append(queue, ref)
+			}
+		}
+	}
+
+	// the queue can still hold assets referenced again before being fetched
+	if slices.ContainsFunc(queue, func(p string) bool { return !seen[p] }) {
+		return res, fmt.Errorf("stopped after fetching %d assets", maxAssets)
+	}
+
+	return res, nil
+}
+
+// assetBase returns the path against which references in the asset are
+// resolved. Directory-like paths (such as "/") are their own base.
+func assetBase(p string) string {
+	if strings.HasSuffix(p, "/") || path.Ext(p) == "" {
+		return strings.TrimSuffix(p, "/") + "/"
+	}
+	return p
+}
@@ -0,0 +1,241 @@
+// Package discover extracts the API endpoints used by the device web UI from
+// its HTML and JavaScript assets.
+package discover
+
+import (
+	"bytes"
+	"cmp"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// window is how many bytes around an API path are inspected to infer the HTTP
+// method and form fields.
+const window = 400
+
+var (
+	// asset references in HTML (script, link, iframe) and in JavaScript
+	// (string literals ending in .js, .html or .htm)
+	reHTMLRef  = regexp.MustCompile(`(?i)(?:src|href)\s*=\s*["']([^"'#?]+\.(?:js|html?))(?:\?[^"']*)?["']`)
+	reQuoteRef = regexp.MustCompile(`["']([A-Za-z0-9_./-]+\.(?:js|html?))["']`)
+
+	// API paths. Dynamic parts concatenated in JavaScript are cut off at the
+	// closing quote, and are recorded as "{}" followed by the literal suffix,
+	// if any
+	reAPIPath = regexp.MustCompile(`/api/v1/[A-Za-z0-9_./,{}$-]*(?:\?[A-Za-z0-9_=&.,-]*)?`)
+	reConcat  = regexp.MustCompile(`^\s*\+\s*(?:[^"'+;,(){}]|\([^()]*\))+(?:\+\s*["']([A-Za-z0-9_./-]*)["'])?`)
+
+	reMethodProp = regexp.MustCompile(`(?i)\b(?:type|method)\s*:\s*["'](GET|POST|PUT|DELETE|PATCH)["']`)
+	reMethodCall = regexp.MustCompile("(?i)\\.(get|post|put|delete|patch)(?:JSON)?\\s*\\(\\s*[\"'`]?$")
+	// form data as an object literal or a variable, in a data property like
+	// $.ajax({data: ...}) or as the argument after the URL like $.post(url, ...)
+	reDataProp  = regexp.MustCompile(`\bdata\s*:\s*(\{[^{}]*\}|[A-Za-z_$][\w$]*)`)
+	reDataArg   = regexp.MustCompile(`^\s*,\s*(\{[^{}]*\}|[A-Za-z_$][\w$]*)`)
+	reObjectKey = regexp.MustCompile(`["']?([A-Za-z_][A-Za-z0-9_]*)["']?\s*:`)
+	reTemplate  = regexp.MustCompile(`\$\{[^}]*\}`)
+)
+
+// Endpoint is an API endpoint found in the assets.
+type Endpoint struct {
+	Path string `json:"path"`
+	// Methods are the HTTP methods inferred from the surrounding code. Empty
+	// if none could be inferred.
+	Methods []string `json:"methods"`
+	// Fields are the names of form fields inferred from the surrounding code
+	// and from the query string.
+	Fields []string `json:"fields"`
+	// Sources are the assets where the endpoint was found.
+	Sources []string `json:"sources"`
+}
+
+// Catalog is a set of endpoints, sorted by path.
+type Catalog struct {
+	Endpoints []Endpoint `json:"endpoints"`
+}
+
+// Add merges the endpoint into the catalog.
+func (c *Catalog) Add(e Endpoint) {
+	i, found := slices.BinarySearchFunc(c.Endpoints, e.Path,
+		func(e Endpoint, p string) int {
+			return cmp.Compare(e.Path, p)
+		})
+	if !found {
+		c.Endpoints = slices.Insert(c.Endpoints, i, Endpoint{Path: e.Path})
+	}
+	dst := &c.Endpoints[i]
+	dst.Methods = mergeSorted(dst.Methods, e.Methods)
+	dst.Fields = mergeSorted(dst.Fields, e.Fields)
+	dst.Sources = mergeSorted(dst.Sources, e.Sources)
+}
+
+func mergeSorted(a, b []string) []string {
+	ret := append(slices.Clone(a), b...)
+	slices.Sort(ret)
+	return slices.Compact(ret)
+}
+
+// ExtractAssets returns the references to other HTML and JavaScript assets
+// found in src, resolved against the path of src.
+func ExtractAssets(src []byte, srcPath string) []string {
+	base, err := url.Parse(srcPath)
+	if err != nil {
+		return nil
+	}
+
+	var ret []string
+	add := func(ref string) {
+		u, err := url.Parse(ref)
+		if err != nil || u.Host != "" || u.Scheme != "" {
+			return // external or invalid
+		}
+		ret = append(ret, path.Clean(base.ResolveReference(u).Path))
+	}
+	for _, re := range []*regexp.Regexp{reHTMLRef, reQuoteRef} {
+		for _, m := range re.FindAllSubmatch(src, -1) {
+			add(string(m[1]))
+		}
+	}
+
+	slices.Sort(ret)
+	return slices.Compact(ret)
+}
+
+// ExtractEndpoints returns the API endpoints referenced in src.
+func ExtractEndpoints(src []byte, srcPath string) []Endpoint {
+	var c Catalog
+	for _, loc := range reAPIPath.FindAllIndex(src, -1) {
+		p, query, _ := strings.Cut(string(src[loc[0]:loc[1]]), "?")
+		before := src[max(0, loc[0]-window):loc[0]]
+		after := src[loc[1]:min(len(src), loc[1]+window)]
+
+		// rest is the code after the expression of the path
+		rest := after
+		if len(rest) > 0 && (rest[0] == '"' || rest[0] == '\'') {
+			rest = rest[1:]
+			// e.g. "/api/v1/wifi/" + index + "/SSIDEnable"
+			for strings.HasSuffix(p, "/") {
+				m := reConcat.FindSubmatchIndex(rest)
+				if m == nil {
+					break
+				}
+				p += "{}"
+				if m[2] >= 0 {
+					p += string(rest[m[2]:m[3]])
+				}
+				rest = rest[m[1]:]
+			}
+		}
+		p = normalizePath(p)
+		if p == "" {
+			continue
+		}
+
+		e := Endpoint{
+			Path:    p,
+			Methods: inferMethods(before, after),
+			Fields:  inferFields(src[:loc[0]], before, rest, query),
+			Sources: []string{srcPath},
+		}
+		c.Add(e)
+	}
+	return c.Endpoints
+}
+
+func normalizePath(p string) string {
+	p = reTemplate.ReplaceAllString(p, "{}")
+	p = strings.TrimRight(p, ".,-")
+	if p == "/api/v1/" || p == "/api/v1" {
+		return ""
+	}
+	return p
+}
+
+// inferMethods looks for the method declarations around the path:
+// jQuery-style calls like $.post("/api/v1/...") right before it, and request
+// options like {type: "POST"} in the same object literal.
+func inferMethods(before, after []byte) []string {
+	var ret []string
+	if m := reMethodCall.FindSubmatch(tail(before, 40)); m != nil {
+		ret = append(ret, strings.ToUpper(string(m[1])))
+	}
+	for _, seg := range [][]byte{objectTail(before), objectHead(after)} {
+		if m := reMethodProp.FindSubmatch(seg); m != nil {
+			ret = append(ret, strings.ToUpper(string(m[1])))
+		}
+	}
+	slices.Sort(ret)
+	return slices.Compact(ret)
+}
+
+// objectHead returns b up to the end of the current object literal or
+// statement.
+func objectHead(b []byte) []byte {
+	if i := bytes.IndexAny(b, "};"); i >= 0 {
+		return b[:i]
+	}
+	return b
+}
+
+// objectTail returns b from the start of the current object literal, or nil if
+// b doesn't end inside an object literal.
+func objectTail(b []byte) []byte {
+	i := bytes.LastIndexByte(b, '{')
+	if i < 0 || bytes.LastIndexAny(b, "};") > i {
+		return nil
+	}
+	return b[i:]
+}
+
+// inferFields looks for the form fields in the query, and in the data sent
+// with the path, resolving variables to the object literal last assigned to
+// them in src.
+func inferFields(src, before, rest []byte, query string) []string {
+	var ret []string
+	// the API takes comma-separated lists of field names in the query, like
+	// /api/v1/system?ModelName,SoftwareVersion
+	if values, err := url.ParseQuery(query); err == nil {
+		for k := range values {
+			ret = append(ret, strings.Split(k, ",")...)
+		}
+	}
+	if i := bytes.IndexByte(rest, ';'); i >= 0 {
+		rest = rest[:i]
+	}
+	var data []byte
+	if m := reDataProp.FindSubmatch(rest); m != nil {
+		data = m[1]
+	} else if m := reDataArg.FindSubmatch(rest); m != nil &&
+		reMethodCall.Match(tail(before, 40)) {
+		data = m[1]
+	}
+	if len(data) > 0 && data[0] != '{' {
+		data = resolveObject(src, string(data))
+	}
+	for _, k := range reObjectKey.FindAllSubmatch(data, -1) {
+		ret = append(ret, string(k[1]))
+	}
+	slices.Sort(ret)
+	return slices.Compact(ret)
+}
+
+// resolveObject returns the object literal last assigned to the variable in
+// src, or nil if there is none.
+func resolveObject(src []byte, name string) []byte {
+	re, err := regexp.Compile(`\b` + regexp.QuoteMeta(name) +
+		`\s*=\s*(\{[^{}]*\})`)
+	if err != nil {
+		return nil
+	}
+	ms := re.FindAllSubmatch(src, -1)
+	if len(ms) == 0 {
+		return nil
+	}
+	return ms[len(ms)-1][1]
+}
+
+func tail(b []byte, n int) []byte {
+	return b[max(0, len(b)-n):]
+}
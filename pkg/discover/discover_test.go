@@ -0,0 +1,138 @@
+package discover
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestExtractAssets(t *testing.T) {
+	t.Parallel()
+
+	const html = `<html><head>
+<script src="js/app.js?v=2"></script>
+<script src="/lib/jquery.min.js"></script>
+<script src="https://cdn.example.com/x.js"></script>
+</head><body><script>load("views/wifi.html");</script></body></html>`
+
+	got := ExtractAssets([]byte(html), "/index.html")
+	expected := []string{"/js/app.js", "/lib/jquery.min.js", "/views/wifi.html"}
+	if !slices.Equal(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestExtractEndpoints(t *testing.T) {
+	t.Parallel()
+
+	const js = `
+function login(user, pass) {
+	return $.ajax({
+		url: "/api/v1/session/login",
+		type: "POST",
+		data: {username: user, "password": pass}
+	});
+}
+function hosts() { return $.get("/api/v1/host/hostTbl"); }
+function ssid(i) {
+	return $.post("/api/v1/wifi/" + i + "/SSIDEnable", {enable: true});
+}
+function ssidName(i, name) {
+	var form = {ssid: "x"};
+	form = {SSID: name, Band: getBand(i)};
+	return $.ajax({url: "/api/v1/wifi/" + i + "/" + band(i) + "/SSIDName", type: "POST", data: form});
+}
+function reserve(r) {
+	var reservation = {MACAddress: r.mac, Yiaddr: r.ip};
+	return $.post("/api/v1/dhcp/v4/1/staticAddressTbl", reservation);
+}
+var u = "/api/v1/system?ModelName,SoftwareVersion";
+var v = ` + "`/api/v1/dhcp/v4/${idx}/reservation`" + `;
+$.ajax({url: "/api/v1/session/login", method: "GET"});
+`
+
+	got := ExtractEndpoints([]byte(js), "/js/app.js")
+	expected := []Endpoint{
+		{
+			Path:    "/api/v1/dhcp/v4/1/staticAddressTbl",
+			Methods: []string{"POST"},
+			Fields:  []string{"MACAddress", "Yiaddr"},
+		},
+		{Path: "/api/v1/dhcp/v4/{}/reservation"},
+		{Path: "/api/v1/host/hostTbl", Methods: []string{"GET"}},
+		{
+			Path:    "/api/v1/session/login",
+			Methods: []string{"GET", "POST"},
+			Fields:  []string{"password", "username"},
+		},
+		{Path: "/api/v1/system", Fields: []string{"ModelName", "SoftwareVersion"}},
+		{
+			Path:    "/api/v1/wifi/{}/SSIDEnable",
+			Methods: []string{"POST"},
+			Fields:  []string{"enable"},
+		},
+		{
+			Path:    "/api/v1/wifi/{}/{}/SSIDName",
+			Methods: []string{"POST"},
+			Fields:  []string{"Band", "SSID"},
+		},
+	}
+
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d endpoints, got %d: %#v", len(expected), len(got),
+			got)
+	}
+	for i, x := range expected {
+		g := got[i]
+		if g.Path != x.Path || !slices.Equal(g.Methods, x.Methods) ||
+			!slices.Equal(g.Fields, x.Fields) ||
+			!slices.Equal(g.Sources, []string{"/js/app.js"}) {
+			t.Errorf("[#%d] expected %#v, got %#v", i, x, g)
+		}
+	}
+}
+
+func TestCrawl(t *testing.T) {
+	t.Parallel()
+
+	assets := map[string]string{
+		"/":           `<script src="js/app.js"></script>`,
+		"/js/app.js":  `$.get("/api/v1/host/hostTbl"); load("/js/wifi.js");`,
+		"/js/wifi.js": `$.post("/api/v1/wifi/1/SSIDEnable"); load("/js/missing.js");`,
+	}
+	fetch := func(_ context.Context, p string) ([]byte, error) {
+		if s, ok := assets[p]; ok {
+			return []byte(s), nil
+		}
+		return nil, errors.New("not found")
+	}
+
+	res, err := Crawl(context.Background(), fetch, []string{"/"}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"/", "/js/app.js", "/js/wifi.js"}; !slices.Equal(res.Assets, expected) {
+		t.Fatalf("expected assets %v, got %v", expected, res.Assets)
+	}
+	if _, ok := res.Errors["/js/missing.js"]; !ok || len(res.Errors) != 1 {
+		t.Fatalf("unexpected errors: %v", res.Errors)
+	}
+	if len(res.Endpoints) != 2 {
+		t.Fatalf("expected 2 endpoints, got %#v", res.Endpoints)
+	}
+
+	if _, err := Crawl(context.Background(), fetch, []string{"/"}, 1); err == nil {
+		t.Fatalf("expected error when exceeding the maximum assets")
+	}
+
+	// the queue only holds an asset that was referenced twice
+	assets = map[string]string{
+		"/":     `<script src="a.js"></script><script src="b.js"></script>`,
+		"/a.js": `load("/b.js");`,
+		"/b.js": ``,
+	}
+	if _, err := Crawl(context.Background(), fetch, []string{"/"}, 3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
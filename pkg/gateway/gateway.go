@@ -0,0 +1,349 @@
+// Package gateway implements a local JSON REST API for the device, so that
+// other tools don't need to implement the device login. The gateway holds a
+// single session to the device, which only allows one admin session at a time,
+// and shares it among all its clients.
+//
+// All the requests must have an "Authorization: Bearer <token>" header with
+// one of the configured tokens. The endpoints are:
+//
+//	GET  /v1/status      device identification, firmware and uptime
+//	GET  /v1/channels    DOCSIS downstream and upstream channels
+//	GET  /v1/hosts       LAN hosts; with ?active=true, only the connected ones
+//	GET  /v1/wifi        Wi-Fi networks
+//	GET  /v1/wan         Internet connection and cable modem provisioning
+//	POST /v1/reboot      restart the device
+//	POST /v1/guest-wifi  enable or disable the guest Wi-Fi with ?enabled=;
+//	                     with ?for=2h, disable it again after that time;
+//	                     when enabling, returns the guest networks with their
+//	                     passphrases
+//
+// The deadline set with ?for= is only honored while Server.Run is running. If
+// Params.GuestDeadlineFile is set, it is also honored after a restart.
+// Server.Run also removes the access rules of the device pauses that ended,
+// since they would apply again the next week.
+//
+// Successful responses have the form {"data": ...}. Errors have the form
+// {"error": {"code": "...", "message": "..."}}, with the following codes and
+// HTTP status codes:
+//
+//	unauthorized  401  missing or invalid token
+//	not_found     404  unknown endpoint
+//	bad_request   400  invalid request parameters
+//	unsupported   501  not supported by the device firmware
+//	unavailable   503  the device is failing, try again later
+//	timeout       504  the device did not answer in time
+//	device_error  502  any other error talking to the device
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/httpdoer"
+)
+
+// DefaultTimeout is the default maximum duration of a request to the device.
+const DefaultTimeout = 30 * time.Second
+
+// Error codes.
+const (
+	CodeUnauthorized = "unauthorized"
+	CodeNotFound     = "not_found"
+	CodeBadRequest   = "bad_request"
+	CodeUnsupported  = "unsupported"
+	CodeUnavailable  = "unavailable"
+	CodeTimeout      = "timeout"
+	CodeDeviceError  = "device_error"
+)
+
+// Params configures a Server.
+type Params struct {
+	// Client is the client of the device. It should be already logged in.
+	Client client.Client
+	// Tokens are the accepted API tokens. At least one is required.
+	Tokens *Tokens
+	// Timeout is the maximum duration of a request to the device. Defaults
+	// to DefaultTimeout.
+	Timeout time.Duration
+	// Logger, if set, receives a record for each request.
+	Logger *slog.Logger
+	// GuestDeadlineFile, if set, stores the deadline of the guest Wi-Fi, so
+	// that it survives restarts.
+	GuestDeadlineFile string
+}
+
+func (p Params) WithDefaults() Params {
+	if p.Timeout <= 0 {
+		p.Timeout = DefaultTimeout
+	}
+	if p.Logger == nil {
+		p.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return p
+}
+
+// Server is the http.Handler of the gateway API.
+type Server struct {
+	p   Params
+	mux *http.ServeMux
+
+	guestMu    sync.Mutex
+	guestUntil time.Time
+}
+
+func New(p Params) (*Server, error) {
+	p = p.WithDefaults()
+	if p.Client == nil {
+		return nil, errors.New("no client")
+	}
+	if p.Tokens == nil || p.Tokens.Len() == 0 {
+		return nil, errors.New("no API tokens")
+	}
+
+	s := &Server{
+		p:   p,
+		mux: http.NewServeMux(),
+	}
+	if err := s.loadGuestDeadline(); err != nil {
+		return nil, err
+	}
+	s.mux.HandleFunc("GET /v1/status", s.status)
+	s.mux.HandleFunc("GET /v1/channels", s.channels)
+	s.mux.HandleFunc("GET /v1/hosts", s.hosts)
+	s.mux.HandleFunc("GET /v1/wifi", s.wifi)
+	s.mux.HandleFunc("GET /v1/wan", s.wan)
+	s.mux.HandleFunc("POST /v1/reboot", s.reboot)
+	s.mux.HandleFunc("POST /v1/guest-wifi", s.guestWiFi)
+	s.mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		writeError(w, http.StatusNotFound, CodeNotFound, "unknown endpoint")
+	})
+
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	name, valid := s.p.Tokens.Lookup(strings.TrimSpace(token))
+	if !ok || !valid {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="cga-gateway"`)
+		writeError(rec, http.StatusUnauthorized, CodeUnauthorized,
+			"missing or invalid API token")
+	} else {
+		ctx, cancel := context.WithTimeout(r.Context(), s.p.Timeout)
+		defer cancel()
+		s.mux.ServeHTTP(rec, r.WithContext(ctx))
+	}
+
+	s.p.Logger.LogAttrs(r.Context(), slog.LevelInfo, "request",
+		slog.String("token", name),
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.Int("status", rec.status),
+		slog.Duration("duration", time.Since(start)),
+	)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Status is the response of GET /v1/status.
+type Status struct {
+	Model             string `json:"model"`
+	HardwareVersion   string `json:"hardwareVersion"`
+	FirmwareVersion   string `json:"firmwareVersion"`
+	BootloaderVersion string `json:"bootloaderVersion"`
+	SerialNumber      string `json:"serialNumber"`
+	CMMACAddress      string `json:"cmMacAddress"`
+	UptimeSeconds     int64  `json:"uptimeSeconds"`
+}
+
+func (s *Server) status(w http.ResponseWriter, r *http.Request) {
+	info, err := s.p.Client.DeviceInfo(r.Context())
+	if err != nil {
+		writeClientError(w, err)
+		return
+	}
+	writeData(w, Status{
+		Model:             info.Model,
+		HardwareVersion:   info.HardwareVersion,
+		FirmwareVersion:   info.FirmwareVersion.String(),
+		BootloaderVersion: info.BootloaderVersion,
+		SerialNumber:      info.SerialNumber,
+		CMMACAddress:      info.CMMACAddress,
+		UptimeSeconds:     int64(info.CurrentUptime() / time.Second),
+	})
+}
+
+func (s *Server) channels(w http.ResponseWriter, r *http.Request) {
+	channels, err := s.p.Client.Channels(r.Context())
+	if err != nil {
+		writeClientError(w, err)
+		return
+	}
+	writeData(w, channels)
+}
+
+func (s *Server) hosts(w http.ResponseWriter, r *http.Request) {
+	var activeOnly bool
+	if v := r.URL.Query().Get("active"); v != "" {
+		var err error
+		activeOnly, err = strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, CodeBadRequest,
+				"invalid value for active: "+v)
+			return
+		}
+	}
+
+	hosts, err := s.p.Client.Hosts(r.Context())
+	if err != nil {
+		writeClientError(w, err)
+		return
+	}
+	if activeOnly {
+		active := hosts[:0]
+		for _, h := range hosts {
+			if h.Active {
+				active = append(active, h)
+			}
+		}
+		hosts = active
+	}
+	writeData(w, hosts)
+}
+
+func (s *Server) wifi(w http.ResponseWriter, r *http.Request) {
+	networks, err := s.p.Client.WiFi(r.Context())
+	if err != nil {
+		writeClientError(w, err)
+		return
+	}
+	writeData(w, networks)
+}
+
+// WAN is the response of GET /v1/wan.
+type WAN struct {
+	Connected bool `json:"connected"`
+	// Address is the public IPv4 address with its prefix length, or empty
+	// while disconnected.
+	Address       string       `json:"address"`
+	Gateway       string       `json:"gateway"`
+	DNSServers    []string     `json:"dnsServers"`
+	UptimeSeconds int64        `json:"uptimeSeconds"`
+	Provisioning  Provisioning `json:"provisioning"`
+}
+
+// Provisioning is the cable modem provisioning status in WAN.
+type Provisioning struct {
+	Operational  bool   `json:"operational"`
+	State        string `json:"state"`
+	Registration string `json:"registration"`
+	Ranging      string `json:"ranging"`
+	BPIEnabled   bool   `json:"bpiEnabled"`
+	ConfigFile   string `json:"configFile"`
+	ToD          string `json:"tod"`
+	CMAddress    string `json:"cmAddress"`
+}
+
+func (s *Server) wan(w http.ResponseWriter, r *http.Request) {
+	st, err := s.p.Client.WANStatus(r.Context())
+	if err != nil {
+		writeClientError(w, err)
+		return
+	}
+	prov, err := s.p.Client.ProvisioningStatus(r.Context())
+	if err != nil {
+		writeClientError(w, err)
+		return
+	}
+
+	res := WAN{
+		Connected:     st.Connected(),
+		DNSServers:    make([]string, len(st.DNSServers)),
+		UptimeSeconds: int64(st.CurrentUptime() / time.Second),
+		Provisioning: Provisioning{
+			Operational:  prov.Operational(),
+			State:        prov.State,
+			Registration: prov.Registration,
+			Ranging:      prov.Ranging,
+			BPIEnabled:   prov.BPIEnabled,
+			ConfigFile:   prov.ConfigFile,
+			ToD:          prov.ToD,
+		},
+	}
+	if st.Address.IsValid() {
+		res.Address = st.Address.String()
+	}
+	if st.Gateway.IsValid() {
+		res.Gateway = st.Gateway.String()
+	}
+	if prov.CMAddress.IsValid() {
+		res.Provisioning.CMAddress = prov.CMAddress.String()
+	}
+	for i, ip := range st.DNSServers {
+		res.DNSServers[i] = ip.String()
+	}
+	writeData(w, res)
+}
+
+func (s *Server) reboot(w http.ResponseWriter, r *http.Request) {
+	if err := s.p.Client.Reboot(r.Context()); err != nil {
+		writeClientError(w, err)
+		return
+	}
+	writeData(w, struct{}{})
+}
+
+type errorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeData(w http.ResponseWriter, data any) {
+	writeJSON(w, http.StatusOK, map[string]any{"data": data})
+}
+
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	writeJSON(w, status, map[string]any{
+		"error": errorBody{Code: code, Message: msg},
+	})
+}
+
+// writeClientError writes an error returned by the client.
+func writeClientError(w http.ResponseWriter, err error) {
+	status, code := http.StatusBadGateway, CodeDeviceError
+	switch {
+	case errors.Is(err, client.ErrUnsupported):
+		status, code = http.StatusNotImplemented, CodeUnsupported
+	case errors.Is(err, httpdoer.ErrCircuitOpen):
+		status, code = http.StatusServiceUnavailable, CodeUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		status, code = http.StatusGatewayTimeout, CodeTimeout
+	}
+	writeError(w, status, code, err.Error())
+}
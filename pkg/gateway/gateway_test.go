@@ -0,0 +1,271 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+)
+
+type fakeClient struct {
+	client.Client // unimplemented methods panic
+	hosts         []client.Host
+	rebooted      bool
+	guest         bool
+}
+
+func (c *fakeClient) DeviceInfo(context.Context) (*client.DeviceInfo, error) {
+	return &client.DeviceInfo{
+		Model:           "CGA4233TCH3",
+		FirmwareVersion: client.ParseFirmwareVersion("CGA4233TCH3-1.0.26-TCH"),
+		Uptime:          time.Hour,
+		FetchedAt:       time.Now(),
+	}, nil
+}
+
+func (c *fakeClient) Hosts(context.Context) ([]client.Host, error) {
+	return c.hosts, nil
+}
+
+func (c *fakeClient) WiFi(context.Context) ([]client.WiFiNetwork, error) {
+	return nil, &client.UnsupportedError{Feature: client.FeatureWiFi}
+}
+
+func (c *fakeClient) WANStatus(context.Context) (*client.WANStatus, error) {
+	return &client.WANStatus{
+		Address:   netip.MustParsePrefix("203.0.113.7/22"),
+		Uptime:    time.Minute,
+		FetchedAt: time.Now(),
+	}, nil
+}
+
+func (c *fakeClient) ProvisioningStatus(context.Context) (*client.ProvisioningStatus, error) {
+	return &client.ProvisioningStatus{State: "OPERATIONAL", ConfigFile: "cm.cfg"}, nil
+}
+
+func (c *fakeClient) Reboot(context.Context) error {
+	c.rebooted = true
+	return nil
+}
+
+func (c *fakeClient) SetGuestWiFi(_ context.Context, enabled bool) error {
+	c.guest = enabled
+	return nil
+}
+
+func (c *fakeClient) GuestWiFi(context.Context) ([]client.GuestWiFi, error) {
+	return []client.GuestWiFi{{
+		Band:       "2.4GHz",
+		Enabled:    c.guest,
+		SSID:       "Guests",
+		Security:   "WPA2-Personal",
+		Passphrase: "visitors-welcome",
+	}}, nil
+}
+
+func TestServer(t *testing.T) {
+	t.Parallel()
+
+	const token = "0123456789abcdef"
+	tokens := new(Tokens)
+	if err := tokens.Add("test", token); err != nil {
+		t.Fatalf("add token: %v", err)
+	}
+	c := &fakeClient{hosts: []client.Host{
+		{MAC: "00:11:22:33:44:55", Active: true},
+		{MAC: "00:11:22:33:44:66"},
+	}}
+	s, err := New(Params{Client: c, Tokens: tokens})
+	if err != nil {
+		t.Fatalf("create server: %v", err)
+	}
+
+	do := func(method, target, token string, data any) (int, string) {
+		t.Helper()
+		req := httptest.NewRequest(method, target, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+
+		var body struct {
+			Data  json.RawMessage `json:"data"`
+			Error errorBody       `json:"error"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s %s: decode body: %v", method, target, err)
+		}
+		if data != nil {
+			if err := json.Unmarshal(body.Data, data); err != nil {
+				t.Fatalf("%s %s: decode data: %v", method, target, err)
+			}
+		}
+		return rec.Code, body.Error.Code
+	}
+
+	if status, code := do("GET", "/v1/status", "", nil); status != 401 ||
+		code != CodeUnauthorized {
+		t.Fatalf("no token: expected 401 unauthorized, got %d %s", status, code)
+	}
+	if status, _ := do("GET", "/v1/status", "wrong-token-wrong-token", nil); status != 401 {
+		t.Fatalf("wrong token: expected 401, got %d", status)
+	}
+
+	var st Status
+	if status, _ := do("GET", "/v1/status", token, &st); status != 200 {
+		t.Fatalf("status: expected 200, got %d", status)
+	}
+	if st.Model != "CGA4233TCH3" || st.UptimeSeconds < 3600 {
+		t.Fatalf("unexpected status: %#v", st)
+	}
+
+	var hosts []client.Host
+	if status, _ := do("GET", "/v1/hosts?active=true", token, &hosts); status != 200 {
+		t.Fatalf("hosts: expected 200, got %d", status)
+	}
+	if len(hosts) != 1 || hosts[0].MAC != "00:11:22:33:44:55" {
+		t.Fatalf("unexpected hosts: %#v", hosts)
+	}
+
+	var wan WAN
+	if status, _ := do("GET", "/v1/wan", token, &wan); status != 200 {
+		t.Fatalf("wan: expected 200, got %d", status)
+	}
+	if !wan.Connected || wan.Address != "203.0.113.7/22" || wan.Gateway != "" ||
+		wan.UptimeSeconds < 60 || !wan.Provisioning.Operational {
+		t.Fatalf("unexpected WAN status: %#v", wan)
+	}
+
+	if status, code := do("GET", "/v1/wifi", token, nil); status != 501 ||
+		code != CodeUnsupported {
+		t.Fatalf("wifi: expected 501 unsupported, got %d %s", status, code)
+	}
+	if status, code := do("GET", "/v1/nope", token, nil); status != 404 ||
+		code != CodeNotFound {
+		t.Fatalf("unknown: expected 404 not_found, got %d %s", status, code)
+	}
+
+	if status, _ := do("POST", "/v1/reboot", token, nil); status != 200 ||
+		!c.rebooted {
+		t.Fatalf("reboot: expected 200 and a reboot, got %d", status)
+	}
+}
+
+func TestGuestWiFiDeadline(t *testing.T) {
+	t.Parallel()
+
+	const token = "0123456789abcdef"
+	tokens := new(Tokens)
+	if err := tokens.Add("test", token); err != nil {
+		t.Fatalf("add token: %v", err)
+	}
+	c := new(fakeClient)
+	p := Params{
+		Client:            c,
+		Tokens:            tokens,
+		GuestDeadlineFile: filepath.Join(t.TempDir(), "guest_deadline"),
+	}
+	s, err := New(p)
+	if err != nil {
+		t.Fatalf("create server: %v", err)
+	}
+	var res struct {
+		Data GuestWiFi `json:"data"`
+	}
+	do := func(target string) int {
+		t.Helper()
+		req := httptest.NewRequest("POST", target, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		res.Data = GuestWiFi{}
+		json.NewDecoder(rec.Body).Decode(&res)
+		return rec.Code
+	}
+
+	for _, target := range []string{
+		"/v1/guest-wifi",
+		"/v1/guest-wifi?enabled=true&for=soon",
+		"/v1/guest-wifi?enabled=false&for=1h",
+	} {
+		if status := do(target); status != 400 {
+			t.Fatalf("%s: expected 400, got %d", target, status)
+		}
+	}
+
+	if status := do("/v1/guest-wifi?enabled=true&for=1h"); status != 200 ||
+		!c.guest {
+		t.Fatalf("expected 200 and the guest Wi-Fi enabled, got %d", status)
+	}
+	if len(res.Data.Networks) != 1 || res.Data.Networks[0].SSID != "Guests" ||
+		res.Data.Networks[0].Passphrase != "visitors-welcome" {
+		t.Fatalf("unexpected guest networks: %#v", res.Data.Networks)
+	}
+	s.checkGuestDeadline(context.Background())
+	if !c.guest {
+		t.Fatalf("guest Wi-Fi disabled before its deadline")
+	}
+
+	// the deadline survives a restart, and is honored once passed
+	s, err = New(p)
+	if err != nil {
+		t.Fatalf("create server: %v", err)
+	}
+	if time.Until(s.guestUntil) < 59*time.Minute {
+		t.Fatalf("unexpected deadline: %v", s.guestUntil)
+	}
+	s.guestUntil = time.Now().Add(-time.Minute)
+	s.checkGuestDeadline(context.Background())
+	if c.guest {
+		t.Fatalf("guest Wi-Fi not disabled after its deadline")
+	}
+	if _, err := os.Stat(p.GuestDeadlineFile); !os.IsNotExist(err) {
+		t.Fatalf("deadline file not removed: %v", err)
+	}
+
+	// enabling without a deadline clears the previous one
+	do("/v1/guest-wifi?enabled=true&for=1m")
+	do("/v1/guest-wifi?enabled=true")
+	if !s.guestUntil.IsZero() {
+		t.Fatalf("deadline not cleared: %v", s.guestUntil)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "tokens")
+	token, err := AppendToken(path, "ha")
+	if err != nil {
+		t.Fatalf("append token: %v", err)
+	}
+	if _, err := AppendToken(path, "ha"); err == nil {
+		t.Fatalf("expected error appending a duplicate name")
+	}
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString("\n# comment\nscripts 0123456789abcdef\n")
+	f.Close()
+
+	tokens, err := LoadTokens(path)
+	if err != nil {
+		t.Fatalf("load tokens: %v", err)
+	}
+	for expected, tok := range map[string]string{
+		"ha":      token,
+		"scripts": "0123456789abcdef",
+	} {
+		if name, ok := tokens.Lookup(tok); !ok || name != expected {
+			t.Errorf("expected %q, got %q (found: %v)", expected, name, ok)
+		}
+	}
+	if _, ok := tokens.Lookup("0123456789abcdeg"); ok {
+		t.Errorf("unexpected match")
+	}
+}
@@ -0,0 +1,194 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+)
+
+// checkInterval is how often Server.Run checks the deadline of the guest Wi-Fi
+// and the pauses. The deadline is compared in wall clock time, so that if it
+// passed while the host was suspended, it is noticed at most this long after
+// the host resumes.
+const checkInterval = time.Minute
+
+// GuestWiFi is the response of POST /v1/guest-wifi.
+type GuestWiFi struct {
+	Enabled bool `json:"enabled"`
+	// Until is when the gateway disables the guest Wi-Fi again, in RFC 3339
+	// format, or empty if it doesn't.
+	Until string `json:"until,omitempty"`
+	// Networks are the guest networks when they are enabled, so that clients
+	// can show how to join them, e.g. with a QR code.
+	Networks []GuestNetwork `json:"networks,omitempty"`
+}
+
+// GuestNetwork is a guest Wi-Fi network.
+type GuestNetwork struct {
+	Band       string `json:"band"`
+	SSID       string `json:"ssid"`
+	Security   string `json:"security"`
+	Passphrase string `json:"passphrase"`
+}
+
+func (s *Server) guestWiFi(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	enabled, err := strconv.ParseBool(q.Get("enabled"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, CodeBadRequest,
+			"invalid value for enabled: "+q.Get("enabled"))
+		return
+	}
+	var until time.Time
+	if v := q.Get("for"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 || !enabled {
+			writeError(w, http.StatusBadRequest, CodeBadRequest,
+				"invalid value for for: "+v)
+			return
+		}
+		until = time.Now().Add(d)
+	}
+
+	// the deadline is replaced before enabling, so that it is not lost if
+	// the gateway stops in the meantime, and an earlier deadline doesn't
+	// disable the network just enabled
+	s.guestMu.Lock()
+	defer s.guestMu.Unlock()
+	if err := s.setGuestDeadline(until); err != nil {
+		writeError(w, http.StatusInternalServerError, CodeDeviceError,
+			err.Error())
+		return
+	}
+	if err := s.p.Client.SetGuestWiFi(r.Context(), enabled); err != nil {
+		writeClientError(w, err)
+		return
+	}
+
+	res := GuestWiFi{Enabled: enabled}
+	if !until.IsZero() {
+		res.Until = until.Format(time.RFC3339)
+	}
+	if enabled {
+		// the networks are already enabled, so failing to read them is not
+		// an error of the request
+		networks, err := s.p.Client.GuestWiFi(r.Context())
+		if err != nil {
+			s.p.Logger.LogAttrs(r.Context(), slog.LevelError,
+				"read guest Wi-Fi", slog.Any("error", err))
+		}
+		for _, n := range networks {
+			res.Networks = append(res.Networks, GuestNetwork{
+				Band:       n.Band,
+				SSID:       n.SSID,
+				Security:   n.Security,
+				Passphrase: n.Passphrase,
+			})
+		}
+	}
+	writeData(w, res)
+}
+
+// Run disables the guest Wi-Fi when the deadline set with POST /v1/guest-wifi
+// expires, and removes the access rules of the device pauses that ended, until
+// ctx is done. Failures are logged and retried.
+func (s *Server) Run(ctx context.Context) {
+	t := time.NewTicker(checkInterval)
+	defer t.Stop()
+	for {
+		s.checkGuestDeadline(ctx)
+		s.expirePauses(ctx)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Server) checkGuestDeadline(ctx context.Context) {
+	s.guestMu.Lock()
+	defer s.guestMu.Unlock()
+	// Round strips the monotonic clock reading, which doesn't advance while
+	// the host is suspended
+	if s.guestUntil.IsZero() || time.Now().Round(0).Before(s.guestUntil) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.p.Timeout)
+	defer cancel()
+	if err := s.p.Client.SetGuestWiFi(ctx, false); err != nil {
+		s.p.Logger.LogAttrs(ctx, slog.LevelError, "disable guest Wi-Fi",
+			slog.Time("deadline", s.guestUntil), slog.Any("error", err))
+		return
+	}
+	s.p.Logger.LogAttrs(ctx, slog.LevelInfo, "guest Wi-Fi disabled",
+		slog.Time("deadline", s.guestUntil))
+	if err := s.setGuestDeadline(time.Time{}); err != nil {
+		s.p.Logger.LogAttrs(ctx, slog.LevelError, "clear guest Wi-Fi deadline",
+			slog.Any("error", err))
+	}
+}
+
+func (s *Server) expirePauses(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, s.p.Timeout)
+	defer cancel()
+	err := s.p.Client.ExpirePauses(ctx)
+	if err != nil && !errors.Is(err, client.ErrUnsupported) {
+		s.p.Logger.LogAttrs(ctx, slog.LevelError, "expire pauses",
+			slog.Any("error", err))
+	}
+}
+
+// setGuestDeadline sets the deadline of the guest Wi-Fi, and stores it in the
+// GuestDeadlineFile. A zero until clears it. guestMu must be held.
+func (s *Server) setGuestDeadline(until time.Time) error {
+	until = until.Round(0)
+	if f := s.p.GuestDeadlineFile; f != "" {
+		var err error
+		if until.IsZero() {
+			err = os.Remove(f)
+			if errors.Is(err, fs.ErrNotExist) {
+				err = nil
+			}
+		} else {
+			err = os.WriteFile(f, []byte(until.Format(time.RFC3339)+"\n"),
+				0o600)
+		}
+		if err != nil {
+			return fmt.Errorf("store guest Wi-Fi deadline: %w", err)
+		}
+	}
+	s.guestUntil = until
+	return nil
+}
+
+// loadGuestDeadline reads the deadline of the guest Wi-Fi stored in the
+// GuestDeadlineFile, if any.
+func (s *Server) loadGuestDeadline() error {
+	if s.p.GuestDeadlineFile == "" {
+		return nil
+	}
+	b, err := os.ReadFile(s.p.GuestDeadlineFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read guest Wi-Fi deadline: %w", err)
+	}
+	until, err := time.Parse(time.RFC3339, strings.TrimSpace(string(b)))
+	if err != nil {
+		return fmt.Errorf("parse guest Wi-Fi deadline: %w", err)
+	}
+	s.guestUntil = until
+	return nil
+}
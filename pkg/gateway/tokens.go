@@ -0,0 +1,143 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// minTokenLength is the minimum length of an API token, to prevent guessable
+// tokens.
+const minTokenLength = 16
+
+// Tokens are the API tokens accepted by the gateway. Each token has a name,
+// used to identify the client in the logs.
+type Tokens struct {
+	entries []tokenEntry
+}
+
+type tokenEntry struct {
+	name string
+	hash [sha256.Size]byte
+}
+
+// LoadTokens reads a tokens file. Each line has a name and a token, separated
+// by spaces. Empty lines and lines starting with "#" are ignored.
+func LoadTokens(path string) (*Tokens, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read tokens file: %w", err)
+	}
+
+	t := new(Tokens)
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for lineNum := 1; s.Scan(); lineNum++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected a name and a token", path,
+				lineNum)
+		}
+		if err := t.Add(fields[0], fields[1]); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNum, err)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("read tokens file: %w", err)
+	}
+
+	return t, nil
+}
+
+// Add adds a token.
+func (t *Tokens) Add(name, token string) error {
+	if len(token) < minTokenLength {
+		return fmt.Errorf("token %q is shorter than %d characters", name,
+			minTokenLength)
+	}
+	t.entries = append(t.entries, tokenEntry{name, sha256.Sum256([]byte(token))})
+	return nil
+}
+
+// Len returns the number of tokens.
+func (t *Tokens) Len() int { return len(t.entries) }
+
+// Lookup returns the name of the token. All the tokens are compared in
+// constant time.
+func (t *Tokens) Lookup(token string) (string, bool) {
+	hash := sha256.Sum256([]byte(token))
+	var name string
+	var found bool
+	for _, e := range t.entries {
+		if subtle.ConstantTimeCompare(hash[:], e.hash[:]) == 1 && !found {
+			name, found = e.name, true
+		}
+	}
+	return name, found
+}
+
+// NewToken returns a random token.
+func NewToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// AppendToken generates a token with the given name, appends it to the tokens
+// file, creating it if needed, and returns it.
+func AppendToken(path, name string) (string, error) {
+	if name == "" || strings.ContainsFunc(name, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\n' || r == '#'
+	}) {
+		return "", fmt.Errorf("invalid token name %q", name)
+	}
+	if t, err := LoadTokens(path); err == nil {
+		if t.hasName(name) {
+			return "", fmt.Errorf("token %q already exists", name)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	token, err := NewToken()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", fmt.Errorf("create tokens directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", fmt.Errorf("open tokens file: %w", err)
+	}
+	if _, err := fmt.Fprintf(f, "%s %s\n", name, token); err != nil {
+		f.Close()
+		return "", fmt.Errorf("write tokens file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("write tokens file: %w", err)
+	}
+
+	return token, nil
+}
+
+func (t *Tokens) hasName(name string) bool {
+	return slices.ContainsFunc(t.entries, func(e tokenEntry) bool {
+		return e.name == name
+	})
+}
@@ -0,0 +1,160 @@
+package httpdoer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/util"
+)
+
+// DefaultMaxBodySize is the default maximum size of a buffered response body.
+// The device API responses are a few KiB at most.
+const DefaultMaxBodySize = 8 << 20
+
+var defaultBodyPool = util.NewBytesBufferAdaptivePool(2)
+
+// BodyTooLargeError is returned when a response body exceeds the maximum size
+// allowed for buffering.
+type BodyTooLargeError struct {
+	Limit int64
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("response body larger than %d bytes", e.Limit)
+}
+
+type streamingContextKey struct{}
+
+// WithStreaming returns a context that makes the requests that use it bypass
+// body buffering, so that large responses can be streamed. The caller must
+// close the response body.
+func WithStreaming(ctx context.Context) context.Context {
+	return context.WithValue(ctx, streamingContextKey{}, true)
+}
+
+// IsStreaming reports whether the context was created with WithStreaming.
+func IsStreaming(ctx context.Context) bool {
+	v, _ := ctx.Value(streamingContextKey{}).(bool)
+	return v
+}
+
+// BufferedBody is a response body held in memory in a pooled buffer. Closing
+// it returns the buffer to the pool.
+type BufferedBody struct {
+	mu   sync.Mutex
+	buf  *bytes.Buffer
+	pool *util.BytesBufferAdaptivePool
+}
+
+func (b *BufferedBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.buf == nil {
+		return 0, http.ErrBodyReadAfterClose
+	}
+	return b.buf.Read(p)
+}
+
+// Bytes returns a copy of the unread portion of the body.
+func (b *BufferedBody) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.buf == nil {
+		return nil
+	}
+	return bytes.Clone(b.buf.Bytes())
+}
+
+// Len returns the length of the unread portion of the body.
+func (b *BufferedBody) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.buf == nil {
+		return 0
+	}
+	return b.buf.Len()
+}
+
+func (b *BufferedBody) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.buf != nil {
+		b.pool.Release(b.buf)
+		b.buf = nil
+	}
+	return nil
+}
+
+// BufferParams configures BufferBody.
+type BufferParams struct {
+	// MaxBodySize is the maximum size of a body to be buffered. Defaults to
+	// DefaultMaxBodySize. A negative value means no limit.
+	MaxBodySize int64
+	// Pool is where buffers are taken from. Defaults to a shared pool.
+	Pool *util.BytesBufferAdaptivePool
+}
+
+// BufferBody reads the whole response body into a *BufferedBody and closes the
+// original one, failing with a *BodyTooLargeError if the body is larger than
+// allowed. Requests whose context was created with WithStreaming are passed
+// through.
+func BufferBody(d HTTPDoer, p BufferParams) HTTPDoer {
+	if p.MaxBodySize == 0 {
+		p.MaxBodySize = DefaultMaxBodySize
+	}
+	if p.Pool == nil {
+		p.Pool = defaultBodyPool
+	}
+
+	return HTTPDoerFunc(func(req *http.Request) (*http.Response, error) {
+		res, err := d.Do(req)
+		if err != nil || IsStreaming(req.Context()) {
+			return res, err
+		}
+		defer res.Body.Close()
+
+		var r io.Reader = res.Body
+		if p.MaxBodySize > 0 {
+			r = io.LimitReader(r, p.MaxBodySize+1)
+		}
+		buf := p.Pool.Acquire()
+		if _, err := buf.ReadFrom(r); err != nil {
+			p.Pool.Release(buf)
+			return nil, fmt.Errorf("buffering body: %w", err)
+		}
+		if p.MaxBodySize > 0 && int64(buf.Len()) > p.MaxBodySize {
+			p.Pool.Release(buf)
+			return nil, &BodyTooLargeError{Limit: p.MaxBodySize}
+		}
+		if err := res.Body.Close(); err != nil {
+			p.Pool.Release(buf)
+			return nil, fmt.Errorf("closing body: %w", err)
+		}
+
+		res.Body = &BufferedBody{buf: buf, pool: p.Pool}
+		return res, nil
+	})
+}
+
+// BufferAndCloseBody calls BufferBody with the default params.
+func BufferAndCloseBody(d HTTPDoer) HTTPDoer {
+	return BufferBody(d, BufferParams{})
+}
+
+// ReadAndCloseBody returns the contents of the response body and closes it,
+// regardless of the concrete type of the body.
+func ReadAndCloseBody(res *http.Response) ([]byte, error) {
+	defer res.Body.Close()
+	if b, ok := res.Body.(*BufferedBody); ok {
+		return b.Bytes(), nil
+	}
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+	return b, nil
+}
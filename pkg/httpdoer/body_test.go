@@ -0,0 +1,77 @@
+package httpdoer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func stringResponder(body string) HTTPDoer {
+	return HTTPDoerFunc(func(*http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}, nil
+	})
+}
+
+func TestBufferBody(t *testing.T) {
+	t.Parallel()
+
+	const body = "0123456789"
+
+	testCases := []struct {
+		name      string
+		max       int64
+		streaming bool
+		wantErr   bool
+	}{
+		{name: "default limit"},
+		{name: "exact limit", max: int64(len(body))},
+		{name: "no limit", max: -1},
+		{name: "too large", max: int64(len(body)) - 1, wantErr: true},
+		{name: "streaming", max: 1, streaming: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			d := BufferBody(stringResponder(body), BufferParams{
+				MaxBodySize: tc.max,
+			})
+			ctx := context.Background()
+			if tc.streaming {
+				ctx = WithStreaming(ctx)
+			}
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet,
+				"https://device/", nil)
+
+			res, err := d.Do(req)
+			if tc.wantErr {
+				var tooLarge *BodyTooLargeError
+				if !errors.As(err, &tooLarge) {
+					t.Fatalf("expected *BodyTooLargeError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if _, buffered := res.Body.(*BufferedBody); buffered == tc.streaming {
+				t.Fatalf("unexpected body type %T", res.Body)
+			}
+			got, err := ReadAndCloseBody(res)
+			if err != nil {
+				t.Fatalf("unexpected error reading body: %v", err)
+			}
+			if string(got) != body {
+				t.Fatalf("expected body %q, got %q", body, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package httpdoer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultBreakerFailureThreshold = 5
+	DefaultBreakerCooldown         = 30 * time.Second
+	DefaultBreakerHalfOpenProbes   = 1
+)
+
+// ErrCircuitOpen is matched by errors.Is for all *CircuitOpenError values.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// CircuitOpenError is returned without performing the request while the
+// circuit breaker for a host is open.
+type CircuitOpenError struct {
+	Host string
+	// Until is when the circuit will allow probe requests.
+	Until time.Time
+	// LastErr is the error that caused the circuit to open.
+	LastErr error
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit open for %s until %s; last error: %v",
+		e.Host, e.Until.Format(time.RFC3339), e.LastErr)
+}
+
+func (e *CircuitOpenError) Is(target error) bool { return target == ErrCircuitOpen }
+
+func (e *CircuitOpenError) Unwrap() error { return e.LastErr }
+
+// BreakerState is the state of the circuit breaker of a host.
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("BreakerState(%d)", int(s))
+}
+
+// BreakerParams configures a Breaker.
+type BreakerParams struct {
+	// FailureThreshold is the number of consecutive failures that open the
+	// circuit. Defaults to DefaultBreakerFailureThreshold.
+	FailureThreshold int
+	// Cooldown is the time the circuit stays open before allowing probes.
+	// Defaults to DefaultBreakerCooldown.
+	Cooldown time.Duration
+	// HalfOpenProbes is the number of concurrent probe requests allowed while
+	// half-open. Defaults to DefaultBreakerHalfOpenProbes.
+	HalfOpenProbes int
+	// IsFailure decides whether the result of a request counts as a failure.
+	// Defaults to IsBreakerFailure.
+	IsFailure func(*http.Response, error) bool
+	// OnStateChange, if set, is called when the state of a host changes. It
+	// must not call the Breaker.
+	OnStateChange func(host string, from, to BreakerState)
+}
+
+// IsBreakerFailure reports transport errors (including timeouts) and 5xx
+// responses as failures. Requests canceled by the caller are never reported
+// to IsFailure.
+func IsBreakerFailure(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return res.StatusCode >= http.StatusInternalServerError
+}
+
+// Breaker is a circuit breaker that keeps a separate circuit for each host.
+// After FailureThreshold consecutive failures the circuit opens and requests
+// fail immediately with a *CircuitOpenError. After the cooldown, the circuit
+// becomes half-open and lets up to HalfOpenProbes requests through: if one
+// succeeds the circuit closes, and if one fails it opens again.
+type Breaker struct {
+	p     BreakerParams
+	mu    sync.Mutex
+	hosts map[string]*hostBreaker
+}
+
+type hostBreaker struct {
+	state     BreakerState
+	failures  int
+	openUntil time.Time
+	probes    int
+	lastErr   error
+}
+
+func NewBreaker(p BreakerParams) *Breaker {
+	if p.FailureThreshold <= 0 {
+		p.FailureThreshold = DefaultBreakerFailureThreshold
+	}
+	if p.Cooldown <= 0 {
+		p.Cooldown = DefaultBreakerCooldown
+	}
+	if p.HalfOpenProbes <= 0 {
+		p.HalfOpenProbes = DefaultBreakerHalfOpenProbes
+	}
+	if p.IsFailure == nil {
+		p.IsFailure = IsBreakerFailure
+	}
+	return &Breaker{
+		p:     p,
+		hosts: make(map[string]*hostBreaker),
+	}
+}
+
+// State returns the current state of the circuit for host.
+func (b *Breaker) State(host string) BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h := b.hosts[host]
+	if h == nil {
+		return BreakerClosed
+	}
+	if h.state == BreakerOpen && !time.Now().Before(h.openUntil) {
+		return BreakerHalfOpen
+	}
+	return h.state
+}
+
+func (b *Breaker) setState(host string, h *hostBreaker, to BreakerState) {
+	from := h.state
+	h.state = to
+	if from != to && b.p.OnStateChange != nil {
+		b.p.OnStateChange(host, from, to)
+	}
+}
+
+// allow returns whether a request can be performed, and whether it is a
+// probe.
+func (b *Breaker) allow(host string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	h := b.hosts[host]
+	if h == nil {
+		h = new(hostBreaker)
+		b.hosts[host] = h
+	}
+
+	if h.state == BreakerOpen && !time.Now().Before(h.openUntil) {
+		b.setState(host, h, BreakerHalfOpen)
+		h.probes = 0
+	}
+
+	switch h.state {
+	case BreakerClosed:
+		return false, nil
+	case BreakerHalfOpen:
+		if h.probes < b.p.HalfOpenProbes {
+			h.probes++
+			return true, nil
+		}
+	}
+
+	return false, &CircuitOpenError{
+		Host:    host,
+		Until:   h.openUntil,
+		LastErr: h.lastErr,
+	}
+}
+
+// report records the outcome of a request. A nil outcome means the request
+// doesn't tell anything about the health of the host.
+func (b *Breaker) report(host string, probe bool, outcome *breakerOutcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	h := b.hosts[host]
+	if probe {
+		h.probes--
+	}
+
+	// only the probes change the state of an open circuit, since the other
+	// requests started before it opened
+	if outcome == nil || !probe && h.state != BreakerClosed {
+		return
+	}
+	failed, err := outcome.failed, outcome.err
+	if !failed {
+		h.failures = 0
+		h.lastErr = nil
+		b.setState(host, h, BreakerClosed)
+		return
+	}
+
+	h.failures++
+	h.lastErr = err
+	if probe || h.failures >= b.p.FailureThreshold {
+		h.openUntil = time.Now().Add(b.p.Cooldown)
+		b.setState(host, h, BreakerOpen)
+	}
+}
+
+type breakerOutcome struct {
+	failed bool
+	err    error
+}
+
+// WithBreaker makes requests through d fail fast while the circuit breaker for
+// their host is open. It must be wrapped by Limit, if used, so that the queued
+// requests are checked when their turn comes.
+func WithBreaker(d HTTPDoer, b *Breaker) HTTPDoer {
+	return HTTPDoerFunc(func(req *http.Request) (*http.Response, error) {
+		host := req.URL.Host
+		probe, err := b.allow(host)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := d.Do(req)
+		if errors.Is(err, context.Canceled) {
+			b.report(host, probe, nil)
+			return res, err
+		}
+
+		outcome := &breakerOutcome{
+			failed: b.p.IsFailure(res, err),
+			err:    err,
+		}
+		if outcome.failed && err == nil {
+			outcome.err = fmt.Errorf("HTTP status %s", res.Status)
+		}
+		b.report(host, probe, outcome)
+
+		return res, err
+	})
+}
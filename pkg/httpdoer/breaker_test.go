@@ -0,0 +1,160 @@
+package httpdoer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	t.Parallel()
+
+	const cooldown = 20 * time.Millisecond
+	var transitions []BreakerState
+	b := NewBreaker(BreakerParams{
+		FailureThreshold: 2,
+		Cooldown:         cooldown,
+		OnStateChange: func(_ string, _, to BreakerState) {
+			transitions = append(transitions, to)
+		},
+	})
+
+	var calls int
+	var status int
+	var fail error
+	d := WithBreaker(HTTPDoerFunc(func(*http.Request) (*http.Response, error) {
+		calls++
+		if fail != nil {
+			return nil, fail
+		}
+		return &http.Response{StatusCode: status, Status: http.StatusText(status)}, nil
+	}), b)
+	do := func(ctx context.Context) error {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet,
+			"https://device/", nil)
+		_, err := d.Do(req)
+		return err
+	}
+	ctx := context.Background()
+
+	// cancellations and successes don't count
+	fail = context.Canceled
+	do(ctx)
+	do(ctx)
+	fail, status = nil, http.StatusOK
+	if err := do(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// open after consecutive failures
+	status = http.StatusServiceUnavailable
+	do(ctx)
+	fail = context.DeadlineExceeded
+	do(ctx)
+	if s := b.State("device"); s != BreakerOpen {
+		t.Fatalf("expected open circuit, got %v", s)
+	}
+
+	// fail fast while open
+	calls = 0
+	err := do(ctx)
+	var openErr *CircuitOpenError
+	if !errors.As(err, &openErr) || !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected *CircuitOpenError, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected last error to be wrapped, got %v", err)
+	}
+	if calls != 0 {
+		t.Fatalf("expected no calls while open, got %d", calls)
+	}
+
+	// a failed probe opens it again
+	time.Sleep(cooldown)
+	do(ctx)
+	if calls != 1 || b.State("device") != BreakerOpen {
+		t.Fatalf("expected a failed probe; calls: %d, state: %v", calls,
+			b.State("device"))
+	}
+
+	// a successful probe closes it
+	time.Sleep(cooldown)
+	fail, status = nil, http.StatusOK
+	if err := do(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s := b.State("device"); s != BreakerClosed {
+		t.Fatalf("expected closed circuit, got %v", s)
+	}
+
+	expected := []BreakerState{BreakerOpen, BreakerHalfOpen, BreakerOpen,
+		BreakerHalfOpen, BreakerClosed}
+	if len(transitions) != len(expected) {
+		t.Fatalf("expected transitions %v, got %v", expected, transitions)
+	}
+	for i := range expected {
+		if transitions[i] != expected[i] {
+			t.Fatalf("expected transitions %v, got %v", expected, transitions)
+		}
+	}
+}
+
+func TestBreakerBehindLimiter(t *testing.T) {
+	t.Parallel()
+
+	b := NewBreaker(BreakerParams{FailureThreshold: 1, Cooldown: time.Hour})
+	var mu sync.Mutex
+	results := map[string]chan error{
+		"/slow":    make(chan error),
+		"/failing": make(chan error),
+		"/queued":  make(chan error),
+	}
+	started := make(chan struct{})
+	d := Limit(WithBreaker(HTTPDoerFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		result := results[req.URL.Path]
+		mu.Unlock()
+		started <- struct{}{}
+		if err := <-result; err != nil {
+			return nil, err
+		}
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	}), b), NewLimiter(LimitParams{MaxConcurrent: 2}))
+	do := func(path string) <-chan error {
+		errc := make(chan error, 1)
+		go func() {
+			req, _ := http.NewRequest(http.MethodGet, "https://device"+path, nil)
+			_, err := d.Do(req)
+			errc <- err
+		}()
+		return errc
+	}
+
+	// the slow request started before the circuit opened
+	slow := do("/slow")
+	<-started
+	failing := do("/failing")
+	<-started
+	queued := do("/queued")
+	results["/failing"] <- context.DeadlineExceeded
+	if err := <-failing; !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the request queued behind the limiter fails fast
+	if err := <-queued; !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected *CircuitOpenError, got %v", err)
+	}
+
+	// and the late success of the slow request doesn't close the circuit
+	results["/slow"] <- nil
+	if err := <-slow; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s := b.State("device"); s != BreakerOpen {
+		t.Fatalf("expected open circuit, got %v", s)
+	}
+}
@@ -2,11 +2,15 @@ package httpdoer
 
 import (
 	"bytes"
-	"crypto/tls"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httputil"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -17,23 +21,13 @@ type HTTPDoer interface {
 	Do(*http.Request) (*http.Response, error)
 }
 
-func New(allowInsecure bool) HTTPDoer {
-	d := new(http.Client)
-	if allowInsecure {
-		t := http.DefaultTransport.(*http.Transport)
-		t.TLSClientConfig = &tls.Config{
-			InsecureSkipVerify: true,
-		}
-		/*
-			t := *http.DefaultTransport.(*http.Transport)
-			t.TLSClientConfig = &tls.Config{
-				InsecureSkipVerify: true,
-			}
-			d.Transport = &t
-		*/
+// New creates a new HTTPDoer using a transport created with the given params.
+func New(p TransportParams) (HTTPDoer, error) {
+	t, err := NewTransport(p)
+	if err != nil {
+		return nil, fmt.Errorf("create transport: %w", err)
 	}
-
-	return d
+	return &http.Client{Transport: t}, nil
 }
 
 type HTTPDoerFunc func(*http.Request) (*http.Response, error)
@@ -69,25 +63,6 @@ type ReadNopCloser struct {
 
 func (rc ReadNopCloser) Close() error { return nil }
 
-func BufferAndCloseBody(d HTTPDoer) HTTPDoer {
-	return HTTPDoerFunc(func(req *http.Request) (*http.Response, error) {
-		res, err := d.Do(req)
-		if err != nil {
-			return nil, err
-		}
-		defer res.Body.Close()
-		buf := new(bytes.Buffer)
-		if _, err := buf.ReadFrom(res.Body); err != nil {
-			return nil, fmt.Errorf("buffering body: %w", err)
-		}
-		if err := res.Body.Close(); err != nil {
-			return nil, fmt.Errorf("closing body: %w", err)
-		}
-		res.Body = ReadNopCloser{buf}
-		return res, nil
-	})
-}
-
 func WithCookieJar(d HTTPDoer, cj http.CookieJar) HTTPDoer {
 	return HTTPDoerFunc(func(req *http.Request) (*http.Response, error) {
 		for _, cookie := range cj.Cookies(req.URL) {
@@ -104,21 +79,66 @@ func WithCookieJar(d HTTPDoer, cj http.CookieJar) HTTPDoer {
 	})
 }
 
+// Debug writes a dump of each request and its response to w. Only the bodies
+// of responses buffered by BufferBody are dumped, so it must wrap it to dump
+// them. The values of the form fields whose name contains "password" are
+// redacted.
 func Debug(d HTTPDoer, w io.Writer) HTTPDoer {
 	pool := util.NewBytesBufferAdaptivePool(2)
 	sep := strings.Repeat("=", 80)
+	var mu sync.Mutex
 
 	return HTTPDoerFunc(func(req *http.Request) (*http.Response, error) {
 		buf := pool.Acquire()
 		defer pool.Release(buf)
 
 		id := uuid.New().String()
-		fmt.FPrintln(sep)
-		fmt.FPrintln()
-
-		req2 := req.Clone(req.Context())
+		fmt.Fprintln(buf, sep)
+		fmt.Fprintf(buf, "%v %v %v meta:%#v\n\n", id, req.Method,
+			req.URL.String(), util.GetContextMeta(req.Context()))
+		if dump, err := httputil.DumpRequestOut(req, true); err != nil {
+			fmt.Fprintf(buf, "error dumping request: %v\n", err)
+		} else {
+			buf.Write(redactDump(req.Header, dump))
+		}
+		fmt.Fprintln(buf)
 
-		fmt.Printf("%v %v meta:%#v", req.Method, req.URL.String(), util.GetContextMeta())
+		start := time.Now()
 		res, err := d.Do(req)
+		fmt.Fprintf(buf, "\n%v took %v\n\n", id, time.Since(start))
+		if err != nil {
+			fmt.Fprintf(buf, "error: %v\n", err)
+		} else if dump, err := httputil.DumpResponse(res, false); err != nil {
+			fmt.Fprintf(buf, "error dumping response: %v\n", err)
+		} else {
+			buf.Write(dump)
+			if b, ok := res.Body.(*BufferedBody); ok {
+				buf.Write(b.Bytes())
+			}
+		}
+		fmt.Fprintln(buf)
+
+		mu.Lock()
+		defer mu.Unlock()
+		buf.WriteTo(w)
+
+		return res, err
 	})
 }
+
+var redactedFormFields = regexp.MustCompile(
+	`(?i)((?:^|&)[^&=]*password[^&=]*=)[^&]*`)
+
+// redactDump redacts the password fields of the form body of a request dump.
+func redactDump(h http.Header, dump []byte) []byte {
+	if !strings.HasPrefix(h.Get(HeaderNameContentType),
+		"application/x-www-form-urlencoded") {
+		return dump
+	}
+	head, body, ok := bytes.Cut(dump, []byte("\r\n\r\n"))
+	if !ok {
+		return dump
+	}
+	body = redactedFormFields.ReplaceAll(body, []byte("${1}REDACTED"))
+	return slices.Concat(head, []byte("\r\n\r\n"), body)
+}
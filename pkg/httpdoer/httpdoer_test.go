@@ -0,0 +1,56 @@
+package httpdoer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestDebug(t *testing.T) {
+	t.Parallel()
+
+	const body = "0123456789"
+	var out bytes.Buffer
+	d := Debug(BufferBody(stringResponder(body), BufferParams{MaxBodySize: 4}), &out)
+	form := url.Values{
+		"username":       {"custadmin"},
+		"password":       {"secret1"},
+		"login_password": {"secret2"},
+	}
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost,
+		"https://device/api/v1/session/login", strings.NewReader(form.Encode()))
+	req.Header.Set(HeaderNameContentType, "application/x-www-form-urlencoded")
+
+	// the body is not read beyond the limit
+	var tooLarge *BodyTooLargeError
+	if _, err := d.Do(req); !errors.As(err, &tooLarge) {
+		t.Fatalf("expected *BodyTooLargeError, got %v", err)
+	}
+	dump := out.String()
+	if strings.Contains(dump, "secret") || !strings.Contains(dump,
+		"login_password=REDACTED&password=REDACTED&username=custadmin") {
+		t.Fatalf("passwords not redacted:\n%s", dump)
+	}
+
+	out.Reset()
+	d = Debug(BufferAndCloseBody(stringResponder(body)), &out)
+	req, _ = http.NewRequest(http.MethodGet, "https://device/", nil)
+	res, err := d.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), body) {
+		t.Fatalf("body not dumped:\n%s", out.String())
+	}
+	// the body can still be read
+	if got, _ := ReadAndCloseBody(res); string(got) != body {
+		t.Fatalf("expected body %q, got %q", body, got)
+	}
+	if _, err := res.Body.Read(make([]byte, 1)); !errors.Is(err, http.ErrBodyReadAfterClose) {
+		t.Fatalf("expected http.ErrBodyReadAfterClose, got %v", err)
+	}
+}
@@ -0,0 +1,243 @@
+package httpdoer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const fingerprintPrefix = "sha256/"
+
+// PinMismatchError is returned when the certificate presented by a host does
+// not match the one recorded in the known hosts file.
+type PinMismatchError struct {
+	Host     string
+	Expected string
+	Got      string
+	File     string
+}
+
+func (e *PinMismatchError) Error() string {
+	return fmt.Sprintf("certificate of %s changed: expected %s, got %s; if "+
+		"the change is intentional, re-pin the host in %s", e.Host,
+		e.Expected, e.Got, e.File)
+}
+
+// Fingerprint returns the fingerprint of the public key (SPKI) of the given
+// certificate, in the same format used in the known hosts file.
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return fingerprintPrefix + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// KnownHosts implements trust-on-first-use certificate pinning. The first time
+// a host is connected to, the fingerprint of its certificate public key is
+// recorded in a file. Subsequent connections fail with a *PinMismatchError if
+// the fingerprint changes. Each line of the file has a "host:port" and a
+// fingerprint, separated by a space. Empty lines and lines starting with "#"
+// are ignored.
+type KnownHosts struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewKnownHosts(path string) *KnownHosts {
+	return &KnownHosts{path: path}
+}
+
+func (k *KnownHosts) Path() string { return k.path }
+
+type knownHost struct {
+	host, fingerprint string
+}
+
+func (k *KnownHosts) load() ([]knownHost, error) {
+	b, err := os.ReadFile(k.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read known hosts file: %w", err)
+	}
+
+	var ret []knownHost
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for lineNum := 1; s.Scan(); lineNum++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 || !strings.HasPrefix(fields[1], fingerprintPrefix) {
+			return nil, fmt.Errorf("%s:%d: invalid known hosts entry", k.path,
+				lineNum)
+		}
+		ret = append(ret, knownHost{fields[0], fields[1]})
+	}
+
+	return ret, s.Err()
+}
+
+func (k *KnownHosts) save(entries []knownHost) error {
+	buf := new(bytes.Buffer)
+	for _, e := range entries {
+		fmt.Fprintf(buf, "%s %s\n", e.host, e.fingerprint)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(k.path), 0o700); err != nil {
+		return fmt.Errorf("create known hosts directory: %w", err)
+	}
+	tmp := k.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("write known hosts file: %w", err)
+	}
+	if err := os.Rename(tmp, k.path); err != nil {
+		return fmt.Errorf("replace known hosts file: %w", err)
+	}
+
+	return nil
+}
+
+// Lookup returns the fingerprint pinned for host, which must be in the form
+// "host:port".
+func (k *KnownHosts) Lookup(host string) (string, bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	entries, err := k.load()
+	if err != nil {
+		return "", false, err
+	}
+	i := slices.IndexFunc(entries, func(e knownHost) bool {
+		return e.host == host
+	})
+	if i < 0 {
+		return "", false, nil
+	}
+	return entries[i].fingerprint, true, nil
+}
+
+// Pin records the fingerprint for the host, replacing any previous one.
+func (k *KnownHosts) Pin(host, fingerprint string) error {
+	if !strings.HasPrefix(fingerprint, fingerprintPrefix) {
+		return fmt.Errorf("invalid fingerprint %q", fingerprint)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	entries, err := k.load()
+	if err != nil {
+		return err
+	}
+	entries = slices.DeleteFunc(entries, func(e knownHost) bool {
+		return e.host == host
+	})
+	entries = append(entries, knownHost{host, fingerprint})
+
+	return k.save(entries)
+}
+
+// Verify checks the certificate of host against the pinned one, pinning it if
+// the host is not known yet.
+func (k *KnownHosts) Verify(host string, cert *x509.Certificate) error {
+	got := Fingerprint(cert)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	entries, err := k.load()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.host != host {
+			continue
+		}
+		if e.fingerprint != got {
+			return &PinMismatchError{
+				Host:     host,
+				Expected: e.fingerprint,
+				Got:      got,
+				File:     k.path,
+			}
+		}
+		return nil
+	}
+
+	return k.save(append(entries, knownHost{host, got}))
+}
+
+// DialTLSContext dials addr and performs a TLS handshake that, instead of
+// verifying the certificate chain, verifies the certificate against the known
+// hosts. The given config is not modified.
+func (k *KnownHosts) DialTLSContext(
+	ctx context.Context,
+	dialer *net.Dialer,
+	config *tls.Config,
+	network string,
+	addr string,
+) (net.Conn, error) {
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c := config.Clone()
+	if c.ServerName == "" {
+		c.ServerName, _, _ = net.SplitHostPort(addr)
+	}
+	c.InsecureSkipVerify = true
+	c.VerifyConnection = func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("no peer certificates")
+		}
+		return k.Verify(addr, cs.PeerCertificates[0])
+	}
+
+	tlsConn := tls.Client(conn, c)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
+
+// FetchFingerprint connects to addr and returns the fingerprint of its
+// certificate, without verifying it in any way.
+func FetchFingerprint(ctx context.Context, dialer *net.Dialer, addr string) (string, error) {
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	host, _, _ := net.SplitHostPort(addr)
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+	})
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return "", fmt.Errorf("TLS handshake: %w", err)
+	}
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return "", errors.New("no peer certificates")
+	}
+
+	return Fingerprint(certs[0]), nil
+}
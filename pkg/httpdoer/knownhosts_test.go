@@ -0,0 +1,62 @@
+package httpdoer
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKnownHosts(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "https://")
+
+	kh := NewKnownHosts(filepath.Join(t.TempDir(), "known_hosts"))
+	d, err := New(TransportParams{KnownHosts: kh})
+	if err != nil {
+		t.Fatalf("create doer: %v", err)
+	}
+	get := func() error {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		res, err := d.Do(req)
+		if err != nil {
+			return err
+		}
+		return res.Body.Close()
+	}
+
+	// trust on first use
+	if err := get(); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	fp, ok, err := kh.Lookup(host)
+	if err != nil || !ok {
+		t.Fatalf("expected host to be pinned; ok: %v, err: %v", ok, err)
+	}
+	if expected := Fingerprint(srv.Certificate()); fp != expected {
+		t.Fatalf("expected fingerprint %q, got %q", expected, fp)
+	}
+
+	// same certificate
+	if err := get(); err != nil {
+		t.Fatalf("second request: %v", err)
+	}
+
+	// changed certificate
+	if err := kh.Pin(host, "sha256/AAAA"); err != nil {
+		t.Fatalf("re-pin: %v", err)
+	}
+	d.(*http.Client).CloseIdleConnections()
+	var pinErr *PinMismatchError
+	if err := get(); !errors.As(err, &pinErr) {
+		t.Fatalf("expected *PinMismatchError, got %v", err)
+	}
+	if pinErr.Host != host || pinErr.Expected != "sha256/AAAA" || pinErr.Got != fp {
+		t.Fatalf("unexpected error data: %#v", pinErr)
+	}
+}
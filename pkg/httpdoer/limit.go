@@ -0,0 +1,130 @@
+package httpdoer
+
+import (
+	"cmp"
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const DefaultMaxConcurrent = 1
+
+// LimitParams configures a Limiter.
+type LimitParams struct {
+	// MaxConcurrent is the maximum number of in-flight requests to a single
+	// host. Defaults to DefaultMaxConcurrent.
+	MaxConcurrent int
+	// MinGap is the minimum time between the start of two consecutive requests
+	// to the same host. Zero means no gap.
+	MinGap time.Duration
+}
+
+// Limiter caps the number of concurrent requests to each host, and optionally
+// spaces them in time. Requests waiting for their turn are queued in arrival
+// order and leave the queue as soon as their context is done. A Limiter can be
+// shared by several HTTPDoers so that they all take turns with the same
+// device.
+type Limiter struct {
+	p     LimitParams
+	mu    sync.Mutex
+	hosts map[string]*hostLimiter
+}
+
+type hostLimiter struct {
+	sem  chan struct{}
+	mu   sync.Mutex
+	next time.Time // earliest start time for the next request
+}
+
+func NewLimiter(p LimitParams) *Limiter {
+	p.MaxConcurrent = cmp.Or(max(p.MaxConcurrent, 0), DefaultMaxConcurrent)
+	p.MinGap = max(p.MinGap, 0)
+	return &Limiter{
+		p:     p,
+		hosts: make(map[string]*hostLimiter),
+	}
+}
+
+func (l *Limiter) host(name string) *hostLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h := l.hosts[name]
+	if h == nil {
+		h = &hostLimiter{
+			sem: make(chan struct{}, l.p.MaxConcurrent),
+		}
+		l.hosts[name] = h
+	}
+	return h
+}
+
+// Acquire blocks until a request to the given host can be started, or until
+// the context is done. On success, the returned function must be called once
+// the request is finished.
+func (l *Limiter) Acquire(ctx context.Context, host string) (func(), error) {
+	h := l.host(host)
+
+	select {
+	case h.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release := func() { <-h.sem }
+
+	if l.p.MinGap > 0 {
+		h.mu.Lock()
+		start := time.Now()
+		if start.Before(h.next) {
+			start = h.next
+		}
+		h.next = start.Add(l.p.MinGap)
+		h.mu.Unlock()
+
+		if wait := time.Until(start); wait > 0 {
+			t := time.NewTimer(wait)
+			defer t.Stop()
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				release()
+				return nil, ctx.Err()
+			}
+		}
+	}
+
+	return release, nil
+}
+
+// Limit makes every request through d wait for its turn in l. The turn is
+// released when d returns, so d should fully read the response body (e.g. by
+// being wrapped in BufferBody) for the limit to be meaningful. For streamed
+// responses (see WithStreaming), the turn is released when the body is closed.
+func Limit(d HTTPDoer, l *Limiter) HTTPDoer {
+	return HTTPDoerFunc(func(req *http.Request) (*http.Response, error) {
+		release, err := l.Acquire(req.Context(), req.URL.Host)
+		if err != nil {
+			return nil, err
+		}
+		res, err := d.Do(req)
+		if err != nil || !IsStreaming(req.Context()) {
+			release()
+			return res, err
+		}
+		res.Body = &releaseOnClose{ReadCloser: res.Body, release: release}
+		return res, nil
+	})
+}
+
+type releaseOnClose struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (r *releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
@@ -0,0 +1,96 @@
+package httpdoer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimitMaxConcurrent(t *testing.T) {
+	t.Parallel()
+
+	const maxConcurrent, total = 2, 10
+	var inFlight, maxSeen atomic.Int32
+	d := Limit(HTTPDoerFunc(func(*http.Request) (*http.Response, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxSeen.Load()
+			if n <= m || maxSeen.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		return new(http.Response), nil
+	}), NewLimiter(LimitParams{MaxConcurrent: maxConcurrent}))
+
+	var wg sync.WaitGroup
+	for range total {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest(http.MethodGet, "https://device/", nil)
+			if _, err := d.Do(req); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := maxSeen.Load(); got != maxConcurrent {
+		t.Fatalf("expected at most %d concurrent requests, got %d",
+			maxConcurrent, got)
+	}
+}
+
+func TestLimitMinGap(t *testing.T) {
+	t.Parallel()
+
+	const gap = 20 * time.Millisecond
+	l := NewLimiter(LimitParams{MinGap: gap})
+	ctx := context.Background()
+
+	var starts []time.Time
+	for range 3 {
+		release, err := l.Acquire(ctx, "device")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		starts = append(starts, time.Now())
+		release()
+	}
+
+	for i := 1; i < len(starts); i++ {
+		if d := starts[i].Sub(starts[i-1]); d < gap {
+			t.Errorf("[#%d] expected a gap of at least %v, got %v", i, gap, d)
+		}
+	}
+}
+
+func TestLimitContextCancel(t *testing.T) {
+	t.Parallel()
+
+	l := NewLimiter(LimitParams{})
+	release, err := l.Acquire(context.Background(), "device")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer release()
+
+	// other hosts are not affected
+	release2, err := l.Acquire(context.Background(), "other")
+	if err != nil {
+		t.Fatalf("unexpected error acquiring other host: %v", err)
+	}
+	release2()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx, "device"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package httpdoer
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Error classes reported in RequestMetrics.
+const (
+	ErrorClassTimeout           = "timeout"
+	ErrorClassCanceled          = "canceled"
+	ErrorClassDNS               = "dns"
+	ErrorClassConnectionRefused = "connection_refused"
+	ErrorClassConnectionReset   = "connection_reset"
+	ErrorClassTLS               = "tls"
+	ErrorClassPinMismatch       = "pin_mismatch"
+	ErrorClassBodyTooLarge      = "body_too_large"
+	ErrorClassCircuitOpen       = "circuit_open"
+	ErrorClassOther             = "other"
+)
+
+// RequestMetrics describes a finished request.
+type RequestMetrics struct {
+	// Endpoint is the path of the request URL.
+	Endpoint string
+	Method   string
+	// StatusCode is zero if the request failed.
+	StatusCode int
+	Duration   time.Duration
+	// ResponseSize is the size of the response body, or -1 if unknown.
+	ResponseSize int64
+	// ErrorClass is empty if the request didn't fail. See ClassifyError.
+	ErrorClass string
+}
+
+// MetricsRecorder receives the metrics of each request. Implementations must be
+// safe for concurrent use.
+type MetricsRecorder interface {
+	RecordRequest(RequestMetrics)
+}
+
+// Metrics reports the metrics of every request made through d to r. To
+// measure the time taken by the device, d should buffer the body and Metrics
+// should be wrapped by Limit, so that neither the body reading is left out nor
+// the time waiting in queue is included.
+func Metrics(d HTTPDoer, r MetricsRecorder) HTTPDoer {
+	return HTTPDoerFunc(func(req *http.Request) (*http.Response, error) {
+		start := time.Now()
+		res, err := d.Do(req)
+		m := RequestMetrics{
+			Endpoint:     req.URL.Path,
+			Method:       req.Method,
+			Duration:     time.Since(start),
+			ResponseSize: -1,
+		}
+		if err != nil {
+			m.ErrorClass = ClassifyError(err)
+		} else {
+			m.StatusCode = res.StatusCode
+			m.ResponseSize = res.ContentLength
+			if b, ok := res.Body.(interface{ Len() int }); ok {
+				m.ResponseSize = int64(b.Len())
+			}
+		}
+		r.RecordRequest(m)
+		return res, err
+	})
+}
+
+// ClassifyError returns a short, low-cardinality description of the error,
+// suitable to be used as a metric label.
+func ClassifyError(err error) string {
+	var (
+		dnsErr     *net.DNSError
+		netErr     net.Error
+		tooLarge   *BodyTooLargeError
+		pinErr     *PinMismatchError
+		certErr    *tls.CertificateVerificationError
+		unknownCA  x509.UnknownAuthorityError
+		recordErr  tls.RecordHeaderError
+		alertErr   tls.AlertError
+		hostErr    x509.HostnameError
+		invalidErr x509.CertificateInvalidError
+	)
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, os.ErrDeadlineExceeded):
+		return ErrorClassTimeout
+	case errors.As(err, &dnsErr):
+		return ErrorClassDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorClassConnectionRefused
+	case errors.Is(err, syscall.ECONNRESET):
+		return ErrorClassConnectionReset
+	case errors.As(err, &pinErr):
+		return ErrorClassPinMismatch
+	case errors.As(err, &tooLarge):
+		return ErrorClassBodyTooLarge
+	case errors.Is(err, ErrCircuitOpen):
+		return ErrorClassCircuitOpen
+	case errors.As(err, &certErr), errors.As(err, &unknownCA),
+		errors.As(err, &recordErr), errors.As(err, &alertErr),
+		errors.As(err, &hostErr), errors.As(err, &invalidErr):
+		return ErrorClassTLS
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorClassTimeout
+	}
+
+	return ErrorClassOther
+}
@@ -0,0 +1,244 @@
+package httpdoer
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MetricsNamespace is the prefix of the metric names exposed by MemoryMetrics
+// in Prometheus format.
+const MetricsNamespace = "cga_http"
+
+var (
+	// DefaultLatencyBuckets are the upper bounds, in seconds, of the latency
+	// histogram buckets.
+	DefaultLatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+	// DefaultSizeBuckets are the upper bounds, in bytes, of the response size
+	// histogram buckets.
+	DefaultSizeBuckets = []float64{256, 1 << 10, 4 << 10, 16 << 10, 64 << 10,
+		256 << 10, 1 << 20}
+)
+
+// Histogram counts observations in buckets. Counts has one more element than
+// Buckets, for the observations greater than the last bucket. Counts are not
+// cumulative.
+type Histogram struct {
+	Buckets []float64 `json:"buckets"`
+	Counts  []uint64  `json:"counts"`
+	Sum     float64   `json:"sum"`
+	Count   uint64    `json:"count"`
+}
+
+func newHistogram(buckets []float64) Histogram {
+	return Histogram{
+		Buckets: buckets,
+		Counts:  make([]uint64, len(buckets)+1),
+	}
+}
+
+func (h *Histogram) observe(v float64) {
+	i, _ := slices.BinarySearch(h.Buckets, v)
+	h.Counts[i]++
+	h.Sum += v
+	h.Count++
+}
+
+func (h Histogram) clone() Histogram {
+	h.Counts = slices.Clone(h.Counts)
+	return h
+}
+
+// EndpointMetrics are the metrics aggregated for a method and endpoint.
+type EndpointMetrics struct {
+	Method       string            `json:"method"`
+	Endpoint     string            `json:"endpoint"`
+	Requests     uint64            `json:"requests"`
+	StatusCodes  map[int]uint64    `json:"status_codes"`
+	Errors       map[string]uint64 `json:"errors"`
+	Latency      Histogram         `json:"latency_seconds"`
+	ResponseSize Histogram         `json:"response_size_bytes"`
+}
+
+type endpointKey struct {
+	method, endpoint string
+}
+
+// MemoryMetrics is a MetricsRecorder that aggregates metrics in memory. It can
+// be published with expvar.Publish, since it implements expvar.Var, and it
+// serves the metrics in Prometheus text format as an http.Handler.
+type MemoryMetrics struct {
+	mu             sync.Mutex
+	endpoints      map[endpointKey]*EndpointMetrics
+	latencyBuckets []float64
+	sizeBuckets    []float64
+}
+
+// NewMemoryMetrics creates a *MemoryMetrics using the given buckets, or the
+// default ones if nil.
+func NewMemoryMetrics(latencyBuckets, sizeBuckets []float64) *MemoryMetrics {
+	if latencyBuckets == nil {
+		latencyBuckets = DefaultLatencyBuckets
+	}
+	if sizeBuckets == nil {
+		sizeBuckets = DefaultSizeBuckets
+	}
+	return &MemoryMetrics{
+		endpoints:      make(map[endpointKey]*EndpointMetrics),
+		latencyBuckets: slices.Sorted(slices.Values(latencyBuckets)),
+		sizeBuckets:    slices.Sorted(slices.Values(sizeBuckets)),
+	}
+}
+
+func (m *MemoryMetrics) RecordRequest(r RequestMetrics) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k := endpointKey{r.Method, r.Endpoint}
+	e := m.endpoints[k]
+	if e == nil {
+		e = &EndpointMetrics{
+			Method:       r.Method,
+			Endpoint:     r.Endpoint,
+			StatusCodes:  make(map[int]uint64),
+			Errors:       make(map[string]uint64),
+			Latency:      newHistogram(m.latencyBuckets),
+			ResponseSize: newHistogram(m.sizeBuckets),
+		}
+		m.endpoints[k] = e
+	}
+
+	e.Requests++
+	e.Latency.observe(r.Duration.Seconds())
+	if r.ErrorClass != "" {
+		e.Errors[r.ErrorClass]++
+		return
+	}
+	e.StatusCodes[r.StatusCode]++
+	if r.ResponseSize >= 0 {
+		e.ResponseSize.observe(float64(r.ResponseSize))
+	}
+}
+
+// Snapshot returns a copy of the current metrics, sorted by endpoint and
+// method.
+func (m *MemoryMetrics) Snapshot() []EndpointMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ret := make([]EndpointMetrics, 0, len(m.endpoints))
+	for _, e := range m.endpoints {
+		c := *e
+		c.StatusCodes = maps.Clone(e.StatusCodes)
+		c.Errors = maps.Clone(e.Errors)
+		c.Latency = e.Latency.clone()
+		c.ResponseSize = e.ResponseSize.clone()
+		ret = append(ret, c)
+	}
+	slices.SortFunc(ret, func(a, b EndpointMetrics) int {
+		return cmp.Or(cmp.Compare(a.Endpoint, b.Endpoint),
+			cmp.Compare(a.Method, b.Method))
+	})
+
+	return ret
+}
+
+// String returns the metrics as JSON, implementing expvar.Var.
+func (m *MemoryMetrics) String() string {
+	b, err := json.Marshal(m.Snapshot())
+	if err != nil {
+		return "null"
+	}
+	return string(b)
+}
+
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func promLabels(kv ...string) string {
+	b := new(strings.Builder)
+	b.WriteByte('{')
+	for i := 0; i < len(kv); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(b, `%s="%s"`, kv[i], promLabelEscaper.Replace(kv[i+1]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func promFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func writePromHistogram(w io.Writer, name string, h Histogram, kv ...string) {
+	var cumulative uint64
+	for i, le := range h.Buckets {
+		cumulative += h.Counts[i]
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name,
+			promLabels(append(kv, "le", promFloat(le))...), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket%s %d\n", name,
+		promLabels(append(kv, "le", "+Inf")...), h.Count)
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, promLabels(kv...), promFloat(h.Sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, promLabels(kv...), h.Count)
+}
+
+// WritePrometheus writes the metrics in Prometheus text exposition format.
+func (m *MemoryMetrics) WritePrometheus(w io.Writer) error {
+	snapshot := m.Snapshot()
+	bw := bufio.NewWriter(w)
+
+	name := MetricsNamespace + "_requests_total"
+	fmt.Fprintf(bw, "# HELP %s Requests with a response, by status code.\n", name)
+	fmt.Fprintf(bw, "# TYPE %s counter\n", name)
+	for _, e := range snapshot {
+		for _, code := range slices.Sorted(maps.Keys(e.StatusCodes)) {
+			fmt.Fprintf(bw, "%s%s %d\n", name, promLabels("method", e.Method,
+				"endpoint", e.Endpoint, "code", strconv.Itoa(code)),
+				e.StatusCodes[code])
+		}
+	}
+
+	name = MetricsNamespace + "_request_errors_total"
+	fmt.Fprintf(bw, "# HELP %s Requests without a response, by error class.\n", name)
+	fmt.Fprintf(bw, "# TYPE %s counter\n", name)
+	for _, e := range snapshot {
+		for _, class := range slices.Sorted(maps.Keys(e.Errors)) {
+			fmt.Fprintf(bw, "%s%s %d\n", name, promLabels("method", e.Method,
+				"endpoint", e.Endpoint, "class", class), e.Errors[class])
+		}
+	}
+
+	name = MetricsNamespace + "_request_duration_seconds"
+	fmt.Fprintf(bw, "# HELP %s Time taken by requests.\n", name)
+	fmt.Fprintf(bw, "# TYPE %s histogram\n", name)
+	for _, e := range snapshot {
+		writePromHistogram(bw, name, e.Latency, "method", e.Method,
+			"endpoint", e.Endpoint)
+	}
+
+	name = MetricsNamespace + "_response_size_bytes"
+	fmt.Fprintf(bw, "# HELP %s Size of response bodies.\n", name)
+	fmt.Fprintf(bw, "# TYPE %s histogram\n", name)
+	for _, e := range snapshot {
+		writePromHistogram(bw, name, e.ResponseSize, "method", e.Method,
+			"endpoint", e.Endpoint)
+	}
+
+	return bw.Flush()
+}
+
+// ServeHTTP serves the metrics in Prometheus text exposition format.
+func (m *MemoryMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set(HeaderNameContentType, "text/plain; version=0.0.4; charset=utf-8")
+	m.WritePrometheus(w)
+}
@@ -0,0 +1,89 @@
+package httpdoer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	m := NewMemoryMetrics(nil, nil)
+	var fail bool
+	d := Metrics(BufferBody(HTTPDoerFunc(func(req *http.Request) (*http.Response, error) {
+		if fail {
+			return nil, fmt.Errorf("wrapped: %w", context.DeadlineExceeded)
+		}
+		return stringResponder("hello").Do(req)
+	}), BufferParams{}), m)
+
+	do := func() {
+		req, _ := http.NewRequest(http.MethodGet, "https://device/api/v1/x", nil)
+		if res, err := d.Do(req); err == nil {
+			res.Body.Close()
+		}
+	}
+	do()
+	do()
+	fail = true
+	do()
+
+	snapshot := m.Snapshot()
+	if len(snapshot) != 1 {
+		t.Fatalf("expected 1 endpoint, got %d", len(snapshot))
+	}
+	e := snapshot[0]
+	if e.Endpoint != "/api/v1/x" || e.Method != http.MethodGet {
+		t.Fatalf("unexpected endpoint: %v %v", e.Method, e.Endpoint)
+	}
+	if e.Requests != 3 || e.StatusCodes[http.StatusOK] != 2 ||
+		e.Errors[ErrorClassTimeout] != 1 {
+		t.Fatalf("unexpected counts: %#v", e)
+	}
+	if e.ResponseSize.Count != 2 || e.ResponseSize.Sum != 10 {
+		t.Fatalf("unexpected response size histogram: %#v", e.ResponseSize)
+	}
+	if e.Latency.Count != 3 {
+		t.Fatalf("unexpected latency histogram: %#v", e.Latency)
+	}
+
+	b := new(strings.Builder)
+	if err := m.WritePrometheus(b); err != nil {
+		t.Fatalf("write prometheus: %v", err)
+	}
+	for _, line := range []string{
+		`cga_http_requests_total{method="GET",endpoint="/api/v1/x",code="200"} 2`,
+		`cga_http_request_errors_total{method="GET",endpoint="/api/v1/x",class="timeout"} 1`,
+		`cga_http_request_duration_seconds_count{method="GET",endpoint="/api/v1/x"} 3`,
+		`cga_http_response_size_bytes_bucket{method="GET",endpoint="/api/v1/x",le="256"} 2`,
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("missing line %q in output:\n%s", line, b.String())
+		}
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		err      error
+		expected string
+	}{
+		{context.Canceled, ErrorClassCanceled},
+		{fmt.Errorf("x: %w", context.DeadlineExceeded), ErrorClassTimeout},
+		{&BodyTooLargeError{}, ErrorClassBodyTooLarge},
+		{&PinMismatchError{}, ErrorClassPinMismatch},
+		{errors.New("something"), ErrorClassOther},
+	}
+
+	for i, tc := range testCases {
+		if got := ClassifyError(tc.err); got != tc.expected {
+			t.Errorf("[#%d] expected %q, got %q", i, tc.expected, got)
+		}
+	}
+}
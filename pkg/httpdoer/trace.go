@@ -0,0 +1,32 @@
+package httpdoer
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/trace"
+)
+
+// Trace creates a span with the given name for each request through d. The
+// span includes the util.ContextMeta values of the request context.
+func Trace(d HTTPDoer, t trace.Tracer, name string) HTTPDoer {
+	return HTTPDoerFunc(func(req *http.Request) (*http.Response, error) {
+		ctx, span := trace.StartWithMeta(req.Context(), t, name,
+			trace.String("http.method", req.Method),
+			trace.String("http.path", req.URL.Path),
+		)
+		defer span.End()
+
+		res, err := d.Do(req.WithContext(ctx))
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		span.SetAttributes(trace.Int("http.status_code", res.StatusCode))
+		if res.StatusCode >= http.StatusBadRequest {
+			span.RecordError(fmt.Errorf("HTTP status %s", res.Status))
+		}
+
+		return res, nil
+	})
+}
@@ -0,0 +1,244 @@
+package httpdoer
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"time"
+)
+
+const (
+	DefaultDialTimeout           = 10 * time.Second
+	DefaultTLSHandshakeTimeout   = 10 * time.Second
+	DefaultResponseHeaderTimeout = 30 * time.Second
+	DefaultKeepAlive             = 30 * time.Second
+	DefaultIdleConnTimeout       = 90 * time.Second
+	DefaultMaxIdleConnsPerHost   = 2
+
+	// ProxyDirect can be used as TransportParams.Proxy to not use any proxy,
+	// not even the ones configured in the environment.
+	ProxyDirect = "direct"
+)
+
+// TransportParams configures the transport created by NewTransport. For all
+// durations, zero means the default value and a negative value means no
+// timeout (or disabled, in the case of KeepAlive).
+type TransportParams struct {
+	// CAFile is the path to a PEM bundle with the CAs to trust, instead of the
+	// system ones.
+	CAFile string
+	// CAPEM is like CAFile, but with the contents of the PEM bundle. Both can
+	// be used at the same time.
+	CAPEM []byte
+	// InsecureSkipVerify disables TLS certificate verification, only for the
+	// transport being created.
+	InsecureSkipVerify bool
+	// KnownHosts enables trust-on-first-use certificate pinning. When set,
+	// certificates are verified against the pinned ones instead of against the
+	// CAs, and requests cannot go through a proxy.
+	KnownHosts *KnownHosts
+
+	DialTimeout           time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+
+	// KeepAlive is the interval between TCP keep-alive probes.
+	KeepAlive time.Duration
+	// IdleConnTimeout is the time an idle connection is kept in the pool.
+	IdleConnTimeout time.Duration
+	// MaxIdleConnsPerHost is the number of idle connections kept in the pool
+	// for each host. Negative keeps none, like DisableKeepAlives.
+	MaxIdleConnsPerHost int
+	// DisableKeepAlives makes each request use a new connection.
+	DisableKeepAlives bool
+
+	// Proxy is the URL of an HTTP, HTTPS, SOCKS5 or SOCKS5h proxy. If empty,
+	// the proxy is taken from the environment. Use ProxyDirect to not use any
+	// proxy at all.
+	Proxy string
+
+	// Interface is the name of the local network interface to send the
+	// requests from. Its first IPv4 address is used, or its first address if
+	// it has no IPv4 address.
+	Interface string
+	// LocalAddr is the source IP address to send the requests from. It cannot
+	// be used at the same time as Interface.
+	LocalAddr string
+}
+
+func durationOr(v, def time.Duration) time.Duration {
+	switch {
+	case v < 0:
+		return 0
+	case v == 0:
+		return def
+	}
+	return v
+}
+
+// NewTransport creates a new *http.Transport. Unlike modifying
+// http.DefaultTransport, the options only affect the returned value.
+func NewTransport(p TransportParams) (*http.Transport, error) {
+	tlsConfig, err := p.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := p.proxy()
+	if err != nil {
+		return nil, err
+	}
+	localAddr, err := p.localAddr()
+	if err != nil {
+		return nil, err
+	}
+
+	keepAlive := p.KeepAlive
+	if keepAlive == 0 {
+		keepAlive = DefaultKeepAlive
+	}
+	dialer := &net.Dialer{
+		Timeout:   durationOr(p.DialTimeout, DefaultDialTimeout),
+		KeepAlive: keepAlive, // negative disables keep-alive probes
+	}
+	if localAddr != nil {
+		dialer.LocalAddr = localAddr
+	}
+
+	maxIdleConnsPerHost := p.MaxIdleConnsPerHost
+	disableKeepAlives := p.DisableKeepAlives
+	switch {
+	case maxIdleConnsPerHost == 0:
+		maxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	case maxIdleConnsPerHost < 0:
+		// net/http would keep its default number for zero
+		maxIdleConnsPerHost = 0
+		disableKeepAlives = true
+	}
+
+	t := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   durationOr(p.TLSHandshakeTimeout, DefaultTLSHandshakeTimeout),
+		ResponseHeaderTimeout: durationOr(p.ResponseHeaderTimeout, DefaultResponseHeaderTimeout),
+		IdleConnTimeout:       durationOr(p.IdleConnTimeout, DefaultIdleConnTimeout),
+		MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+		DisableKeepAlives:     disableKeepAlives,
+		ForceAttemptHTTP2:     true,
+	}
+
+	if p.KnownHosts != nil {
+		t.Proxy = nil
+		t.ForceAttemptHTTP2 = false
+		t.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return p.KnownHosts.DialTLSContext(ctx, dialer, tlsConfig, network,
+				addr)
+		}
+	}
+
+	return t, nil
+}
+
+func (p TransportParams) tlsConfig() (*tls.Config, error) {
+	c := &tls.Config{
+		InsecureSkipVerify: p.InsecureSkipVerify,
+	}
+	if p.CAFile == "" && len(p.CAPEM) == 0 {
+		return c, nil
+	}
+
+	c.RootCAs = x509.NewCertPool()
+	if p.CAFile != "" {
+		b, err := os.ReadFile(p.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA file: %w", err)
+		}
+		if !c.RootCAs.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificates found in CA file %q",
+				p.CAFile)
+		}
+	}
+	if len(p.CAPEM) > 0 && !c.RootCAs.AppendCertsFromPEM(p.CAPEM) {
+		return nil, errors.New("no certificates found in CA PEM")
+	}
+
+	return c, nil
+}
+
+func (p TransportParams) proxy() (func(*http.Request) (*url.URL, error), error) {
+	if p.KnownHosts != nil && p.Proxy != "" && p.Proxy != ProxyDirect {
+		return nil, errors.New("cannot use a proxy with known hosts")
+	}
+
+	switch p.Proxy {
+	case "":
+		return http.ProxyFromEnvironment, nil
+	case ProxyDirect:
+		return nil, nil
+	}
+
+	u, err := url.Parse(p.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("parse proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+
+	return http.ProxyURL(u), nil
+}
+
+func (p TransportParams) localAddr() (net.Addr, error) {
+	if p.Interface != "" && p.LocalAddr != "" {
+		return nil, errors.New("cannot use both Interface and LocalAddr")
+	}
+
+	if p.LocalAddr != "" {
+		ip, err := netip.ParseAddr(p.LocalAddr)
+		if err != nil {
+			return nil, fmt.Errorf("parse local address: %w", err)
+		}
+		return net.TCPAddrFromAddrPort(netip.AddrPortFrom(ip, 0)), nil
+	}
+
+	if p.Interface != "" {
+		iface, err := net.InterfaceByName(p.Interface)
+		if err != nil {
+			return nil, fmt.Errorf("find interface: %w", err)
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("list addresses of interface %q: %w",
+				p.Interface, err)
+		}
+		var first net.IP
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			if ipNet.IP.To4() != nil {
+				return &net.TCPAddr{IP: ipNet.IP}, nil
+			}
+			if first == nil {
+				first = ipNet.IP
+			}
+		}
+		if first == nil {
+			return nil, fmt.Errorf("interface %q has no IP addresses",
+				p.Interface)
+		}
+		return &net.TCPAddr{IP: first}, nil
+	}
+
+	return nil, nil
+}
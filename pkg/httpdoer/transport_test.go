@@ -0,0 +1,113 @@
+package httpdoer
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNewTransport(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		params  TransportParams
+		wantErr bool
+		check   func(*http.Transport) bool
+	}{
+		{
+			name:   "defaults",
+			params: TransportParams{},
+			check: func(tr *http.Transport) bool {
+				return !tr.TLSClientConfig.InsecureSkipVerify &&
+					tr.ResponseHeaderTimeout == DefaultResponseHeaderTimeout &&
+					tr.Proxy != nil
+			},
+		},
+		{
+			name: "insecure and no timeout",
+			params: TransportParams{
+				InsecureSkipVerify:    true,
+				ResponseHeaderTimeout: -1,
+				Proxy:                 ProxyDirect,
+			},
+			check: func(tr *http.Transport) bool {
+				return tr.TLSClientConfig.InsecureSkipVerify &&
+					tr.ResponseHeaderTimeout == 0 && tr.Proxy == nil
+			},
+		},
+		{
+			name:   "custom timeout",
+			params: TransportParams{TLSHandshakeTimeout: time.Second},
+			check: func(tr *http.Transport) bool {
+				return tr.TLSHandshakeTimeout == time.Second
+			},
+		},
+		{
+			name:   "no idle connections",
+			params: TransportParams{MaxIdleConnsPerHost: -1},
+			check: func(tr *http.Transport) bool {
+				return tr.DisableKeepAlives && tr.MaxIdleConnsPerHost == 0
+			},
+		},
+		{
+			name:   "socks5 proxy",
+			params: TransportParams{Proxy: "socks5://127.0.0.1:1080"},
+			check: func(tr *http.Transport) bool {
+				return tr.Proxy != nil
+			},
+		},
+		{
+			name:    "unsupported proxy",
+			params:  TransportParams{Proxy: "ftp://127.0.0.1"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid local address",
+			params:  TransportParams{LocalAddr: "not-an-ip"},
+			wantErr: true,
+		},
+		{
+			name:    "interface and local address",
+			params:  TransportParams{LocalAddr: "127.0.0.1", Interface: "lo"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid CA PEM",
+			params:  TransportParams{CAPEM: []byte("garbage")},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tr, err := NewTransport(tc.params)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tc.check(tr) {
+				t.Fatalf("unexpected transport: %#v", tr)
+			}
+		})
+	}
+}
+
+func TestNewTransportDoesNotModifyDefault(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewTransport(TransportParams{InsecureSkipVerify: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dt := http.DefaultTransport.(*http.Transport)
+	if dt.TLSClientConfig != nil && dt.TLSClientConfig.InsecureSkipVerify {
+		t.Fatalf("http.DefaultTransport was modified")
+	}
+}
@@ -0,0 +1,428 @@
+// Package mqttbridge periodically publishes the state of the device to an MQTT
+// broker, using the Home Assistant MQTT discovery topics, and performs the
+// commands received in command topics.
+//
+// All the topics are under "<TopicPrefix>/<NodeID>":
+//
+//	availability          "online" or "offline", retained
+//	status                device identification and uptime, as JSON
+//	channels              DOCSIS channel quality summary, as JSON
+//	hosts                 connected hosts, as JSON
+//	guest_wifi            "ON" or "OFF"
+//	guest_wifi/set        command: "ON" or "OFF"
+//	reboot/set            command: "PRESS" reboots the device
+//	block/<mac>           "ON" or "OFF", retained
+//	block/<mac>/set       command: "ON" blocks the host, "OFF" unblocks it
+//
+// MAC addresses in topics are lowercase hexadecimal without separators.
+// Retained commands are ignored, since they would be performed again on every
+// reconnection.
+package mqttbridge
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+)
+
+const (
+	DefaultInterval        = time.Minute
+	DefaultTopicPrefix     = "cga"
+	DefaultDiscoveryPrefix = "homeassistant"
+	DefaultNodeID          = "cga4233"
+
+	PayloadOnline  = "online"
+	PayloadOffline = "offline"
+
+	// payloadPress is the payload of the Home Assistant buttons.
+	payloadPress = "PRESS"
+
+	commandQueueSize = 16
+	offlineTimeout   = 5 * time.Second
+)
+
+// MQTT is the MQTT client used by the bridge. See package pahomqtt for an
+// implementation.
+type MQTT interface {
+	Publish(ctx context.Context, topic string, payload []byte, retain bool) error
+	// Subscribe calls h for each message received in the topics matching
+	// the filter, and whether it was a retained message. h must not block.
+	Subscribe(ctx context.Context, filter string, h func(topic string, payload []byte, retained bool)) error
+}
+
+// Params configures a Bridge.
+type Params struct {
+	Client client.Client
+	MQTT   MQTT
+	// Interval is the time between state updates. Defaults to
+	// DefaultInterval.
+	Interval time.Duration
+	// TopicPrefix is the prefix of the state and command topics. Defaults to
+	// DefaultTopicPrefix.
+	TopicPrefix string
+	// DiscoveryPrefix is the Home Assistant discovery prefix. Defaults to
+	// DefaultDiscoveryPrefix.
+	DiscoveryPrefix string
+	// NodeID identifies the device in the topics and in Home Assistant.
+	// Defaults to DefaultNodeID.
+	NodeID string
+	// OnError, if set, is called with the errors that don't stop the bridge,
+	// like failing to poll the device.
+	OnError func(error)
+}
+
+func (p Params) WithDefaults() Params {
+	if p.Interval <= 0 {
+		p.Interval = DefaultInterval
+	}
+	p.TopicPrefix = cmp.Or(p.TopicPrefix, DefaultTopicPrefix)
+	p.DiscoveryPrefix = cmp.Or(p.DiscoveryPrefix, DefaultDiscoveryPrefix)
+	p.NodeID = cmp.Or(p.NodeID, DefaultNodeID)
+	if p.OnError == nil {
+		p.OnError = func(error) {}
+	}
+	return p
+}
+
+// AvailabilityTopic is the topic where the bridge publishes whether it is
+// online. Use it as the MQTT last will topic, with PayloadOffline.
+func (p Params) AvailabilityTopic() string {
+	return p.WithDefaults().topic("availability")
+}
+
+func (p Params) topic(parts ...string) string {
+	return p.TopicPrefix + "/" + p.NodeID + "/" + strings.Join(parts, "/")
+}
+
+// Bridge publishes the state of the device to MQTT.
+type Bridge struct {
+	p    Params
+	cmds chan command
+
+	// announced are the hosts whose block switch was already announced
+	announced map[string]bool
+}
+
+type command struct {
+	name    string // reboot, guest_wifi or block
+	mac     string // for block
+	payload string
+}
+
+func New(p Params) (*Bridge, error) {
+	p = p.WithDefaults()
+	if p.Client == nil {
+		return nil, errors.New("no client")
+	}
+	if p.MQTT == nil {
+		return nil, errors.New("no MQTT client")
+	}
+	return &Bridge{
+		p:         p,
+		cmds:      make(chan command, commandQueueSize),
+		announced: make(map[string]bool),
+	}, nil
+}
+
+// Run publishes the state of the device until the context is canceled.
+// Commands are performed between state updates, so that the device sees a
+// single request at a time.
+func (b *Bridge) Run(ctx context.Context) error {
+	if err := b.p.MQTT.Subscribe(ctx, b.p.topic("+", "set"), b.onMessage); err != nil {
+		return fmt.Errorf("subscribe to commands: %w", err)
+	}
+	if err := b.p.MQTT.Subscribe(ctx, b.p.topic("block", "+", "set"), b.onMessage); err != nil {
+		return fmt.Errorf("subscribe to block commands: %w", err)
+	}
+
+	if err := b.publishDiscovery(ctx); err != nil {
+		return fmt.Errorf("publish discovery: %w", err)
+	}
+	if err := b.publishString(ctx, "availability", PayloadOnline, true); err != nil {
+		return fmt.Errorf("publish availability: %w", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx),
+			offlineTimeout)
+		defer cancel()
+		if err := b.publishString(ctx, "availability", PayloadOffline, true); err != nil {
+			b.p.OnError(fmt.Errorf("publish availability: %w", err))
+		}
+	}()
+
+	ticker := time.NewTicker(b.p.Interval)
+	defer ticker.Stop()
+	b.poll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			b.poll(ctx)
+		case cmd := <-b.cmds:
+			if err := b.handle(ctx, cmd); err != nil {
+				b.p.OnError(fmt.Errorf("command %s: %w", cmd.name, err))
+			}
+		}
+	}
+}
+
+func (b *Bridge) onMessage(topic string, payload []byte, retained bool) {
+	rest, ok := strings.CutPrefix(topic, b.p.topic())
+	if !ok {
+		return
+	}
+	if retained {
+		b.p.OnError(fmt.Errorf("retained command in %s ignored", topic))
+		return
+	}
+	parts := strings.Split(rest, "/")
+
+	var cmd command
+	switch {
+	case len(parts) == 2 && parts[1] == "set" &&
+		(parts[0] == "reboot" || parts[0] == "guest_wifi"):
+		cmd = command{name: parts[0]}
+	case len(parts) == 3 && parts[0] == "block" && parts[2] == "set":
+		cmd = command{name: "block", mac: parts[1]}
+	default:
+		return
+	}
+	cmd.payload = strings.TrimSpace(string(payload))
+
+	select {
+	case b.cmds <- cmd:
+	default:
+		b.p.OnError(fmt.Errorf("command %s dropped: queue full", cmd.name))
+	}
+}
+
+func parseSwitch(payload string) (bool, error) {
+	switch strings.ToUpper(payload) {
+	case "ON":
+		return true, nil
+	case "OFF":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid payload %q, expected ON or OFF", payload)
+}
+
+func switchPayload(on bool) string {
+	if on {
+		return "ON"
+	}
+	return "OFF"
+}
+
+func (b *Bridge) handle(ctx context.Context, cmd command) error {
+	switch cmd.name {
+	case "reboot":
+		if cmd.payload != payloadPress {
+			return fmt.Errorf("invalid payload %q, expected %s", cmd.payload,
+				payloadPress)
+		}
+		return b.p.Client.Reboot(ctx)
+
+	case "guest_wifi":
+		on, err := parseSwitch(cmd.payload)
+		if err != nil {
+			return err
+		}
+		if err := b.p.Client.SetGuestWiFi(ctx, on); err != nil {
+			return err
+		}
+		return b.publishString(ctx, "guest_wifi", switchPayload(on), false)
+
+	case "block":
+		mac, err := client.NormalizeMAC(cmd.mac)
+		if err != nil {
+			return err
+		}
+		on, err := parseSwitch(cmd.payload)
+		if err != nil {
+			return err
+		}
+		if err := b.p.Client.BlockDevice(ctx, mac, on); err != nil {
+			return err
+		}
+		return b.publishString(ctx, "block/"+cmd.mac, switchPayload(on), true)
+	}
+
+	return fmt.Errorf("unknown command %q", cmd.name)
+}
+
+// macTopic returns the MAC address in the form used in topics.
+func macTopic(mac string) string {
+	mac, err := client.NormalizeMAC(mac)
+	if err != nil {
+		return ""
+	}
+	return strings.ReplaceAll(mac, ":", "")
+}
+
+// poll publishes the state of the device. Each part is published
+// independently, so that an unsupported feature doesn't prevent the rest from
+// being published.
+func (b *Bridge) poll(ctx context.Context) {
+	for _, part := range []struct {
+		name    string
+		publish func(context.Context) error
+	}{
+		{"status", b.publishStatus},
+		{"channels", b.publishChannels},
+		{"hosts", b.publishHosts},
+		{"block", b.publishBlocks},
+		{"guest_wifi", b.publishGuestWiFi},
+		{"pauses", b.expirePauses},
+	} {
+		if err := part.publish(ctx); err != nil && ctx.Err() == nil {
+			b.p.OnError(fmt.Errorf("publish %s: %w", part.name, err))
+		}
+	}
+}
+
+func (b *Bridge) publishString(ctx context.Context, topic, payload string, retain bool) error {
+	return b.p.MQTT.Publish(ctx, b.p.topic(topic), []byte(payload), retain)
+}
+
+func (b *Bridge) publishJSON(ctx context.Context, topic string, v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.p.MQTT.Publish(ctx, b.p.topic(topic), payload, false)
+}
+
+type statusPayload struct {
+	Model    string `json:"model"`
+	Hardware string `json:"hardware"`
+	Firmware string `json:"firmware"`
+	Serial   string `json:"serial"`
+	Uptime   int64  `json:"uptime"`
+}
+
+func (b *Bridge) publishStatus(ctx context.Context) error {
+	info, err := b.p.Client.DeviceInfo(ctx)
+	if err != nil {
+		return err
+	}
+	return b.publishJSON(ctx, "status", statusPayload{
+		Model:    info.Model,
+		Hardware: info.HardwareVersion,
+		Firmware: info.FirmwareVersion.String(),
+		Serial:   info.SerialNumber,
+		Uptime:   int64(info.CurrentUptime() / time.Second),
+	})
+}
+
+func (b *Bridge) publishChannels(ctx context.Context) error {
+	ch, err := b.p.Client.Channels(ctx)
+	if err != nil {
+		return err
+	}
+	return b.publishJSON(ctx, "channels", summarizeChannels(ch))
+}
+
+type hostPayload struct {
+	MAC       string `json:"mac"`
+	IP        string `json:"ip,omitempty"`
+	Hostname  string `json:"hostname,omitempty"`
+	Interface string `json:"interface,omitempty"`
+}
+
+type hostsPayload struct {
+	Active int           `json:"active"`
+	Total  int           `json:"total"`
+	Hosts  []hostPayload `json:"hosts"`
+}
+
+func (b *Bridge) publishHosts(ctx context.Context) error {
+	hosts, err := b.p.Client.Hosts(ctx)
+	if err != nil {
+		return err
+	}
+
+	payload := hostsPayload{
+		Total: len(hosts),
+		Hosts: []hostPayload{},
+	}
+	for _, h := range hosts {
+		if err := b.announceHost(ctx, h); err != nil {
+			return err
+		}
+		if !h.Active {
+			continue
+		}
+		payload.Active++
+		payload.Hosts = append(payload.Hosts, hostPayload{
+			MAC:       h.MAC,
+			IP:        h.IP,
+			Hostname:  h.Hostname,
+			Interface: h.Interface,
+		})
+	}
+
+	return b.publishJSON(ctx, "hosts", payload)
+}
+
+func (b *Bridge) publishGuestWiFi(ctx context.Context) error {
+	networks, err := b.p.Client.WiFi(ctx)
+	if err != nil {
+		return err
+	}
+	var found, on bool
+	for _, n := range networks {
+		if n.Guest {
+			found = true
+			on = on || n.Enabled
+		}
+	}
+	if !found {
+		return nil
+	}
+	return b.publishString(ctx, "guest_wifi", switchPayload(on), false)
+}
+
+// expirePauses removes the access rules of the pauses that ended, which would
+// apply again the next week. It publishes nothing.
+func (b *Bridge) expirePauses(ctx context.Context) error {
+	err := b.p.Client.ExpirePauses(ctx)
+	if errors.Is(err, client.ErrUnsupported) {
+		return nil
+	}
+	return err
+}
+
+// publishBlocks publishes the state of the block switches of the announced
+// hosts, from the access rules that always block.
+func (b *Bridge) publishBlocks(ctx context.Context) error {
+	if len(b.announced) == 0 {
+		return nil
+	}
+	rules, err := b.p.Client.AccessRules(ctx)
+	if err != nil {
+		return err
+	}
+	blocked := make(map[string]bool)
+	for _, r := range rules {
+		if r.Blocks() {
+			blocked[macTopic(r.MAC)] = true
+		}
+	}
+	for _, mac := range slices.Sorted(maps.Keys(b.announced)) {
+		err := b.publishString(ctx, "block/"+mac, switchPayload(blocked[mac]),
+			true)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
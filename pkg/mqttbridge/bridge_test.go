@@ -0,0 +1,266 @@
+package mqttbridge
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	server "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/mqttbridge/pahomqtt"
+)
+
+type fakeClient struct {
+	client.Client // unimplemented methods panic
+
+	mu          sync.Mutex
+	blocked     map[string]bool
+	reboots     chan struct{}
+	expirations int
+}
+
+func (c *fakeClient) DeviceInfo(context.Context) (*client.DeviceInfo, error) {
+	return &client.DeviceInfo{
+		Model:           "CGA4233TCH3",
+		FirmwareVersion: client.ParseFirmwareVersion("CGA4233TCH3-1.0.26-TCH"),
+		Uptime:          time.Hour,
+		FetchedAt:       time.Now(),
+	}, nil
+}
+
+func (c *fakeClient) Channels(context.Context) (*client.Channels, error) {
+	return &client.Channels{
+		Downstream: []client.DownstreamChannel{
+			{Power: 2, SNR: 40, Locked: true, Corrected: 3},
+			{Power: 4, SNR: 38, Locked: true, Uncorrectable: 1},
+			{Power: 50, Locked: false},
+		},
+		Upstream: []client.UpstreamChannel{{Power: 45, Locked: true}},
+	}, nil
+}
+
+func (c *fakeClient) Hosts(context.Context) ([]client.Host, error) {
+	return []client.Host{
+		{MAC: "00:11:22:33:44:55", Hostname: "laptop", Active: true},
+		{MAC: "00:11:22:33:44:66"},
+	}, nil
+}
+
+func (c *fakeClient) WiFi(context.Context) ([]client.WiFiNetwork, error) {
+	return []client.WiFiNetwork{{SSID: "guest", Guest: true}}, nil
+}
+
+func (c *fakeClient) BlockDevice(_ context.Context, mac string, block bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.blocked[mac] = block
+	return nil
+}
+
+func (c *fakeClient) AccessRules(context.Context) ([]client.AccessRule, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var ret []client.AccessRule
+	for mac, blocked := range c.blocked {
+		if blocked {
+			ret = append(ret, client.AccessRule{MAC: mac, Enabled: true, Always: true})
+		}
+	}
+	return ret, nil
+}
+
+func (c *fakeClient) ExpirePauses(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expirations++
+	return nil
+}
+
+func (c *fakeClient) Reboot(context.Context) error {
+	c.reboots <- struct{}{}
+	return nil
+}
+
+func (c *fakeClient) isBlocked(mac string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.blocked[mac]
+}
+
+// startBroker starts an embedded broker and returns its address.
+func startBroker(t *testing.T) (*server.Server, string) {
+	t.Helper()
+	srv := server.New(&server.Options{
+		InlineClient: true,
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err := srv.AddHook(new(auth.AllowHook), nil); err != nil {
+		t.Fatalf("add auth hook: %v", err)
+	}
+	l := listeners.NewTCP(listeners.Config{ID: "tcp", Address: "127.0.0.1:0"})
+	if err := srv.AddListener(l); err != nil {
+		t.Fatalf("add listener: %v", err)
+	}
+	if err := srv.Serve(); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	return srv, l.Address()
+}
+
+func TestBridge(t *testing.T) {
+	srv, addr := startBroker(t)
+
+	msgs := make(chan packets.Packet, 100)
+	err := srv.Subscribe("#", 1, func(_ *server.Client, _ packets.Subscription, pk packets.Packet) {
+		msgs <- pk
+	})
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	await := func(topic string) []byte {
+		t.Helper()
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case pk := <-msgs:
+				if pk.TopicName == topic {
+					return pk.Payload
+				}
+			case <-timeout:
+				t.Fatalf("timeout waiting for %s", topic)
+			}
+		}
+	}
+
+	// a retained command is never performed
+	if err := srv.Publish("cga/test/reboot/set", []byte("PRESS"), true, 1); err != nil {
+		t.Fatalf("publish retained command: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	mc, err := pahomqtt.Connect(ctx, mqtt.NewClientOptions().
+		AddBroker("tcp://"+addr).
+		SetClientID("bridge"))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer mc.Disconnect(0)
+
+	c := &fakeClient{
+		blocked: map[string]bool{"00:11:22:33:44:66": true},
+		reboots: make(chan struct{}, 1),
+	}
+	errs := make(chan error, 10)
+	b, err := New(Params{
+		Client:  c,
+		MQTT:    mc,
+		NodeID:  "test",
+		OnError: func(err error) { errs <- err },
+	})
+	if err != nil {
+		t.Fatalf("create bridge: %v", err)
+	}
+	runCtx, stop := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() { done <- b.Run(runCtx) }()
+
+	var cfg discoveryConfig
+	if err := json.Unmarshal(await("homeassistant/sensor/test/uptime/config"), &cfg); err != nil {
+		t.Fatalf("decode discovery config: %v", err)
+	}
+	if cfg.StateTopic != "cga/test/status" || cfg.Device.Model != "CGA4233TCH3" {
+		t.Fatalf("unexpected discovery config: %#v", cfg)
+	}
+
+	var summary channelSummary
+	if err := json.Unmarshal(await("cga/test/channels"), &summary); err != nil {
+		t.Fatalf("decode channels: %v", err)
+	}
+	if summary.DownstreamLocked != 2 || summary.DownstreamPowerAvg != 3 ||
+		summary.DownstreamSNRMin != 38 || summary.Uncorrectable != 1 {
+		t.Fatalf("unexpected channel summary: %#v", summary)
+	}
+
+	await("homeassistant/switch/test/block_001122334455/config")
+	var hosts hostsPayload
+	if err := json.Unmarshal(await("cga/test/hosts"), &hosts); err != nil {
+		t.Fatalf("decode hosts: %v", err)
+	}
+	if hosts.Active != 1 || hosts.Total != 2 || hosts.Hosts[0].Hostname != "laptop" {
+		t.Fatalf("unexpected hosts: %#v", hosts)
+	}
+	if got := string(await("cga/test/block/001122334455")); got != "OFF" {
+		t.Fatalf("expected initial block state OFF, got %q", got)
+	}
+	if got := string(await("cga/test/block/001122334466")); got != "ON" {
+		t.Fatalf("expected initial block state ON, got %q", got)
+	}
+	if got := string(await("cga/test/guest_wifi")); got != "OFF" {
+		t.Fatalf("expected guest Wi-Fi OFF, got %q", got)
+	}
+
+	if err := srv.Publish("cga/test/block/001122334455/set", []byte("ON"), false, 1); err != nil {
+		t.Fatalf("publish command: %v", err)
+	}
+	if got := string(await("cga/test/block/001122334455")); got != "ON" {
+		t.Fatalf("expected block state ON, got %q", got)
+	}
+	if !c.isBlocked("00:11:22:33:44:55") {
+		t.Fatalf("expected host to be blocked")
+	}
+
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "retained") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-c.reboots:
+		t.Fatalf("retained reboot command performed")
+	case <-ctx.Done():
+		t.Fatalf("timeout waiting for the retained command")
+	}
+	if err := srv.Publish("cga/test/reboot/set", []byte("1"), false, 1); err != nil {
+		t.Fatalf("publish command: %v", err)
+	}
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "invalid payload") {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-ctx.Done():
+		t.Fatalf("timeout waiting for the invalid command")
+	}
+	if err := srv.Publish("cga/test/reboot/set", []byte("PRESS"), false, 1); err != nil {
+		t.Fatalf("publish command: %v", err)
+	}
+	select {
+	case <-c.reboots:
+	case <-ctx.Done():
+		t.Fatalf("timeout waiting for the reboot")
+	}
+
+	stop()
+	if err := <-done; err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	c.mu.Lock()
+	if c.expirations == 0 {
+		t.Errorf("expected the pauses to be expired in each poll")
+	}
+	c.mu.Unlock()
+	if got := string(await("cga/test/availability")); got != PayloadOffline {
+		t.Fatalf("expected offline availability, got %q", got)
+	}
+}
@@ -0,0 +1,232 @@
+package mqttbridge
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+)
+
+// discoveryConfig is the payload of a Home Assistant MQTT discovery topic.
+type discoveryConfig struct {
+	Name                   string          `json:"name"`
+	UniqueID               string          `json:"unique_id"`
+	ObjectID               string          `json:"object_id"`
+	StateTopic             string          `json:"state_topic,omitempty"`
+	CommandTopic           string          `json:"command_topic,omitempty"`
+	PayloadPress           string          `json:"payload_press,omitempty"`
+	ValueTemplate          string          `json:"value_template,omitempty"`
+	JSONAttributesTopic    string          `json:"json_attributes_topic,omitempty"`
+	JSONAttributesTemplate string          `json:"json_attributes_template,omitempty"`
+	UnitOfMeasurement      string          `json:"unit_of_measurement,omitempty"`
+	DeviceClass            string          `json:"device_class,omitempty"`
+	StateClass             string          `json:"state_class,omitempty"`
+	EntityCategory         string          `json:"entity_category,omitempty"`
+	Icon                   string          `json:"icon,omitempty"`
+	AvailabilityTopic      string          `json:"availability_topic"`
+	Device                 discoveryDevice `json:"device"`
+}
+
+type discoveryDevice struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+	Model        string   `json:"model,omitempty"`
+	HWVersion    string   `json:"hw_version,omitempty"`
+	SWVersion    string   `json:"sw_version,omitempty"`
+}
+
+// entity is a Home Assistant entity announced by the bridge.
+type entity struct {
+	component string // sensor, switch or button
+	id        string
+	config    discoveryConfig
+}
+
+func (b *Bridge) device(ctx context.Context) discoveryDevice {
+	d := discoveryDevice{
+		Identifiers:  []string{b.p.NodeID},
+		Name:         "Cable modem",
+		Manufacturer: "Technicolor",
+	}
+	if info, err := b.p.Client.DeviceInfo(ctx); err == nil {
+		d.Model = info.Model
+		d.HWVersion = info.HardwareVersion
+		d.SWVersion = info.FirmwareVersion.String()
+	}
+	return d
+}
+
+func (b *Bridge) entities() []entity {
+	sensor := func(id, name, topic, template, unit string) entity {
+		return entity{"sensor", id, discoveryConfig{
+			Name:              name,
+			StateTopic:        b.p.topic(topic),
+			ValueTemplate:     template,
+			UnitOfMeasurement: unit,
+			StateClass:        "measurement",
+		}}
+	}
+
+	uptime := sensor("uptime", "Uptime", "status", "{{ value_json.uptime }}", "s")
+	uptime.config.DeviceClass = "duration"
+	uptime.config.EntityCategory = "diagnostic"
+	firmware := sensor("firmware", "Firmware", "status", "{{ value_json.firmware }}", "")
+	firmware.config.StateClass = ""
+	firmware.config.EntityCategory = "diagnostic"
+	corrected := sensor("corrected", "Corrected codewords", "channels",
+		"{{ value_json.corrected }}", "")
+	corrected.config.StateClass = "total_increasing"
+	uncorrectable := sensor("uncorrectable", "Uncorrectable codewords",
+		"channels", "{{ value_json.uncorrectable }}", "")
+	uncorrectable.config.StateClass = "total_increasing"
+	hosts := sensor("connected_hosts", "Connected hosts", "hosts",
+		"{{ value_json.active }}", "")
+	hosts.config.JSONAttributesTopic = b.p.topic("hosts")
+	hosts.config.JSONAttributesTemplate = "{{ {'hosts': value_json.hosts} | tojson }}"
+	hosts.config.Icon = "mdi:lan-connect"
+
+	return []entity{
+		uptime,
+		firmware,
+		sensor("downstream_locked", "Downstream channels locked", "channels",
+			"{{ value_json.downstream_locked }}", ""),
+		sensor("upstream_locked", "Upstream channels locked", "channels",
+			"{{ value_json.upstream_locked }}", ""),
+		sensor("downstream_power", "Downstream power", "channels",
+			"{{ value_json.downstream_power_avg }}", "dBmV"),
+		sensor("downstream_snr", "Downstream SNR (min)", "channels",
+			"{{ value_json.downstream_snr_min }}", "dB"),
+		sensor("upstream_power", "Upstream power", "channels",
+			"{{ value_json.upstream_power_avg }}", "dBmV"),
+		corrected,
+		uncorrectable,
+		hosts,
+		{"switch", "guest_wifi", discoveryConfig{
+			Name:         "Guest Wi-Fi",
+			StateTopic:   b.p.topic("guest_wifi"),
+			CommandTopic: b.p.topic("guest_wifi", "set"),
+			Icon:         "mdi:wifi",
+		}},
+		{"button", "reboot", discoveryConfig{
+			Name:         "Reboot",
+			CommandTopic: b.p.topic("reboot", "set"),
+			PayloadPress: payloadPress,
+			DeviceClass:  "restart",
+		}},
+	}
+}
+
+func (b *Bridge) publishEntity(ctx context.Context, e entity, d discoveryDevice) error {
+	e.config.UniqueID = b.p.NodeID + "_" + e.id
+	e.config.ObjectID = e.config.UniqueID
+	e.config.AvailabilityTopic = b.p.topic("availability")
+	e.config.Device = d
+
+	payload, err := json.Marshal(e.config)
+	if err != nil {
+		return err
+	}
+	topic := b.p.DiscoveryPrefix + "/" + e.component + "/" + b.p.NodeID +
+		"/" + e.id + "/config"
+	return b.p.MQTT.Publish(ctx, topic, payload, true)
+}
+
+func (b *Bridge) publishDiscovery(ctx context.Context) error {
+	d := b.device(ctx)
+	for _, e := range b.entities() {
+		if err := b.publishEntity(ctx, e, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// announceHost announces the block switch of a host, once.
+func (b *Bridge) announceHost(ctx context.Context, h client.Host) error {
+	mac := macTopic(h.MAC)
+	if mac == "" || b.announced[mac] {
+		return nil
+	}
+
+	name := h.Hostname
+	if name == "" {
+		name = h.MAC
+	}
+	e := entity{"switch", "block_" + mac, discoveryConfig{
+		Name:         "Block " + name,
+		StateTopic:   b.p.topic("block", mac),
+		CommandTopic: b.p.topic("block", mac, "set"),
+		Icon:         "mdi:lan-disconnect",
+	}}
+	if err := b.publishEntity(ctx, e, b.device(ctx)); err != nil {
+		return err
+	}
+	b.announced[mac] = true
+
+	return nil
+}
+
+// channelSummary is the payload of the channels topic.
+type channelSummary struct {
+	DownstreamLocked   int     `json:"downstream_locked"`
+	UpstreamLocked     int     `json:"upstream_locked"`
+	DownstreamPowerMin float64 `json:"downstream_power_min"`
+	DownstreamPowerMax float64 `json:"downstream_power_max"`
+	DownstreamPowerAvg float64 `json:"downstream_power_avg"`
+	DownstreamSNRMin   float64 `json:"downstream_snr_min"`
+	DownstreamSNRAvg   float64 `json:"downstream_snr_avg"`
+	UpstreamPowerMax   float64 `json:"upstream_power_max"`
+	UpstreamPowerAvg   float64 `json:"upstream_power_avg"`
+	Corrected          int64   `json:"corrected"`
+	Uncorrectable      int64   `json:"uncorrectable"`
+}
+
+// summarizeChannels summarizes the quality of the locked channels.
+func summarizeChannels(ch *client.Channels) channelSummary {
+	var s channelSummary
+	var dsPower, dsSNR, usPower float64
+
+	s.DownstreamPowerMin, s.DownstreamSNRMin = math.Inf(1), math.Inf(1)
+	s.DownstreamPowerMax, s.UpstreamPowerMax = math.Inf(-1), math.Inf(-1)
+	for _, c := range ch.Downstream {
+		s.Corrected += c.Corrected
+		s.Uncorrectable += c.Uncorrectable
+		if !c.Locked {
+			continue
+		}
+		s.DownstreamLocked++
+		dsPower += c.Power
+		dsSNR += c.SNR
+		s.DownstreamPowerMin = min(s.DownstreamPowerMin, c.Power)
+		s.DownstreamPowerMax = max(s.DownstreamPowerMax, c.Power)
+		s.DownstreamSNRMin = min(s.DownstreamSNRMin, c.SNR)
+	}
+	for _, c := range ch.Upstream {
+		if !c.Locked {
+			continue
+		}
+		s.UpstreamLocked++
+		usPower += c.Power
+		s.UpstreamPowerMax = max(s.UpstreamPowerMax, c.Power)
+	}
+
+	if s.DownstreamLocked > 0 {
+		s.DownstreamPowerAvg = round1(dsPower / float64(s.DownstreamLocked))
+		s.DownstreamSNRAvg = round1(dsSNR / float64(s.DownstreamLocked))
+	} else {
+		s.DownstreamPowerMin, s.DownstreamPowerMax, s.DownstreamSNRMin = 0, 0, 0
+	}
+	if s.UpstreamLocked > 0 {
+		s.UpstreamPowerAvg = round1(usPower / float64(s.UpstreamLocked))
+	} else {
+		s.UpstreamPowerMax = 0
+	}
+
+	return s
+}
+
+func round1(f float64) float64 {
+	return math.Round(f*10) / 10
+}
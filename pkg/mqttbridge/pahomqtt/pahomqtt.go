@@ -0,0 +1,58 @@
+// Package pahomqtt adapts an Eclipse Paho MQTT client to be used by the bridge.
+package pahomqtt
+
+import (
+	"context"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// QoS is the quality of service used for publishing and subscribing.
+const QoS = 1
+
+// Client wraps a connected Paho client.
+type Client struct {
+	c mqtt.Client
+}
+
+func New(c mqtt.Client) *Client {
+	return &Client{c: c}
+}
+
+// Connect creates a client with the given options and connects it.
+func Connect(ctx context.Context, opts *mqtt.ClientOptions) (*Client, error) {
+	c := mqtt.NewClient(opts)
+	if err := wait(ctx, c.Connect()); err != nil {
+		return nil, err
+	}
+	return New(c), nil
+}
+
+func wait(ctx context.Context, t mqtt.Token) error {
+	select {
+	case <-t.Done():
+		return t.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Client) Publish(ctx context.Context, topic string, payload []byte, retain bool) error {
+	return wait(ctx, c.c.Publish(topic, QoS, retain, payload))
+}
+
+func (c *Client) Subscribe(
+	ctx context.Context,
+	filter string,
+	h func(topic string, payload []byte, retained bool),
+) error {
+	return wait(ctx, c.c.Subscribe(filter, QoS, func(_ mqtt.Client, m mqtt.Message) {
+		h(m.Topic(), m.Payload(), m.Retained())
+	}))
+}
+
+// Disconnect disconnects the client, waiting up to quiesce milliseconds for
+// the pending work to finish.
+func (c *Client) Disconnect(quiesce uint) {
+	c.c.Disconnect(quiesce)
+}
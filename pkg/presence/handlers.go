@@ -0,0 +1,76 @@
+package presence
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/httpdoer"
+)
+
+// Exec returns a handler that runs a command for each event. The event is
+// written as JSON to the standard input of the command, and is also available
+// in the CGA_EVENT (join or leave), CGA_MAC, CGA_HOSTNAME and CGA_IP
+// environment variables.
+func Exec(name string, args ...string) Handler {
+	return HandlerFunc(func(ctx context.Context, e Event) error {
+		payload, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Env = append(os.Environ(),
+			"CGA_EVENT="+e.Type.String(),
+			"CGA_MAC="+e.MAC,
+			"CGA_HOSTNAME="+e.Host.Hostname,
+			"CGA_IP="+e.Host.IP,
+		)
+		cmd.Stdin = bytes.NewReader(payload)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("run %s: %w; output: %s", name, err, out)
+		}
+		return nil
+	})
+}
+
+// DefaultWebhookTimeout is the timeout of the requests of a Webhook without an
+// HTTP doer.
+const DefaultWebhookTimeout = 10 * time.Second
+
+// Webhook returns a handler that POSTs each event as JSON to the given URL. If
+// d is nil, an *http.Client with DefaultWebhookTimeout is used.
+func Webhook(url string, d httpdoer.HTTPDoer) Handler {
+	if d == nil {
+		d = &http.Client{Timeout: DefaultWebhookTimeout}
+	}
+	return HandlerFunc(func(ctx context.Context, e Event) error {
+		payload, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url,
+			bytes.NewReader(payload))
+		if err != nil {
+			return fmt.Errorf("build request: %w", err)
+		}
+		req.Header.Set(httpdoer.HeaderNameContentType, "application/json")
+		res, err := d.Do(req)
+		if err != nil {
+			return fmt.Errorf("perform request: %w", err)
+		}
+		if _, err := httpdoer.ReadAndCloseBody(res); err != nil {
+			return err
+		}
+		if res.StatusCode < 200 || res.StatusCode > 299 {
+			return fmt.Errorf("webhook returned HTTP status %s", res.Status)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,302 @@
+// Package presence detects when devices join or leave the LAN, from the hosts
+// table of the device.
+package presence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+)
+
+const (
+	DefaultInterval      = 30 * time.Second
+	DefaultLeaveDebounce = 5 * time.Minute
+)
+
+// EventType is whether a host joined or left.
+type EventType int
+
+const (
+	Join EventType = iota + 1
+	Leave
+)
+
+func (t EventType) String() string {
+	switch t {
+	case Join:
+		return "join"
+	case Leave:
+		return "leave"
+	}
+	return fmt.Sprintf("EventType(%d)", int(t))
+}
+
+func (t EventType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *EventType) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "join":
+		*t = Join
+	case "leave":
+		*t = Leave
+	default:
+		return fmt.Errorf("invalid event type %q", b)
+	}
+	return nil
+}
+
+// Event is a change in the presence of a host.
+type Event struct {
+	Type EventType `json:"type"`
+	// MAC is the lowercase, colon separated MAC address of the host.
+	MAC string `json:"mac"`
+	// Host is the last known information of the host.
+	Host client.Host `json:"host"`
+	Time time.Time   `json:"time"`
+	// Initial is set for the join events of the hosts that were already
+	// connected when the watcher started.
+	Initial bool `json:"initial,omitempty"`
+}
+
+// Handler handles presence events.
+type Handler interface {
+	HandleEvent(context.Context, Event) error
+}
+
+type HandlerFunc func(context.Context, Event) error
+
+func (f HandlerFunc) HandleEvent(ctx context.Context, e Event) error {
+	return f(ctx, e)
+}
+
+// HostLister lists the hosts known to the device. It is implemented by
+// client.Client.
+type HostLister interface {
+	Hosts(ctx context.Context) ([]client.Host, error)
+}
+
+// Params configures a Watcher.
+type Params struct {
+	Client HostLister
+	// Interval is the time between polls of the hosts table. Defaults to
+	// DefaultInterval.
+	Interval time.Duration
+	// JoinDebounce is how long a host must be connected before it is
+	// considered to have joined. Zero means it joins as soon as it is seen.
+	JoinDebounce time.Duration
+	// LeaveDebounce is how long a host must be disconnected before it is
+	// considered to have left. Defaults to DefaultLeaveDebounce, since
+	// phones disconnect from Wi-Fi while sleeping. A negative value means no
+	// debounce.
+	LeaveDebounce time.Duration
+	// MACs, if set, are the only hosts watched.
+	MACs []string
+	// Handlers are called in order for each event.
+	Handlers []Handler
+	// OnError, if set, is called with the errors polling the device and
+	// handling the events.
+	OnError func(error)
+}
+
+func (p Params) WithDefaults() Params {
+	if p.Interval <= 0 {
+		p.Interval = DefaultInterval
+	}
+	if p.LeaveDebounce == 0 {
+		p.LeaveDebounce = DefaultLeaveDebounce
+	}
+	p.LeaveDebounce = max(p.LeaveDebounce, 0)
+	p.JoinDebounce = max(p.JoinDebounce, 0)
+	if p.OnError == nil {
+		p.OnError = func(error) {}
+	}
+	return p
+}
+
+// Watcher polls the hosts table and emits join and leave events.
+type Watcher struct {
+	p       Params
+	macs    map[string]bool
+	tracker *tracker
+}
+
+func New(p Params) (*Watcher, error) {
+	p = p.WithDefaults()
+	if p.Client == nil {
+		return nil, errors.New("no client")
+	}
+
+	var macs map[string]bool
+	if len(p.MACs) > 0 {
+		macs = make(map[string]bool, len(p.MACs))
+		for _, m := range p.MACs {
+			mac, err := client.NormalizeMAC(m)
+			if err != nil {
+				return nil, err
+			}
+			macs[mac] = true
+		}
+	}
+
+	return &Watcher{
+		p:       p,
+		macs:    macs,
+		tracker: newTracker(p.JoinDebounce, p.LeaveDebounce),
+	}, nil
+}
+
+// Run polls the hosts table until the context is canceled. Failed polls are
+// skipped, so that they don't count as the hosts leaving.
+func (w *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.p.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.Poll(ctx); err != nil && ctx.Err() == nil {
+			w.p.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll fetches the hosts table once and handles the resulting events.
+func (w *Watcher) Poll(ctx context.Context) error {
+	hosts, err := w.p.Client.Hosts(ctx)
+	if err != nil {
+		return fmt.Errorf("get hosts: %w", err)
+	}
+
+	active := make(map[string]client.Host)
+	for _, h := range hosts {
+		mac, err := client.NormalizeMAC(h.MAC)
+		if err != nil || !h.Active || (w.macs != nil && !w.macs[mac]) {
+			continue
+		}
+		active[mac] = h
+	}
+
+	for _, e := range w.tracker.update(time.Now(), active) {
+		for _, h := range w.p.Handlers {
+			if err := h.HandleEvent(ctx, e); err != nil {
+				w.p.OnError(fmt.Errorf("handle %s of %s: %w", e.Type, e.MAC,
+					err))
+			}
+		}
+	}
+
+	return nil
+}
+
+// Present returns the hosts currently considered present.
+func (w *Watcher) Present() []client.Host {
+	return w.tracker.present()
+}
+
+// tracker keeps the debounced presence state of each host.
+type tracker struct {
+	joinDebounce, leaveDebounce time.Duration
+
+	started bool
+	hosts   map[string]*hostState
+}
+
+type hostState struct {
+	host    client.Host
+	present bool
+	// seenSince is when the host was first seen connected in the current
+	// streak, while it is not present yet
+	seenSince time.Time
+	lastSeen  time.Time
+}
+
+func newTracker(joinDebounce, leaveDebounce time.Duration) *tracker {
+	return &tracker{
+		joinDebounce:  joinDebounce,
+		leaveDebounce: leaveDebounce,
+		hosts:         make(map[string]*hostState),
+	}
+}
+
+// update records the hosts connected at the given time and returns the
+// resulting events, sorted by MAC.
+func (t *tracker) update(now time.Time, active map[string]client.Host) []Event {
+	initial := !t.started
+	t.started = true
+
+	var events []Event
+	for mac, h := range active {
+		s := t.hosts[mac]
+		if s == nil {
+			s = new(hostState)
+			t.hosts[mac] = s
+		}
+		s.host = h
+		s.lastSeen = now
+		if s.present {
+			continue
+		}
+		if s.seenSince.IsZero() {
+			s.seenSince = now
+		}
+		if initial || now.Sub(s.seenSince) >= t.joinDebounce {
+			s.present = true
+			s.seenSince = time.Time{}
+			events = append(events, Event{
+				Type:    Join,
+				MAC:     mac,
+				Host:    h,
+				Time:    now,
+				Initial: initial,
+			})
+		}
+	}
+
+	for mac, s := range t.hosts {
+		if _, ok := active[mac]; ok {
+			continue
+		}
+		s.seenSince = time.Time{}
+		if !s.present {
+			delete(t.hosts, mac)
+			continue
+		}
+		if now.Sub(s.lastSeen) >= t.leaveDebounce {
+			delete(t.hosts, mac)
+			events = append(events, Event{
+				Type: Leave,
+				MAC:  mac,
+				Host: s.host,
+				Time: now,
+			})
+		}
+	}
+
+	slices.SortFunc(events, func(a, b Event) int {
+		return strings.Compare(a.MAC, b.MAC)
+	})
+	return events
+}
+
+func (t *tracker) present() []client.Host {
+	var ret []client.Host
+	for _, s := range t.hosts {
+		if s.present {
+			ret = append(ret, s.host)
+		}
+	}
+	slices.SortFunc(ret, func(a, b client.Host) int {
+		return strings.Compare(a.MAC, b.MAC)
+	})
+	return ret
+}
@@ -0,0 +1,109 @@
+package presence
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/client"
+)
+
+func TestTracker(t *testing.T) {
+	t.Parallel()
+
+	const phone, laptop = "00:11:22:33:44:55", "00:11:22:33:44:66"
+	tr := newTracker(time.Minute, 5*time.Minute)
+	start := time.Now()
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+	hosts := func(macs ...string) map[string]client.Host {
+		ret := make(map[string]client.Host)
+		for _, m := range macs {
+			ret[m] = client.Host{MAC: m, Active: true}
+		}
+		return ret
+	}
+
+	steps := []struct {
+		at       time.Duration
+		active   []string
+		expected []string // "type mac"
+	}{
+		// connected hosts join immediately at start
+		{0, []string{phone}, []string{"join " + phone}},
+		// joins are debounced
+		{time.Minute / 2, []string{phone, laptop}, nil},
+		{time.Minute, []string{phone}, nil}, // laptop flapped, streak reset
+		{2 * time.Minute, []string{phone, laptop}, nil},
+		{3 * time.Minute, []string{phone, laptop}, []string{"join " + laptop}},
+		// leaves are debounced
+		{4 * time.Minute, []string{laptop}, nil},
+		{6 * time.Minute, []string{phone, laptop}, nil}, // phone back in time
+		{7 * time.Minute, nil, nil},
+		{12 * time.Minute, nil, []string{"leave " + phone, "leave " + laptop}},
+	}
+
+	for i, s := range steps {
+		events := tr.update(at(s.at), hosts(s.active...))
+		var got []string
+		for _, e := range events {
+			got = append(got, e.Type.String()+" "+e.MAC)
+			if e.Initial != (i == 0) {
+				t.Errorf("step %d: unexpected Initial in %#v", i, e)
+			}
+		}
+		if len(got) != len(s.expected) {
+			t.Fatalf("step %d: expected events %v, got %v", i, s.expected, got)
+		}
+		for j := range got {
+			if got[j] != s.expected[j] {
+				t.Fatalf("step %d: expected events %v, got %v", i, s.expected,
+					got)
+			}
+		}
+	}
+	if p := tr.present(); len(p) != 0 {
+		t.Fatalf("expected no hosts present, got %v", p)
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	t.Parallel()
+
+	e := Event{
+		Type: Join,
+		MAC:  "00:11:22:33:44:55",
+		Host: client.Host{MAC: "00:11:22:33:44:55", Hostname: "phone"},
+		Time: time.Now(),
+	}
+	ctx := context.Background()
+
+	var got Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+	if err := Webhook(srv.URL, nil).HandleEvent(ctx, e); err != nil {
+		t.Fatalf("webhook: %v", err)
+	}
+	if got.Type != Join || got.Host.Hostname != "phone" {
+		t.Fatalf("unexpected webhook event: %#v", got)
+	}
+
+	out := filepath.Join(t.TempDir(), "out")
+	h := Exec("sh", "-c", `echo "$CGA_EVENT $CGA_MAC $CGA_HOSTNAME" > "$0"`, out)
+	if err := h.HandleEvent(ctx, e); err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if expected := "join 00:11:22:33:44:55 phone\n"; string(b) != expected {
+		t.Fatalf("expected %q, got %q", expected, b)
+	}
+}
@@ -0,0 +1,123 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+)
+
+// Baseline maps each endpoint (e.g. "GET /api/v1/host/hostTbl") to the shape
+// of its responses.
+type Baseline map[string]Shape
+
+// EndpointKey returns the key used in a Baseline for the method and path.
+func EndpointKey(method, path string) string {
+	return method + " " + path
+}
+
+// LoadBaseline reads a baseline from a JSON file. A missing file is an empty
+// baseline.
+func LoadBaseline(path string) (Baseline, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Baseline{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read baseline: %w", err)
+	}
+	var ret Baseline
+	if err := json.Unmarshal(b, &ret); err != nil {
+		return nil, fmt.Errorf("decode baseline: %w", err)
+	}
+	if ret == nil {
+		ret = Baseline{}
+	}
+	return ret, nil
+}
+
+// Save writes the baseline to a JSON file.
+func (b Baseline) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode baseline: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write baseline: %w", err)
+	}
+	return nil
+}
+
+// EndpointChanges are the changes in the responses of an endpoint.
+type EndpointChanges struct {
+	Endpoint string   `json:"endpoint"`
+	Changes  []Change `json:"changes"`
+}
+
+// Compare returns the changes from the baseline for the endpoints in current,
+// sorted by endpoint. Endpoints missing in the baseline are reported as an
+// addition of the root path. Endpoints missing in current are not reported,
+// since they may just have not been called.
+func (b Baseline) Compare(current Baseline) []EndpointChanges {
+	var ret []EndpointChanges
+	for _, e := range slices.Sorted(maps.Keys(current)) {
+		var changes []Change
+		if base, ok := b[e]; ok {
+			changes = Diff(base, current[e])
+		} else {
+			changes = []Change{{Kind: Added, Path: "", To: current[e][""]}}
+		}
+		if len(changes) > 0 {
+			ret = append(ret, EndpointChanges{e, changes})
+		}
+	}
+	return ret
+}
+
+// Recorder accumulates the shapes of the responses of each endpoint. It
+// implements client.ResponseObserver. It is safe for concurrent use.
+type Recorder struct {
+	mu     sync.Mutex
+	shapes Baseline
+	// OnError, if set, is called with the responses that could not be decoded.
+	OnError func(method, path string, err error)
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{shapes: Baseline{}}
+}
+
+// ObserveResponse records the shape of a response body.
+func (r *Recorder) ObserveResponse(method, path string, body []byte) {
+	s, err := Of(body)
+	if err != nil {
+		if r.OnError != nil {
+			r.OnError(method, path, err)
+		}
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k := EndpointKey(method, path)
+	if cur, ok := r.shapes[k]; ok {
+		cur.Merge(s)
+	} else {
+		r.shapes[k] = s
+	}
+}
+
+// Shapes returns a copy of the shapes recorded so far.
+func (r *Recorder) Shapes() Baseline {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ret := make(Baseline, len(r.shapes))
+	for k, s := range r.shapes {
+		ret[k] = maps.Clone(s)
+	}
+	return ret
+}
@@ -0,0 +1,211 @@
+// Package schema records the shape of JSON documents (keys, types and
+// nesting) and reports how it changes, to detect firmware updates that change
+// the API responses.
+package schema
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Types of JSON values.
+const (
+	TypeObject = "object"
+	TypeArray  = "array"
+	TypeString = "string"
+	TypeNumber = "number"
+	TypeBool   = "bool"
+	TypeNull   = "null"
+)
+
+// Shape maps the path of each value in a JSON document to its type. Paths are
+// made of the object keys separated by dots, with "[]" for the elements of
+// arrays, e.g. "data.hostTbl[].physaddress". The root path is "". When the
+// same path has values of different types (e.g. in different elements of an
+// array), the types are sorted and separated with "|".
+type Shape map[string]string
+
+// Of returns the shape of a JSON document.
+func Of(raw []byte) (Shape, error) {
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, fmt.Errorf("decode JSON: %w", err)
+	}
+	s := Shape{}
+	s.add("", v)
+	return s, nil
+}
+
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func (s Shape) add(path string, v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		s.addType(path, TypeObject)
+		for k, e := range v {
+			s.add(join(path, k), e)
+		}
+	case []any:
+		s.addType(path, TypeArray)
+		for _, e := range v {
+			s.add(path+"[]", e)
+		}
+	case string:
+		s.addType(path, TypeString)
+	case json.Number:
+		s.addType(path, TypeNumber)
+	case bool:
+		s.addType(path, TypeBool)
+	case nil:
+		s.addType(path, TypeNull)
+	}
+}
+
+func (s Shape) addType(path, typ string) {
+	cur, ok := s[path]
+	if !ok {
+		s[path] = typ
+		return
+	}
+	types := strings.Split(cur, "|")
+	if slices.Contains(types, typ) {
+		return
+	}
+	types = append(types, typ)
+	slices.Sort(types)
+	s[path] = strings.Join(types, "|")
+}
+
+// Merge adds the paths and types of other to s.
+func (s Shape) Merge(other Shape) {
+	for path, typ := range other {
+		for _, t := range strings.Split(typ, "|") {
+			s.addType(path, t)
+		}
+	}
+}
+
+// ChangeKind is the kind of a Change.
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Retyped ChangeKind = "retyped"
+)
+
+// Change is a difference between two shapes.
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+	Path string     `json:"path"`
+	// From is the type in the baseline. Empty for Added.
+	From string `json:"from,omitempty"`
+	// To is the current type. Empty for Removed.
+	To string `json:"to,omitempty"`
+}
+
+func (c Change) String() string {
+	path := cmp.Or(c.Path, "(root)")
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s (%s)", path, c.To)
+	case Removed:
+		return fmt.Sprintf("- %s (%s)", path, c.From)
+	}
+	return fmt.Sprintf("~ %s: %s -> %s", path, c.From, c.To)
+}
+
+// Diff returns the changes from baseline to current, sorted by path. Paths
+// under an added or removed path are not reported, nor are the removals under
+// arrays that are empty in current, which only means that there were no
+// elements to sample. Null is compatible with any type, since fields are often
+// null when not set.
+func Diff(baseline, current Shape) []Change {
+	var ret []Change
+	paths := slices.Sorted(maps.Keys(baseline))
+	for p := range current {
+		if _, ok := baseline[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	slices.Sort(paths)
+
+	// siblings can sort between a path and its children, e.g. "data.c",
+	// "data.c2", "data.c[]"
+	var skip []string
+	skipped := func(p string) bool {
+		return slices.ContainsFunc(skip, func(s string) bool {
+			return under(p, s)
+		})
+	}
+	for _, p := range paths {
+		if skipped(p) {
+			continue
+		}
+		from, inBaseline := baseline[p]
+		to, inCurrent := current[p]
+		switch {
+		case !inBaseline:
+			ret = append(ret, Change{Kind: Added, Path: p, To: to})
+			skip = append(skip, p)
+		case !inCurrent:
+			if !underEmptyArray(p, current) {
+				ret = append(ret, Change{Kind: Removed, Path: p, From: from})
+			}
+			skip = append(skip, p)
+		case !compatible(from, to):
+			ret = append(ret, Change{Kind: Retyped, Path: p, From: from, To: to})
+		}
+	}
+
+	return ret
+}
+
+// compatible reports whether the types are the same, ignoring null.
+func compatible(from, to string) bool {
+	notNull := func(typ string) []string {
+		return slices.DeleteFunc(strings.Split(typ, "|"), func(t string) bool {
+			return t == TypeNull
+		})
+	}
+	a, b := notNull(from), notNull(to)
+	return len(a) == 0 || len(b) == 0 || slices.Equal(a, b)
+}
+
+// underEmptyArray reports whether path is under the elements of an array that
+// has no elements in s.
+func underEmptyArray(path string, s Shape) bool {
+	for i := 0; ; i += len("[]") {
+		j := strings.Index(path[i:], "[]")
+		if j < 0 {
+			return false
+		}
+		i += j
+		array := path[:i]
+		_, hasElems := s[array+"[]"]
+		if !hasElems && slices.Contains(strings.Split(s[array], "|"), TypeArray) {
+			return true
+		}
+	}
+}
+
+// under reports whether path is nested under parent.
+func under(path, parent string) bool {
+	if parent == "" {
+		return path != ""
+	}
+	rest, ok := strings.CutPrefix(path, parent)
+	return ok && (strings.HasPrefix(rest, ".") || strings.HasPrefix(rest, "[]"))
+}
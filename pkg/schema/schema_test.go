@@ -0,0 +1,146 @@
+package schema
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestOf(t *testing.T) {
+	t.Parallel()
+
+	s, err := Of([]byte(`{
+		"error": "ok",
+		"data": {
+			"hostTbl": [
+				{"mac": "AA", "active": true, "lease": 10},
+				{"mac": "BB", "active": "true", "lease": null}
+			],
+			"empty": []
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := Shape{
+		"":                      TypeObject,
+		"error":                 TypeString,
+		"data":                  TypeObject,
+		"data.hostTbl":          TypeArray,
+		"data.hostTbl[]":        TypeObject,
+		"data.hostTbl[].mac":    TypeString,
+		"data.hostTbl[].active": "bool|string",
+		"data.hostTbl[].lease":  "null|number",
+		"data.empty":            TypeArray,
+	}
+	if len(s) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, s)
+	}
+	for k, x := range expected {
+		if g := s[k]; g != x {
+			t.Errorf("%q: expected %q, got %q", k, x, g)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	baseline := Shape{
+		"":             TypeObject,
+		"data":         TypeObject,
+		"data.a":       TypeString,
+		"data.b":       TypeObject,
+		"data.b.x":     TypeString,
+		"data.b.y":     TypeString,
+		"data.removed": TypeBool,
+	}
+	current := Shape{
+		"":         TypeObject,
+		"data":     TypeObject,
+		"data.a":   TypeNumber,
+		"data.b":   TypeObject,
+		"data.b.x": TypeString,
+		"data.c":   TypeArray,
+		"data.c[]": TypeString,
+		"data.b.y": TypeString,
+	}
+
+	got := Diff(baseline, current)
+	expected := []Change{
+		{Kind: Retyped, Path: "data.a", From: TypeString, To: TypeNumber},
+		{Kind: Added, Path: "data.c", To: TypeArray},
+		{Kind: Removed, Path: "data.removed", From: TypeBool},
+	}
+	if !slices.Equal(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+
+	if changes := Diff(current, current); len(changes) != 0 {
+		t.Fatalf("expected no changes, got %v", changes)
+	}
+
+	baseline = Shape{
+		"":                  TypeObject,
+		"data":              TypeObject,
+		"data.hostTbl":      TypeArray,
+		"data.hostTbl[]":    TypeObject,
+		"data.hostTbl[].ip": TypeString,
+		"data.lease":        "null|number",
+		"data.name":         TypeString,
+		"data.c":            TypeArray,
+		"data.c[]":          TypeObject,
+		"data.c[].x":        TypeString,
+		"data.c2":           TypeString,
+	}
+	current = Shape{
+		"":             TypeObject,
+		"data":         TypeObject,
+		"data.hostTbl": TypeArray, // empty
+		"data.lease":   TypeNull,
+		"data.name":    "null|string",
+		"data.c2":      TypeString,
+	}
+	got = Diff(baseline, current)
+	expected = []Change{{Kind: Removed, Path: "data.c", From: TypeArray}}
+	if !slices.Equal(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestRecorderAndBaseline(t *testing.T) {
+	t.Parallel()
+
+	r := NewRecorder()
+	r.ObserveResponse("GET", "/a", []byte(`{"data":{"x":1}}`))
+	r.ObserveResponse("GET", "/a", []byte(`{"data":{"x":null}}`))
+	var decodeErrs int
+	r.OnError = func(string, string, error) { decodeErrs++ }
+	r.ObserveResponse("GET", "/b", []byte(`<html>`))
+	if decodeErrs != 1 {
+		t.Fatalf("expected 1 decode error, got %d", decodeErrs)
+	}
+
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	if err := r.Shapes().Save(path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	baseline, err := LoadBaseline(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got := baseline[EndpointKey("GET", "/a")]["data.x"]; got != "null|number" {
+		t.Fatalf("unexpected type of data.x: %q", got)
+	}
+
+	r2 := NewRecorder()
+	r2.ObserveResponse("GET", "/a", []byte(`{"data":{"x":"1"}}`))
+	r2.ObserveResponse("GET", "/c", []byte(`{}`))
+	got := baseline.Compare(r2.Shapes())
+	if len(got) != 2 || got[0].Endpoint != "GET /a" ||
+		got[0].Changes[0].Kind != Retyped || got[1].Endpoint != "GET /c" ||
+		got[1].Changes[0].Kind != Added {
+		t.Fatalf("unexpected changes: %#v", got)
+	}
+}
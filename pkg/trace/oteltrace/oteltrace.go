@@ -0,0 +1,68 @@
+// Package oteltrace adapts an OpenTelemetry tracer to trace.Tracer.
+package oteltrace
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/trace"
+)
+
+// New returns a trace.Tracer that creates spans with the given OpenTelemetry
+// tracer.
+func New(t oteltrace.Tracer) trace.Tracer {
+	return tracer{t}
+}
+
+type tracer struct {
+	t oteltrace.Tracer
+}
+
+func (t tracer) Start(ctx context.Context, name string, attrs ...trace.Attribute) (context.Context, trace.Span) {
+	ctx, s := t.t.Start(ctx, name, oteltrace.WithAttributes(convert(attrs)...))
+	return ctx, span{s}
+}
+
+type span struct {
+	s oteltrace.Span
+}
+
+func (s span) SetAttributes(attrs ...trace.Attribute) {
+	s.s.SetAttributes(convert(attrs)...)
+}
+
+func (s span) RecordError(err error) {
+	s.s.RecordError(err)
+	s.s.SetStatus(codes.Error, err.Error())
+}
+
+func (s span) End() { s.s.End() }
+
+func convert(attrs []trace.Attribute) []attribute.KeyValue {
+	ret := make([]attribute.KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		var kv attribute.KeyValue
+		switch v := a.Value.(type) {
+		case string:
+			kv = attribute.String(a.Key, v)
+		case bool:
+			kv = attribute.Bool(a.Key, v)
+		case int:
+			kv = attribute.Int(a.Key, v)
+		case int64:
+			kv = attribute.Int64(a.Key, v)
+		case float64:
+			kv = attribute.Float64(a.Key, v)
+		case fmt.Stringer:
+			kv = attribute.Stringer(a.Key, v)
+		default:
+			kv = attribute.String(a.Key, fmt.Sprint(v))
+		}
+		ret = append(ret, kv)
+	}
+	return ret
+}
@@ -0,0 +1,140 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SpanData is a finished span.
+type SpanData struct {
+	ID         uint64
+	ParentID   uint64 // zero for root spans
+	Depth      int    // zero for root spans
+	Name       string
+	Start, End time.Time
+	Attributes []Attribute
+	Errors     []error
+}
+
+func (s SpanData) Duration() time.Duration { return s.End.Sub(s.Start) }
+
+// recorder is a Tracer that calls onEnd with the data of each span when it
+// ends.
+type recorder struct {
+	lastID atomic.Uint64
+	onEnd  func(SpanData)
+}
+
+type recorderContextKey struct{}
+
+type recordedSpan struct {
+	r    *recorder
+	mu   sync.Mutex
+	data SpanData
+	done bool
+}
+
+func (r *recorder) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	s := &recordedSpan{
+		r: r,
+		data: SpanData{
+			ID:         r.lastID.Add(1),
+			Name:       name,
+			Start:      time.Now(),
+			Attributes: attrs,
+		},
+	}
+	if parent, _ := ctx.Value(recorderContextKey{}).(*recordedSpan); parent != nil && parent.r == r {
+		s.data.ParentID = parent.data.ID
+		s.data.Depth = parent.data.Depth + 1
+	}
+	return context.WithValue(ctx, recorderContextKey{}, s), s
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...Attribute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes = append(s.data.Attributes, attrs...)
+}
+
+func (s *recordedSpan) RecordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Errors = append(s.data.Errors, err)
+}
+
+func (s *recordedSpan) End() {
+	s.mu.Lock()
+	if s.done {
+		s.mu.Unlock()
+		return
+	}
+	s.done = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	s.r.onEnd(data)
+}
+
+// Memory is a Tracer that keeps the finished spans in memory, useful for tests
+// and for debugging.
+type Memory struct {
+	recorder
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func NewMemory() *Memory {
+	m := new(Memory)
+	m.onEnd = func(s SpanData) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.spans = append(m.spans, s)
+	}
+	return m
+}
+
+// Spans returns the finished spans, in the order they ended.
+func (m *Memory) Spans() []SpanData {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]SpanData(nil), m.spans...)
+}
+
+// Reset discards the finished spans.
+func (m *Memory) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.spans = nil
+}
+
+// NewWriter returns a Tracer that writes a line to w for each span when it
+// ends, indented by its depth. Use it with os.Stdout or os.Stderr for quick
+// debugging.
+func NewWriter(w io.Writer) Tracer {
+	var mu sync.Mutex
+	return &recorder{
+		onEnd: func(s SpanData) {
+			b := new(strings.Builder)
+			fmt.Fprintf(b, "%s%s %v", strings.Repeat("  ", s.Depth), s.Name,
+				s.Duration())
+			for _, a := range s.Attributes {
+				fmt.Fprintf(b, " %s=%v", a.Key, a.Value)
+			}
+			for _, err := range s.Errors {
+				fmt.Fprintf(b, " error=%q", err.Error())
+			}
+			b.WriteByte('\n')
+
+			mu.Lock()
+			defer mu.Unlock()
+			io.WriteString(w, b.String())
+		},
+	}
+}
@@ -0,0 +1,67 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/util"
+)
+
+func TestMemory(t *testing.T) {
+	t.Parallel()
+
+	m := NewMemory()
+	ctx := util.AddContextMeta(context.Background(), util.ContextMeta{
+		"caller": "test",
+	})
+
+	ctx, parent := StartWithMeta(ctx, m, "parent", String("a", "b"))
+	_, child := m.Start(ctx, "child")
+	End(child, errors.New("boom"))
+	End(parent, nil)
+
+	spans := m.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	c, p := spans[0], spans[1]
+	if c.Name != "child" || p.Name != "parent" {
+		t.Fatalf("unexpected span names: %q, %q", c.Name, p.Name)
+	}
+	if c.ParentID != p.ID || c.Depth != 1 || p.ParentID != 0 {
+		t.Fatalf("unexpected parenting: child %#v; parent %#v", c, p)
+	}
+	if len(c.Errors) != 1 || len(p.Errors) != 0 {
+		t.Fatalf("unexpected errors: child %v; parent %v", c.Errors, p.Errors)
+	}
+	expectedAttrs := []Attribute{String("meta.caller", "test"), String("a", "b")}
+	if len(p.Attributes) != len(expectedAttrs) {
+		t.Fatalf("unexpected attributes: %#v", p.Attributes)
+	}
+	for i, a := range expectedAttrs {
+		if p.Attributes[i] != a {
+			t.Errorf("[#%d] expected attribute %#v, got %#v", i, a,
+				p.Attributes[i])
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	t.Parallel()
+
+	b := new(strings.Builder)
+	w := NewWriter(b)
+	ctx, parent := w.Start(context.Background(), "parent")
+	_, child := w.Start(ctx, "child", Int("n", 1))
+	child.End()
+	parent.End()
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "  child ") ||
+		!strings.HasSuffix(lines[0], " n=1") ||
+		!strings.HasPrefix(lines[1], "parent ") {
+		t.Fatalf("unexpected output:\n%s", b.String())
+	}
+}
@@ -0,0 +1,85 @@
+// Package trace defines a small tracing interface used to instrument the
+// client, with no-op, in-memory and writer implementations. See the oteltrace
+// subpackage for an OpenTelemetry implementation.
+package trace
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/diegommm/technicolor-cga4233tch3/pkg/util"
+)
+
+// MetaAttributePrefix is prepended to the keys of the util.ContextMeta values
+// when they are added as span attributes.
+const MetaAttributePrefix = "meta."
+
+type Attribute struct {
+	Key   string
+	Value any // string, bool, int, int64, float64 or fmt.Stringer
+}
+
+func String(k, v string) Attribute    { return Attribute{k, v} }
+func Int(k string, v int) Attribute   { return Attribute{k, v} }
+func Bool(k string, v bool) Attribute { return Attribute{k, v} }
+
+// Tracer starts spans. The returned context carries the span, so that spans
+// started from it are its children.
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// End records the error in the span, if any, and ends it. It's meant to be
+// deferred in functions with a named error return value:
+//
+//	defer func() { trace.End(span, err) }()
+func End(s Span, err error) {
+	if err != nil {
+		s.RecordError(err)
+	}
+	s.End()
+}
+
+// MetaAttributes returns the util.ContextMeta values in the context as
+// attributes, sorted by key.
+func MetaAttributes(ctx context.Context) []Attribute {
+	m := util.GetContextMeta(ctx)
+	ret := make([]Attribute, 0, len(m))
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		ret = append(ret, String(MetaAttributePrefix+k, m[k]))
+	}
+	return ret
+}
+
+// StartWithMeta starts a span including the util.ContextMeta values in the
+// context as attributes.
+func StartWithMeta(
+	ctx context.Context,
+	t Tracer,
+	name string,
+	attrs ...Attribute,
+) (context.Context, Span) {
+	return t.Start(ctx, name, append(MetaAttributes(ctx), attrs...)...)
+}
+
+// Nop returns a Tracer that does nothing.
+func Nop() Tracer { return nopTracer{} }
+
+type nopTracer struct{}
+
+func (nopTracer) Start(ctx context.Context, _ string, _ ...Attribute) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(...Attribute) {}
+func (nopSpan) RecordError(error)          {}
+func (nopSpan) End()                       {}
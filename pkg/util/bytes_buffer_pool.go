@@ -0,0 +1,58 @@
+package util
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+)
+
+// minPooledCap is the capacity under which buffers are always pooled.
+const minPooledCap = 4 << 10
+
+// BytesBufferAdaptivePool is a pool of *bytes.Buffer that avoids retaining
+// buffers that grew much larger than usual. It keeps a moving average of the
+// length of the released buffers, and drops those with a capacity greater than
+// that average multiplied by a factor, so that an occasional large payload
+// doesn't stay in memory forever.
+type BytesBufferAdaptivePool struct {
+	pool   sync.Pool
+	factor int64
+	avg    atomic.Int64
+}
+
+func NewBytesBufferAdaptivePool(factor int) *BytesBufferAdaptivePool {
+	return &BytesBufferAdaptivePool{
+		pool: sync.Pool{
+			New: func() any { return new(bytes.Buffer) },
+		},
+		factor: int64(max(factor, 1)),
+	}
+}
+
+// Acquire returns an empty buffer.
+func (p *BytesBufferAdaptivePool) Acquire() *bytes.Buffer {
+	return p.pool.Get().(*bytes.Buffer)
+}
+
+// Release returns the buffer to the pool. The buffer must not be used
+// afterwards.
+func (p *BytesBufferAdaptivePool) Release(buf *bytes.Buffer) {
+	if buf == nil {
+		return
+	}
+
+	// exponential moving average with alpha = 1/8
+	n := int64(buf.Len())
+	for {
+		old := p.avg.Load()
+		if p.avg.CompareAndSwap(old, old+(n-old)/8) {
+			break
+		}
+	}
+
+	if c := int64(buf.Cap()); c > minPooledCap && c > p.factor*p.avg.Load() {
+		return
+	}
+	buf.Reset()
+	p.pool.Put(buf)
+}
@@ -0,0 +1,32 @@
+package util
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBytesBufferAdaptivePool(t *testing.T) {
+	t.Parallel()
+
+	p := NewBytesBufferAdaptivePool(2)
+
+	buf := p.Acquire()
+	if buf.Len() != 0 {
+		t.Fatalf("expected empty buffer, got length %d", buf.Len())
+	}
+	buf.WriteString("hello")
+	p.Release(buf)
+
+	if got := p.Acquire(); got.Len() != 0 {
+		t.Fatalf("expected pooled buffer to be reset, got %q", got.String())
+	}
+
+	// a buffer much larger than the average must not be pooled
+	large := bytes.NewBuffer(make([]byte, 0, 1<<20))
+	p.Release(large)
+	for range 10 {
+		if got := p.Acquire(); got == large {
+			t.Fatalf("large buffer should not have been pooled")
+		}
+	}
+}
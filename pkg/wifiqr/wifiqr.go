@@ -0,0 +1,99 @@
+// Package wifiqr generates "WIFI:" QR codes, which phones scan to join a Wi-Fi
+// network.
+package wifiqr
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// Authentication types.
+const (
+	AuthWPA    = "WPA" // WPA, WPA2 and WPA3 personal
+	AuthWEP    = "WEP"
+	AuthNoPass = "nopass"
+)
+
+// DefaultPNGSize is the default size in pixels of the PNG images.
+const DefaultPNGSize = 512
+
+// Network is a Wi-Fi network to encode in a QR code.
+type Network struct {
+	SSID       string
+	Passphrase string
+	// Auth is one of the Auth* constants.
+	Auth   string
+	Hidden bool
+}
+
+// AuthFromSecurity returns the authentication type for a security mode as
+// reported by the device, e.g. "WPA2-Personal" or "None".
+func AuthFromSecurity(mode string) string {
+	m := strings.ToUpper(mode)
+	switch {
+	case strings.Contains(m, "WPA"):
+		return AuthWPA
+	case strings.Contains(m, "WEP"):
+		return AuthWEP
+	}
+	return AuthNoPass
+}
+
+var escaper = strings.NewReplacer(
+	`\`, `\\`,
+	`;`, `\;`,
+	`,`, `\,`,
+	`:`, `\:`,
+	`"`, `\"`,
+)
+
+// String returns the contents of the QR code.
+func (n Network) String() string {
+	auth := n.Auth
+	if auth == "" {
+		auth = AuthNoPass
+	}
+
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "WIFI:T:%s;S:%s;", auth, escaper.Replace(n.SSID))
+	if auth != AuthNoPass {
+		fmt.Fprintf(b, "P:%s;", escaper.Replace(n.Passphrase))
+	}
+	if n.Hidden {
+		b.WriteString("H:true;")
+	}
+	b.WriteString(";")
+
+	return b.String()
+}
+
+// WriteTerminal writes the QR code to w using Unicode block characters.
+func (n Network) WriteTerminal(w io.Writer) error {
+	q, err := qrcode.New(n.String(), qrcode.Medium)
+	if err != nil {
+		return fmt.Errorf("generate QR code: %w", err)
+	}
+	_, err = io.WriteString(w, q.ToSmallString(false))
+	return err
+}
+
+// WritePNG writes the QR code as a PNG image of size x size pixels to the file.
+// A size of zero means DefaultPNGSize. The file is only readable by the user,
+// since the QR code contains the passphrase.
+func (n Network) WritePNG(path string, size int) error {
+	if size <= 0 {
+		size = DefaultPNGSize
+	}
+	b, err := qrcode.Encode(n.String(), qrcode.Medium, size)
+	if err != nil {
+		return fmt.Errorf("generate QR code: %w", err)
+	}
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		return fmt.Errorf("write PNG: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package wifiqr
+
+import "testing"
+
+func TestNetworkString(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		n        Network
+		expected string
+	}{
+		{
+			Network{SSID: "Guest", Passphrase: "secret123", Auth: AuthWPA},
+			"WIFI:T:WPA;S:Guest;P:secret123;;",
+		},
+		{
+			Network{SSID: `a;b,c:d"e\f`, Passphrase: "p;ss:word", Auth: AuthWPA,
+				Hidden: true},
+			`WIFI:T:WPA;S:a\;b\,c\:d\"e\\f;P:p\;ss\:word;H:true;;`,
+		},
+		{
+			Network{SSID: "Open", Passphrase: "ignored"},
+			"WIFI:T:nopass;S:Open;;",
+		},
+	}
+
+	for _, tc := range testCases {
+		if got := tc.n.String(); got != tc.expected {
+			t.Errorf("expected %q, got %q", tc.expected, got)
+		}
+	}
+
+	if a := AuthFromSecurity("WPA2-WPA3-Personal"); a != AuthWPA {
+		t.Errorf("expected WPA, got %q", a)
+	}
+}